@@ -0,0 +1,206 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	store "github.com/Silencevoice/go-store"
+	bolt "go.etcd.io/bbolt"
+)
+
+const backend = "bolt"
+
+// BoltStore keeps a collection in a bucket of a bbolt database, a single
+// B+tree file, with the entities as JSON under their id. Several stores can
+// share the database, each with its own bucket. The keys are kept in byte
+// order, which Scan uses.
+type BoltStore[T any] struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+// NewBoltStore returns a BoltStore on the bucket, creating it when missing.
+func NewBoltStore[T any](db *bolt.DB, bucket string) (*BoltStore[T], error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		return err
+	})
+	if err != nil {
+		return nil, store.NewError(backend, "Open", nil, err)
+	}
+	return &BoltStore[T]{db: db, bucket: []byte(bucket)}, nil
+}
+
+// view runs f in a read-only transaction.
+func (b *BoltStore[T]) view(ctx context.Context, f func(bucket *bolt.Bucket) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+		if bucket == nil {
+			return fmt.Errorf("bucket %q not found", b.bucket)
+		}
+		return f(bucket)
+	})
+}
+
+// update runs f in a read-write transaction, which is committed unless f
+// fails.
+func (b *BoltStore[T]) update(ctx context.Context, id string, f func(bucket *bolt.Bucket) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// bbolt does not take empty keys
+	if id == "" {
+		return store.ErrInvalidID
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+		if bucket == nil {
+			return fmt.Errorf("bucket %q not found", b.bucket)
+		}
+		return f(bucket)
+	})
+}
+
+func (b *BoltStore[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	var entity *T
+	err := b.view(ctx, func(bucket *bolt.Bucket) error {
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return store.ErrNotFound
+		}
+		var err error
+		entity, err = decode[T](data)
+		return err
+	})
+	if err != nil {
+		return nil, store.NewError(backend, "GetByID", id, err)
+	}
+	return entity, nil
+}
+
+func (b *BoltStore[T]) GetMultipleByID(ctx context.Context, ids []string) ([]*T, error) {
+	ents := make([]*T, len(ids))
+	var missing any
+	err := b.view(ctx, func(bucket *bolt.Bucket) error {
+		for i, id := range ids {
+			data := bucket.Get([]byte(id))
+			if data == nil {
+				missing = id
+				return store.ErrNotFound
+			}
+			entity, err := decode[T](data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", id, err)
+			}
+			ents[i] = entity
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, store.NewError(backend, "GetMultipleByID", missing, err)
+	}
+	return ents, nil
+}
+
+// GetAll returns every entity in key order, read in a single read-only
+// transaction so writers do not wait for it.
+func (b *BoltStore[T]) GetAll(ctx context.Context) ([]*T, error) {
+	ents, err := b.scan(ctx, Range{})
+	if err != nil {
+		return nil, store.NewError(backend, "GetAll", nil, err)
+	}
+	return ents, nil
+}
+
+func (b *BoltStore[T]) Insert(ctx context.Context, id string, entity *T) (*T, error) {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
+
+	err = b.update(ctx, id, func(bucket *bolt.Bucket) error {
+		if bucket.Get([]byte(id)) != nil {
+			return store.ErrAlreadyExists
+		}
+		return bucket.Put([]byte(id), data)
+	})
+	if err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
+
+	// Decode what was stored, so the caller gets what a later GetByID returns
+	inserted, err := decode[T](data)
+	if err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
+	return inserted, nil
+}
+
+func (b *BoltStore[T]) Delete(ctx context.Context, id string) error {
+	err := b.update(ctx, id, func(bucket *bolt.Bucket) error {
+		if bucket.Get([]byte(id)) == nil {
+			return store.ErrNotFound
+		}
+		return bucket.Delete([]byte(id))
+	})
+	if err != nil {
+		return store.NewError(backend, "Delete", id, err)
+	}
+	return nil
+}
+
+func (b *BoltStore[T]) Update(ctx context.Context, id string, entity *T) error {
+	return b.replace(ctx, "Update", id, entity)
+}
+
+// Replace puts the JSON of the entity over the stored one, as Update does.
+func (b *BoltStore[T]) Replace(ctx context.Context, id string, entity *T) error {
+	return b.replace(ctx, "Replace", id, entity)
+}
+
+func (b *BoltStore[T]) replace(ctx context.Context, op string, id string, entity *T) error {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return store.NewError(backend, op, id, err)
+	}
+
+	err = b.update(ctx, id, func(bucket *bolt.Bucket) error {
+		if bucket.Get([]byte(id)) == nil {
+			return store.ErrNotFound
+		}
+		return bucket.Put([]byte(id), data)
+	})
+	if err != nil {
+		return store.NewError(backend, op, id, err)
+	}
+	return nil
+}
+
+func (b *BoltStore[T]) Upsert(ctx context.Context, id string, entity *T) (bool, error) {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return false, store.NewError(backend, "Upsert", id, err)
+	}
+
+	created := false
+	err = b.update(ctx, id, func(bucket *bolt.Bucket) error {
+		created = bucket.Get([]byte(id)) == nil
+		return bucket.Put([]byte(id), data)
+	})
+	if err != nil {
+		return false, store.NewError(backend, "Upsert", id, err)
+	}
+	return created, nil
+}
+
+func decode[T any](data []byte) (*T, error) {
+	var entity T
+	if err := json.Unmarshal(data, &entity); err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
@@ -0,0 +1,112 @@
+package bolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+type TestEntity struct {
+	ID    string
+	Value string
+}
+
+var _ gostore.Store[TestEntity] = (*BoltStore[TestEntity])(nil)
+
+func openDB(t *testing.T) *bolt.DB {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newStore(t *testing.T) *BoltStore[TestEntity] {
+	store, err := NewBoltStore[TestEntity](openDB(t), "entities")
+	require.NoError(t, err)
+	return store
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) gostore.KeyedStore[string, storetest.Entity] {
+		store, err := NewBoltStore[storetest.Entity](openDB(t), "entities")
+		require.NoError(t, err)
+		return store
+	})
+}
+
+func TestEmptyID(t *testing.T) {
+	_, err := newStore(t).Insert(context.Background(), "", &TestEntity{})
+	assert.ErrorIs(t, err, gostore.ErrInvalidID)
+}
+
+func TestGetAllIsSorted(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	store.Insert(ctx, "2", &TestEntity{ID: "2"})
+	store.Insert(ctx, "1", &TestEntity{ID: "1"})
+
+	entities, err := store.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*TestEntity{{ID: "1"}, {ID: "2"}}, entities)
+}
+
+func TestCanceledContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := newStore(t).GetByID(canceled, "1")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBuckets(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	cars, err := NewBoltStore[TestEntity](db, "cars")
+	require.NoError(t, err)
+	bikes, err := NewBoltStore[TestEntity](db, "bikes")
+	require.NoError(t, err)
+
+	t.Run("Collections are independent", func(t *testing.T) {
+		cars.Insert(ctx, "1", &TestEntity{ID: "1"})
+		_, err := bikes.GetByID(ctx, "1")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+
+		again, err := NewBoltStore[TestEntity](db, "cars")
+		require.NoError(t, err)
+		_, err = again.GetByID(ctx, "1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Bucket deleted behind the store", func(t *testing.T) {
+		require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+			return tx.DeleteBucket([]byte("bikes"))
+		}))
+
+		_, err := bikes.GetAll(ctx)
+		assert.ErrorContains(t, err, `bucket "bikes" not found`)
+		_, err = bikes.Insert(ctx, "1", &TestEntity{})
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid bucket", func(t *testing.T) {
+		_, err := NewBoltStore[TestEntity](db, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("Corrupted value", func(t *testing.T) {
+		require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte("cars")).Put([]byte("2"), []byte("{"))
+		}))
+
+		_, err := cars.GetByID(ctx, "2")
+		assert.Error(t, err)
+		_, err = cars.GetAll(ctx)
+		assert.ErrorContains(t, err, "2: ")
+	})
+}
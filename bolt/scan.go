@@ -0,0 +1,70 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	store "github.com/Silencevoice/go-store"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Range selects the keys read by Scan. Prefix keeps the keys starting with
+// it, Start and End bound the keys to [Start, End), where an empty End has
+// no bound, and a positive Limit stops after that many entities. The zero
+// Range reads everything.
+type Range struct {
+	Prefix string
+	Start  string
+	End    string
+	Limit  int
+}
+
+// Prefix returns the Range of the keys starting with prefix.
+func Prefix(prefix string) Range {
+	return Range{Prefix: prefix}
+}
+
+// Scan returns the entities in r, in key order, read in a single read-only
+// transaction.
+func (b *BoltStore[T]) Scan(ctx context.Context, r Range) ([]*T, error) {
+	ents, err := b.scan(ctx, r)
+	if err != nil {
+		return nil, store.NewError(backend, "Scan", nil, err)
+	}
+	return ents, nil
+}
+
+func (b *BoltStore[T]) scan(ctx context.Context, r Range) ([]*T, error) {
+	ents := []*T{}
+	err := b.view(ctx, func(bucket *bolt.Bucket) error {
+		start := []byte(r.Start)
+		if r.Prefix > r.Start {
+			start = []byte(r.Prefix)
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Seek(start); k != nil; k, v = c.Next() {
+			if !bytes.HasPrefix(k, []byte(r.Prefix)) {
+				break
+			}
+			if r.End != "" && bytes.Compare(k, []byte(r.End)) >= 0 {
+				break
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			entity, err := decode[T](v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			ents = append(ents, entity)
+			if r.Limit > 0 && len(ents) == r.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	return ents, err
+}
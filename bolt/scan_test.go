@@ -0,0 +1,53 @@
+package bolt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScan(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	for _, id := range []string{"car/3", "bike/1", "car/1", "car/2", "carpet/1", "dog/1"} {
+		_, err := store.Insert(ctx, id, &TestEntity{ID: id})
+		require.NoError(t, err)
+	}
+
+	ids := func(ents []*TestEntity) []string {
+		ids := []string{}
+		for _, e := range ents {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	for name, test := range map[string]struct {
+		r        Range
+		expected []string
+	}{
+		"Everything":          {Range{}, []string{"bike/1", "car/1", "car/2", "car/3", "carpet/1", "dog/1"}},
+		"Prefix":              {Prefix("car/"), []string{"car/1", "car/2", "car/3"}},
+		"No match":            {Prefix("cat/"), []string{}},
+		"Prefix from a start": {Range{Prefix: "car/", Start: "car/2"}, []string{"car/2", "car/3"}},
+		"Start past a prefix": {Range{Prefix: "car/", Start: "d"}, []string{}},
+		"Half open range":     {Range{Start: "car/2", End: "dog/1"}, []string{"car/2", "car/3", "carpet/1"}},
+		"Prefix and end":      {Range{Prefix: "car", End: "car/3"}, []string{"car/1", "car/2"}},
+		"Limit":               {Range{Prefix: "car", Limit: 2}, []string{"car/1", "car/2"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ents, err := store.Scan(ctx, test.r)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ids(ents))
+		})
+	}
+
+	t.Run("Canceled context", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := store.Scan(canceled, Range{})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"errors"
+)
+
+type Entry[K comparable, T any] struct {
+	ID     K
+	Entity *T
+}
+
+// BulkOptions controls how bulk operations deal with failing items. Ordered
+// operations stop at the first failure, leaving the remaining items
+// untouched, while unordered ones (the default) go on with the rest.
+type BulkOptions struct {
+	Ordered bool
+}
+
+// BulkFailure tells which item of a bulk operation failed. Index is its
+// position in the input.
+type BulkFailure[K comparable] struct {
+	Index int
+	ID    K
+	Err   error
+}
+
+type BulkResult[K comparable] struct {
+	Succeeded int
+	Failures  []BulkFailure[K]
+}
+
+// Err joins the errors of every failed item, or returns nil when all of them
+// succeeded.
+func (r *BulkResult[K]) Err() error {
+	errs := make([]error, len(r.Failures))
+	for i, failure := range r.Failures {
+		errs[i] = failure.Err
+	}
+	return errors.Join(errs...)
+}
+
+// BulkWriter writes many entities at once. Besides the report, the returned
+// error is the BulkResult Err when some items failed, so the usual err != nil
+// check is enough to notice them.
+type BulkWriter[K comparable, T any] interface {
+	InsertMany(ctx context.Context, entries []Entry[K, T], opts BulkOptions) (*BulkResult[K], error)
+	UpdateMany(ctx context.Context, entries []Entry[K, T], opts BulkOptions) (*BulkResult[K], error)
+	DeleteMany(ctx context.Context, ids []K, opts BulkOptions) (*BulkResult[K], error)
+}
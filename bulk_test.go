@@ -0,0 +1,27 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkResult_Err(t *testing.T) {
+	t.Run("No failures", func(t *testing.T) {
+		result := &BulkResult[string]{Succeeded: 2}
+		assert.NoError(t, result.Err())
+	})
+
+	t.Run("Joins failures", func(t *testing.T) {
+		other := errors.New("other")
+		result := &BulkResult[string]{Failures: []BulkFailure[string]{
+			{Index: 0, ID: "1", Err: &Error{Op: "InsertMany", Key: "1", Err: ErrAlreadyExists}},
+			{Index: 2, ID: "3", Err: other},
+		}}
+
+		err := result.Err()
+		assert.ErrorIs(t, err, ErrAlreadyExists)
+		assert.ErrorIs(t, err, other)
+	})
+}
@@ -0,0 +1,378 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	store "github.com/Silencevoice/go-store"
+)
+
+const backend = "cache"
+
+// CachedStore fronts a source store with a cache store, typically a MemStore
+// in front of a MongoStore. Both must be used only through the CachedStore,
+// which keeps track of what the cache holds.
+//
+// Errors of the source are returned as they are.
+type CachedStore[K comparable, T any] struct {
+	source store.KeyedStore[K, T]
+	cache  store.KeyedStore[K, T]
+	o      options
+	key    func(*T) K
+	now    func() time.Time
+
+	mu sync.Mutex
+	// expires has the expiration of every cached entity, zero for none
+	expires map[K]time.Time
+	// missing has the expiration of the ids cached as not found
+	missing map[K]time.Time
+	// writes counts the writes, when they start and when they end, so a read
+	// does not cache what a concurrent write changed while the source was
+	// read. inflight counts the writes to the source that did not end yet.
+	writes   uint64
+	inflight int
+
+	// The state of WriteBehind, see write_behind.go
+	pending map[K]pendingWrite[T]
+	seq     uint64
+	closed  bool
+	writeMu sync.Mutex
+	flushMu sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewCachedStore returns a string keyed CachedStore.
+func NewCachedStore[T any](source, cache store.Store[T], opts ...Option) *CachedStore[string, T] {
+	return NewKeyedCachedStore[string, T](source, cache, opts...)
+}
+
+// NewKeyedCachedStore returns a CachedStore of source using cache, both
+// keyed by K. With WriteBehind it starts the goroutine applying the writes,
+// which Close stops.
+func NewKeyedCachedStore[K comparable, T any](source, cache store.KeyedStore[K, T], opts ...Option) *CachedStore[K, T] {
+	o := options{flushInterval: time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	c := &CachedStore[K, T]{
+		source:  source,
+		cache:   cache,
+		o:       o,
+		key:     newKey[K, T](o.key),
+		now:     time.Now,
+		expires: map[K]time.Time{},
+		missing: map[K]time.Time{},
+		pending: map[K]pendingWrite[T]{},
+	}
+	if o.mode == WriteBehind {
+		c.stop, c.done = make(chan struct{}), make(chan struct{})
+		go c.flushLoop()
+	}
+	return c
+}
+
+func (c *CachedStore[K, T]) GetByID(ctx context.Context, id K) (*T, error) {
+	if entity, ok, err := c.cached(ctx, "GetByID", id); ok {
+		return entity, err
+	}
+
+	writes := c.writeCount()
+	entity, err := c.source.GetByID(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		c.remember(id, writes)
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	c.fill(ctx, id, entity, writes)
+	return entity, nil
+}
+
+// GetMultipleByID only asks the source for the entities not cached. Sources
+// may return them in any order, skipping the missing ones, so without WithKey
+// every miss is read on its own with GetByID.
+func (c *CachedStore[K, T]) GetMultipleByID(ctx context.Context, ids []K) ([]*T, error) {
+	ents := make([]*T, len(ids))
+	misses := []K{}
+	positions := []int{}
+	for i, id := range ids {
+		entity, ok, err := c.cached(ctx, "GetMultipleByID", id)
+		if !ok {
+			misses = append(misses, id)
+			positions = append(positions, i)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ents[i] = entity
+	}
+	if len(misses) == 0 {
+		return ents, nil
+	}
+
+	if c.key == nil {
+		for j, id := range misses {
+			entity, err := c.GetByID(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			ents[positions[j]] = entity
+		}
+		return ents, nil
+	}
+
+	writes := c.writeCount()
+	loaded, err := c.source.GetMultipleByID(ctx, misses)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+	byID := make(map[K]*T, len(loaded))
+	for _, entity := range loaded {
+		byID[c.key(entity)] = entity
+	}
+
+	var missing error
+	for j, id := range misses {
+		entity, ok := byID[id]
+		if !ok {
+			c.remember(id, writes)
+			if missing == nil {
+				missing = store.NewError(backend, "GetMultipleByID", id, store.ErrNotFound)
+			}
+			continue
+		}
+		// The same entity is handed out once per position
+		copied := *entity
+		ents[positions[j]] = &copied
+		c.fill(ctx, id, entity, writes)
+	}
+	if missing != nil {
+		return nil, missing
+	}
+	return ents, nil
+}
+
+// GetAll always reads the source, which is the only one having everything.
+// With WriteBehind, the queued writes are applied first.
+func (c *CachedStore[K, T]) GetAll(ctx context.Context) ([]*T, error) {
+	if c.o.mode == WriteBehind {
+		if err := c.Flush(ctx); err != nil {
+			return nil, store.NewError(backend, "GetAll", nil, err)
+		}
+	}
+	return c.source.GetAll(ctx)
+}
+
+func (c *CachedStore[K, T]) Insert(ctx context.Context, id K, entity *T) (*T, error) {
+	if c.o.mode == WriteBehind {
+		return c.insertBehind(ctx, id, entity)
+	}
+
+	writes := c.writing()
+	inserted, err := c.source.Insert(ctx, id, entity)
+	c.written(ctx, id, inserted, err, writes)
+	return inserted, err
+}
+
+func (c *CachedStore[K, T]) Delete(ctx context.Context, id K) error {
+	if c.o.mode == WriteBehind {
+		return c.deleteBehind(ctx, id)
+	}
+
+	writes := c.writing()
+	err := c.source.Delete(ctx, id)
+	c.written(ctx, id, nil, err, writes)
+	return err
+}
+
+func (c *CachedStore[K, T]) Update(ctx context.Context, id K, entity *T) error {
+	if c.o.mode == WriteBehind {
+		return c.replaceBehind(ctx, "Update", id, entity)
+	}
+
+	writes := c.writing()
+	err := c.source.Update(ctx, id, entity)
+	c.written(ctx, id, entity, err, writes)
+	return err
+}
+
+func (c *CachedStore[K, T]) Replace(ctx context.Context, id K, entity *T) error {
+	if c.o.mode == WriteBehind {
+		return c.replaceBehind(ctx, "Replace", id, entity)
+	}
+
+	writes := c.writing()
+	err := c.source.Replace(ctx, id, entity)
+	c.written(ctx, id, entity, err, writes)
+	return err
+}
+
+func (c *CachedStore[K, T]) Upsert(ctx context.Context, id K, entity *T) (bool, error) {
+	if c.o.mode == WriteBehind {
+		return c.upsertBehind(ctx, id, entity)
+	}
+
+	writes := c.writing()
+	created, err := c.source.Upsert(ctx, id, entity)
+	c.written(ctx, id, entity, err, writes)
+	return created, err
+}
+
+// cached looks for id in the cache. ok tells whether the answer is known
+// without the source, and then err is an ErrNotFound for the ids known to be
+// missing.
+func (c *CachedStore[K, T]) cached(ctx context.Context, op string, id K) (entity *T, ok bool, err error) {
+	now := c.now()
+
+	c.mu.Lock()
+	if expiration, found := c.missing[id]; found {
+		if now.Before(expiration) {
+			c.mu.Unlock()
+			return nil, true, store.NewError(backend, op, id, store.ErrNotFound)
+		}
+		delete(c.missing, id)
+	}
+	write, pending := c.pending[id]
+	expiration, cached := c.expires[id]
+	c.mu.Unlock()
+
+	if pending && write.deleted {
+		return nil, true, store.NewError(backend, op, id, store.ErrNotFound)
+	}
+
+	// The queued writes are not expired, because the source does not have
+	// them yet
+	fresh := pending || expiration.IsZero() || now.Before(expiration)
+	if cached && fresh {
+		entity, err := c.cache.GetByID(ctx, id)
+		if err == nil {
+			return entity, true, nil
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			c.report(err)
+		}
+	}
+
+	if pending {
+		// The cache lost it, so hand out a copy of the queued entity
+		copied := *write.entity
+		return &copied, true, nil
+	}
+	if cached {
+		c.forget(ctx, id)
+	}
+	return nil, false, nil
+}
+
+func (c *CachedStore[K, T]) writeCount() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writes
+}
+
+// fill caches an entity read from the source, unless there were writes since
+// it was read.
+func (c *CachedStore[K, T]) fill(ctx context.Context, id K, entity *T, writes uint64) {
+	if _, err := c.cache.Upsert(ctx, id, entity); err != nil {
+		c.report(err)
+		return
+	}
+
+	c.mu.Lock()
+	if c.writes != writes {
+		c.mu.Unlock()
+		c.forget(ctx, id)
+		return
+	}
+	c.expires[id] = c.expiration(c.o.ttl)
+	c.mu.Unlock()
+}
+
+// remember caches that the source did not find id.
+func (c *CachedStore[K, T]) remember(id K, writes uint64) {
+	if c.o.negativeTTL <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.writes == writes {
+		c.missing[id] = c.expiration(c.o.negativeTTL)
+	}
+}
+
+// writing counts a write to the source about to start, and returns the count
+// to give to written once it ends.
+func (c *CachedStore[K, T]) writing() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writes++
+	c.inflight++
+	return c.writes
+}
+
+// written updates the cache after a write to the source. WriteThrough caches
+// the written entity, and the rest drops it, like every failed write does.
+//
+// The source and the cache may apply concurrent writes in different orders,
+// so the entity is only kept when no other write ran at the same time: one
+// started or ended while this one ran, or is still running.
+func (c *CachedStore[K, T]) written(ctx context.Context, id K, entity *T, err error, writes uint64) {
+	c.mu.Lock()
+	c.writes++
+	c.inflight--
+	alone := c.writes == writes+1 && c.inflight == 0
+	writes = c.writes
+	delete(c.missing, id)
+	delete(c.expires, id)
+	c.mu.Unlock()
+
+	if c.o.mode != WriteThrough || err != nil || entity == nil || !alone {
+		c.forget(ctx, id)
+		return
+	}
+
+	if _, err := c.cache.Upsert(ctx, id, entity); err != nil {
+		c.report(err)
+		c.forget(ctx, id)
+		return
+	}
+	c.mu.Lock()
+	if c.writes != writes {
+		c.mu.Unlock()
+		c.forget(ctx, id)
+		return
+	}
+	c.expires[id] = c.expiration(c.o.ttl)
+	c.mu.Unlock()
+}
+
+// forget drops id from the cache.
+func (c *CachedStore[K, T]) forget(ctx context.Context, id K) {
+	c.mu.Lock()
+	delete(c.expires, id)
+	c.mu.Unlock()
+
+	if err := c.cache.Delete(ctx, id); err != nil && !errors.Is(err, store.ErrNotFound) {
+		c.report(err)
+	}
+}
+
+func (c *CachedStore[K, T]) expiration(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return c.now().Add(ttl)
+}
+
+func (c *CachedStore[K, T]) report(err error) {
+	if c.o.onError != nil {
+		c.o.onError(err)
+	}
+}
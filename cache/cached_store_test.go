@@ -0,0 +1,420 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/memory"
+	"github.com/Silencevoice/go-store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestEntity struct {
+	ID    string
+	Value string
+}
+
+var _ gostore.Store[TestEntity] = (*CachedStore[string, TestEntity])(nil)
+
+// countingStore counts the reads reaching a store, and fails the writes when
+// err is set.
+type countingStore struct {
+	gostore.Store[TestEntity]
+	mu      sync.Mutex
+	reads   []string
+	upserts int
+	err     error
+	// onRead runs after reading, and onUpsert after upserting, before
+	// returning
+	onRead   func()
+	onUpsert func()
+}
+
+func newSource() *countingStore {
+	return &countingStore{Store: memory.NewMemStore[TestEntity]()}
+}
+
+func (s *countingStore) failure() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *countingStore) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *countingStore) readCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.reads)
+}
+
+func (s *countingStore) GetByID(ctx context.Context, id string) (*TestEntity, error) {
+	s.mu.Lock()
+	s.reads = append(s.reads, id)
+	s.mu.Unlock()
+
+	entity, err := s.Store.GetByID(ctx, id)
+	if s.onRead != nil {
+		s.onRead()
+	}
+	return entity, err
+}
+
+func (s *countingStore) GetMultipleByID(ctx context.Context, ids []string) ([]*TestEntity, error) {
+	s.mu.Lock()
+	s.reads = append(s.reads, ids...)
+	s.mu.Unlock()
+	return s.Store.GetMultipleByID(ctx, ids)
+}
+
+func (s *countingStore) Upsert(ctx context.Context, id string, entity *TestEntity) (bool, error) {
+	if err := s.failure(); err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	s.upserts++
+	s.mu.Unlock()
+
+	created, err := s.Store.Upsert(ctx, id, entity)
+	if s.onUpsert != nil {
+		s.onUpsert()
+	}
+	return created, err
+}
+
+func (s *countingStore) Insert(ctx context.Context, id string, entity *TestEntity) (*TestEntity, error) {
+	if err := s.failure(); err != nil {
+		return nil, err
+	}
+	return s.Store.Insert(ctx, id, entity)
+}
+
+func TestConformance(t *testing.T) {
+	for name, mode := range map[string]Mode{"Read-through": ReadThrough, "Write-through": WriteThrough, "Write-behind": WriteBehind} {
+		t.Run(name, func(t *testing.T) {
+			storetest.Run(t, func(t *testing.T) gostore.KeyedStore[string, storetest.Entity] {
+				source, cache := memory.NewMemStore[storetest.Entity](), memory.NewMemStore[storetest.Entity]()
+				cached := NewCachedStore[storetest.Entity](source, cache, WithMode(mode))
+				t.Cleanup(func() { cached.Close(context.Background()) })
+				return cached
+			})
+		})
+	}
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time { return c.now }
+
+func TestReadThrough(t *testing.T) {
+	ctx := context.Background()
+	source := newSource()
+	source.Store.Insert(ctx, "1", &TestEntity{ID: "1", Value: "v1"})
+	cached := NewCachedStore[TestEntity](source, memory.NewMemStore[TestEntity]())
+
+	t.Run("Hits are served by the cache", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			entity, err := cached.GetByID(ctx, "1")
+			require.NoError(t, err)
+			assert.Equal(t, "v1", entity.Value)
+		}
+		assert.Equal(t, 1, source.readCount())
+	})
+
+	t.Run("Returned entities are copies", func(t *testing.T) {
+		entity, err := cached.GetByID(ctx, "1")
+		require.NoError(t, err)
+		entity.Value = "changed"
+
+		entity, err = cached.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "v1", entity.Value)
+	})
+
+	t.Run("Update invalidates", func(t *testing.T) {
+		require.NoError(t, cached.Update(ctx, "1", &TestEntity{ID: "1", Value: "v2"}))
+		reads := source.readCount()
+
+		entity, err := cached.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "v2", entity.Value)
+		assert.Equal(t, reads+1, source.readCount())
+	})
+
+	t.Run("Delete invalidates", func(t *testing.T) {
+		require.NoError(t, cached.Delete(ctx, "1"))
+		_, err := cached.GetByID(ctx, "1")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+
+		err = cached.Delete(ctx, "1")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+	})
+
+	t.Run("Source errors are returned as they are", func(t *testing.T) {
+		_, err := cached.Insert(ctx, "2", &TestEntity{ID: "2"})
+		require.NoError(t, err)
+		_, err = cached.Insert(ctx, "2", &TestEntity{ID: "2"})
+
+		var storeErr *gostore.Error
+		require.ErrorAs(t, err, &storeErr)
+		assert.Equal(t, "memory", storeErr.Backend)
+		assert.ErrorIs(t, err, gostore.ErrAlreadyExists)
+	})
+
+	t.Run("Concurrent write while reading the source", func(t *testing.T) {
+		source.Store.Upsert(ctx, "3", &TestEntity{ID: "3", Value: "old"})
+		source.onRead = func() {
+			source.onRead = nil
+			require.NoError(t, cached.Update(ctx, "3", &TestEntity{ID: "3", Value: "new"}))
+		}
+
+		entity, err := cached.GetByID(ctx, "3")
+		require.NoError(t, err)
+		assert.Equal(t, "old", entity.Value)
+
+		// The old value was not cached
+		entity, err = cached.GetByID(ctx, "3")
+		require.NoError(t, err)
+		assert.Equal(t, "new", entity.Value)
+	})
+}
+
+func TestWriteThrough(t *testing.T) {
+	ctx := context.Background()
+	source := newSource()
+	cache := memory.NewMemStore[TestEntity]()
+	cached := NewCachedStore[TestEntity](source, cache, WithMode(WriteThrough))
+
+	_, err := cached.Insert(ctx, "1", &TestEntity{ID: "1", Value: "v1"})
+	require.NoError(t, err)
+	entity, err := cached.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", entity.Value)
+
+	require.NoError(t, cached.Update(ctx, "1", &TestEntity{ID: "1", Value: "v2"}))
+	entity, err = cached.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", entity.Value)
+
+	created, err := cached.Upsert(ctx, "2", &TestEntity{ID: "2", Value: "v1"})
+	require.NoError(t, err)
+	assert.True(t, created)
+	require.NoError(t, cached.Replace(ctx, "2", &TestEntity{ID: "2"}))
+	entity, err = cached.GetByID(ctx, "2")
+	require.NoError(t, err)
+	assert.Equal(t, &TestEntity{ID: "2"}, entity)
+
+	assert.Zero(t, source.readCount())
+
+	t.Run("Concurrent writes applied in another order", func(t *testing.T) {
+		// The source applies A and then B, but the cache gets B first
+		source.onUpsert = func() {
+			source.onUpsert = nil
+			_, err := cached.Upsert(ctx, "3", &TestEntity{ID: "3", Value: "B"})
+			require.NoError(t, err)
+		}
+		_, err := cached.Upsert(ctx, "3", &TestEntity{ID: "3", Value: "A"})
+		require.NoError(t, err)
+
+		entity, err := cached.GetByID(ctx, "3")
+		require.NoError(t, err)
+		assert.Equal(t, "B", entity.Value)
+	})
+
+	t.Run("Failed writes drop the cached entity", func(t *testing.T) {
+		err := cached.Update(ctx, "missing", &TestEntity{})
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+		_, err = cache.GetByID(ctx, "missing")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+	})
+
+	t.Run("Cache failures are only reported", func(t *testing.T) {
+		reported := []error{}
+		broken := NewCachedStore[TestEntity](source, brokenCache{}, WithMode(WriteThrough),
+			WithErrorHandler(func(err error) { reported = append(reported, err) }))
+
+		require.NoError(t, broken.Update(ctx, "1", &TestEntity{ID: "1", Value: "v3"}))
+		entity, err := broken.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "v3", entity.Value)
+		assert.NotEmpty(t, reported)
+	})
+}
+
+// brokenCache fails every call.
+type brokenCache struct {
+	gostore.Store[TestEntity]
+}
+
+var errBroken = errors.New("cache down")
+
+func (brokenCache) GetByID(ctx context.Context, id string) (*TestEntity, error) {
+	return nil, errBroken
+}
+
+func (brokenCache) Upsert(ctx context.Context, id string, entity *TestEntity) (bool, error) {
+	return false, errBroken
+}
+
+func (brokenCache) Delete(ctx context.Context, id string) error {
+	return errBroken
+}
+
+func TestTTL(t *testing.T) {
+	ctx := context.Background()
+	source := newSource()
+	source.Store.Insert(ctx, "1", &TestEntity{ID: "1"})
+	clock := &clock{now: time.Now()}
+	cached := NewCachedStore[TestEntity](source, memory.NewMemStore[TestEntity](), WithTTL(time.Minute))
+	cached.now = clock.Now
+
+	cached.GetByID(ctx, "1")
+	clock.now = clock.now.Add(59 * time.Second)
+	cached.GetByID(ctx, "1")
+	assert.Equal(t, 1, source.readCount())
+
+	clock.now = clock.now.Add(time.Second)
+	cached.GetByID(ctx, "1")
+	assert.Equal(t, 2, source.readCount())
+
+	// Cached again from the last read
+	clock.now = clock.now.Add(30 * time.Second)
+	cached.GetByID(ctx, "1")
+	assert.Equal(t, 2, source.readCount())
+}
+
+func TestNegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	source := newSource()
+	clock := &clock{now: time.Now()}
+	cached := NewCachedStore[TestEntity](source, memory.NewMemStore[TestEntity](), WithNegativeTTL(time.Minute))
+	cached.now = clock.Now
+
+	for i := 0; i < 3; i++ {
+		_, err := cached.GetByID(ctx, "1")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+	}
+	assert.Equal(t, 1, source.readCount())
+
+	_, err := cached.GetMultipleByID(ctx, []string{"1"})
+	assert.ErrorIs(t, err, gostore.ErrNotFound)
+	assert.Equal(t, 1, source.readCount())
+
+	clock.now = clock.now.Add(time.Minute)
+	_, err = cached.GetByID(ctx, "1")
+	assert.ErrorIs(t, err, gostore.ErrNotFound)
+	assert.Equal(t, 2, source.readCount())
+
+	t.Run("Writes forget the missing ids", func(t *testing.T) {
+		_, err := cached.Insert(ctx, "1", &TestEntity{ID: "1"})
+		require.NoError(t, err)
+		_, err = cached.GetByID(ctx, "1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Not without a negative TTL", func(t *testing.T) {
+		source := newSource()
+		cached := NewCachedStore[TestEntity](source, memory.NewMemStore[TestEntity]())
+		cached.GetByID(ctx, "1")
+		cached.GetByID(ctx, "1")
+		assert.Equal(t, 2, source.readCount())
+	})
+}
+
+func TestGetMultipleByID(t *testing.T) {
+	ctx := context.Background()
+	source := newSource()
+	for _, id := range []string{"1", "2", "3"} {
+		source.Store.Insert(ctx, id, &TestEntity{ID: id})
+	}
+	cached := NewCachedStore[TestEntity](source, memory.NewMemStore[TestEntity]())
+
+	cached.GetByID(ctx, "2")
+	entities, err := cached.GetMultipleByID(ctx, []string{"3", "2", "1"})
+	require.NoError(t, err)
+	assert.Equal(t, []*TestEntity{{ID: "3"}, {ID: "2"}, {ID: "1"}}, entities)
+	assert.Equal(t, []string{"2", "3", "1"}, source.reads)
+
+	entities, err = cached.GetMultipleByID(ctx, []string{"1", "3"})
+	require.NoError(t, err)
+	assert.Len(t, entities, 2)
+	assert.Len(t, source.reads, 3)
+
+	_, err = cached.GetMultipleByID(ctx, []string{"1", "missing"})
+	assert.ErrorIs(t, err, gostore.ErrNotFound)
+
+	all, err := cached.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+}
+
+// unorderedStore answers GetMultipleByID like a Mongo $in query: the found
+// entities in reverse order, without the missing ones.
+type unorderedStore struct {
+	*countingStore
+}
+
+func (s unorderedStore) GetMultipleByID(ctx context.Context, ids []string) ([]*TestEntity, error) {
+	s.mu.Lock()
+	s.reads = append(s.reads, ids...)
+	s.mu.Unlock()
+
+	ents := []*TestEntity{}
+	for i := len(ids) - 1; i >= 0; i-- {
+		if entity, err := s.Store.GetByID(ctx, ids[i]); err == nil {
+			ents = append(ents, entity)
+		}
+	}
+	return ents, nil
+}
+
+func TestGetMultipleByID_UnorderedSource(t *testing.T) {
+	ctx := context.Background()
+
+	for name, opts := range map[string][]Option{
+		"One read per miss": nil,
+		"With key":          {WithKey(func(e *TestEntity) string { return e.ID })},
+	} {
+		t.Run(name, func(t *testing.T) {
+			source := unorderedStore{newSource()}
+			for _, id := range []string{"1", "2", "3"} {
+				source.Store.Insert(ctx, id, &TestEntity{ID: id, Value: "v" + id})
+			}
+			cached := NewCachedStore[TestEntity](source, memory.NewMemStore[TestEntity](),
+				append([]Option{WithNegativeTTL(time.Minute)}, opts...)...)
+
+			entities, err := cached.GetMultipleByID(ctx, []string{"1", "2", "3", "1"})
+			require.NoError(t, err)
+			assert.Equal(t, []*TestEntity{{ID: "1", Value: "v1"}, {ID: "2", Value: "v2"}, {ID: "3", Value: "v3"}, {ID: "1", Value: "v1"}}, entities)
+			for _, id := range []string{"1", "2", "3"} {
+				entity, err := cached.GetByID(ctx, id)
+				require.NoError(t, err)
+				assert.Equal(t, "v"+id, entity.Value)
+			}
+
+			_, err = cached.GetMultipleByID(ctx, []string{"4", "2"})
+			assert.ErrorIs(t, err, gostore.ErrNotFound)
+			reads := source.readCount()
+			_, err = cached.GetByID(ctx, "4")
+			assert.ErrorIs(t, err, gostore.ErrNotFound)
+			assert.Equal(t, reads, source.readCount())
+		})
+	}
+
+	t.Run("Key of the wrong type", func(t *testing.T) {
+		assert.Panics(t, func() {
+			NewCachedStore[TestEntity](newSource(), memory.NewMemStore[TestEntity](), WithKey(func(e *TestEntity) int { return 0 }))
+		})
+	})
+}
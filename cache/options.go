@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// Mode decides what the writes do with the cache. Reads are read-through in
+// every mode: a miss loads the entity from the source and keeps it.
+type Mode int
+
+const (
+	// ReadThrough writes to the source and drops the cached entity.
+	ReadThrough Mode = iota
+	// WriteThrough writes to the source and then to the cache.
+	WriteThrough
+	// WriteBehind writes to the cache and queues the write, which a
+	// background goroutine applies to the source every flush interval.
+	WriteBehind
+)
+
+// Option configures a CachedStore.
+type Option func(*options)
+
+type options struct {
+	mode          Mode
+	ttl           time.Duration
+	negativeTTL   time.Duration
+	flushInterval time.Duration
+	onError       func(error)
+	key           any
+}
+
+// WithMode sets the Mode, ReadThrough by default.
+func WithMode(mode Mode) Option {
+	return func(o *options) {
+		o.mode = mode
+	}
+}
+
+// WithTTL sets how long every entity stays cached, counting from when it was
+// cached. The default 0 keeps them until they are written.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithNegativeTTL caches the ids the source did not find for ttl, so asking
+// for them again does not reach the source.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithFlushInterval sets how often WriteBehind applies the queued writes. The
+// default is one second.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.flushInterval = interval
+	}
+}
+
+// WithErrorHandler receives the errors nobody else sees: the failed cache
+// writes, which never fail the operation because the source already has the
+// change, and the failed background flushes of WriteBehind.
+func WithErrorHandler(handler func(error)) Option {
+	return func(o *options) {
+		o.onError = handler
+	}
+}
+
+// WithKey tells how to get the id of an entity, so GetMultipleByID can ask
+// the source for all the misses at once and match what it returns by id,
+// whatever order it uses.
+func WithKey[K comparable, T any](key func(entity *T) K) Option {
+	return func(o *options) {
+		o.key = key
+	}
+}
+
+func newKey[K comparable, T any](key any) func(*T) K {
+	if key == nil {
+		return nil
+	}
+	fn, ok := key.(func(*T) K)
+	if !ok {
+		var id K
+		var zero T
+		panic(fmt.Sprintf("cache: key function is not defined for %T keys and %T entities", id, zero))
+	}
+	return fn
+}
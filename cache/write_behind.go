@@ -0,0 +1,218 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"time"
+
+	store "github.com/Silencevoice/go-store"
+)
+
+// ErrClosed is returned by the WriteBehind writes after Close.
+var ErrClosed = errors.New("cached store closed")
+
+// pendingWrite is a write queued for the source. Only the last write of every
+// id is kept.
+type pendingWrite[T any] struct {
+	entity  *T
+	deleted bool
+	seq     uint64
+}
+
+// exists tells whether id exists, as seen through the cache.
+func (c *CachedStore[K, T]) exists(ctx context.Context, id K) (bool, error) {
+	if _, ok, err := c.cached(ctx, "", id); ok {
+		return err == nil, nil
+	}
+
+	_, err := c.source.GetByID(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// queue writes entity, or the deletion of id when it is nil, to the cache
+// and queues it for the source.
+func (c *CachedStore[K, T]) queue(ctx context.Context, id K, entity *T) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.writes++
+	delete(c.missing, id)
+	c.mu.Unlock()
+
+	write := pendingWrite[T]{deleted: entity == nil}
+	if entity != nil {
+		if _, err := c.cache.Upsert(ctx, id, entity); err != nil {
+			return err
+		}
+		// Queue the copy kept by the cache, which nobody else has
+		stored, err := c.cache.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		write.entity = stored
+	} else if err := c.cache.Delete(ctx, id); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	write.seq = c.seq
+	c.pending[id] = write
+	if entity != nil {
+		c.expires[id] = c.expiration(c.o.ttl)
+	} else {
+		delete(c.expires, id)
+	}
+	return nil
+}
+
+func (c *CachedStore[K, T]) insertBehind(ctx context.Context, id K, entity *T) (*T, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	exists, err := c.exists(ctx, id)
+	if err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
+	if exists {
+		return nil, store.NewError(backend, "Insert", id, store.ErrAlreadyExists)
+	}
+	if err := c.queue(ctx, id, entity); err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
+
+	inserted, err := c.cache.GetByID(ctx, id)
+	if err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
+	return inserted, nil
+}
+
+func (c *CachedStore[K, T]) deleteBehind(ctx context.Context, id K) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	exists, err := c.exists(ctx, id)
+	if err != nil {
+		return store.NewError(backend, "Delete", id, err)
+	}
+	if !exists {
+		return store.NewError(backend, "Delete", id, store.ErrNotFound)
+	}
+	if err := c.queue(ctx, id, nil); err != nil {
+		return store.NewError(backend, "Delete", id, err)
+	}
+	return nil
+}
+
+func (c *CachedStore[K, T]) replaceBehind(ctx context.Context, op string, id K, entity *T) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	exists, err := c.exists(ctx, id)
+	if err != nil {
+		return store.NewError(backend, op, id, err)
+	}
+	if !exists {
+		return store.NewError(backend, op, id, store.ErrNotFound)
+	}
+	if err := c.queue(ctx, id, entity); err != nil {
+		return store.NewError(backend, op, id, err)
+	}
+	return nil
+}
+
+func (c *CachedStore[K, T]) upsertBehind(ctx context.Context, id K, entity *T) (bool, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	exists, err := c.exists(ctx, id)
+	if err != nil {
+		return false, store.NewError(backend, "Upsert", id, err)
+	}
+	if err := c.queue(ctx, id, entity); err != nil {
+		return false, store.NewError(backend, "Upsert", id, err)
+	}
+	return !exists, nil
+}
+
+// Flush applies the queued writes of WriteBehind to the source. The ones
+// that fail stay queued, to be tried again, and their errors are returned.
+func (c *CachedStore[K, T]) Flush(ctx context.Context) error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	batch := maps.Clone(c.pending)
+	c.mu.Unlock()
+
+	errs := []error{}
+	for id, write := range batch {
+		var err error
+		if write.deleted {
+			err = c.source.Delete(ctx, id)
+			if errors.Is(err, store.ErrNotFound) {
+				err = nil
+			}
+		} else {
+			_, err = c.source.Upsert(ctx, id, write.entity)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// Unless it was written again meanwhile
+		c.mu.Lock()
+		if current, ok := c.pending[id]; ok && current.seq == write.seq {
+			delete(c.pending, id)
+		}
+		c.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+func (c *CachedStore[K, T]) flushLoop() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.o.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			if err := c.Flush(context.Background()); err != nil {
+				c.report(err)
+			}
+		}
+	}
+}
+
+// Close stops WriteBehind, applying the queued writes a last time. The
+// writes fail with ErrClosed from then on. It does nothing in the other
+// modes.
+func (c *CachedStore[K, T]) Close(ctx context.Context) error {
+	if c.o.mode != WriteBehind {
+		return nil
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+
+	close(c.stop)
+	<-c.done
+	return c.Flush(ctx)
+}
@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWriteBehind(t *testing.T, source *countingStore, opts ...Option) (*CachedStore[string, TestEntity], *memory.MemStore[string, TestEntity]) {
+	cache := memory.NewMemStore[TestEntity]()
+	cached := NewCachedStore[TestEntity](source, cache, append([]Option{WithMode(WriteBehind), WithFlushInterval(time.Hour)}, opts...)...)
+	t.Cleanup(func() { cached.Close(context.Background()) })
+	return cached, cache
+}
+
+func TestWriteBehind(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Writes reach the source on Flush", func(t *testing.T) {
+		source := newSource()
+		source.Store.Insert(ctx, "old", &TestEntity{ID: "old"})
+		cached, _ := newWriteBehind(t, source)
+
+		inserted, err := cached.Insert(ctx, "1", &TestEntity{ID: "1", Value: "v1"})
+		require.NoError(t, err)
+		assert.Equal(t, &TestEntity{ID: "1", Value: "v1"}, inserted)
+		require.NoError(t, cached.Update(ctx, "1", &TestEntity{ID: "1", Value: "v2"}))
+		require.NoError(t, cached.Delete(ctx, "old"))
+
+		entity, err := cached.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "v2", entity.Value)
+		_, err = cached.GetByID(ctx, "old")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+
+		_, err = source.Store.GetByID(ctx, "1")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+		_, err = source.Store.GetByID(ctx, "old")
+		assert.NoError(t, err)
+
+		require.NoError(t, cached.Flush(ctx))
+		entity, err = source.Store.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "v2", entity.Value)
+		_, err = source.Store.GetByID(ctx, "old")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+
+		// Only the last write of every id is applied
+		assert.Equal(t, 1, source.upserts)
+	})
+
+	t.Run("Same errors as the source", func(t *testing.T) {
+		source := newSource()
+		source.Store.Insert(ctx, "1", &TestEntity{ID: "1"})
+		cached, _ := newWriteBehind(t, source)
+
+		_, err := cached.Insert(ctx, "1", &TestEntity{})
+		assert.ErrorIs(t, err, gostore.ErrAlreadyExists)
+		err = cached.Update(ctx, "2", &TestEntity{})
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+		err = cached.Replace(ctx, "2", &TestEntity{})
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+
+		require.NoError(t, cached.Delete(ctx, "1"))
+		err = cached.Delete(ctx, "1")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+
+		created, err := cached.Upsert(ctx, "1", &TestEntity{ID: "1"})
+		require.NoError(t, err)
+		assert.True(t, created)
+		created, err = cached.Upsert(ctx, "1", &TestEntity{ID: "1"})
+		require.NoError(t, err)
+		assert.False(t, created)
+	})
+
+	t.Run("GetAll flushes first", func(t *testing.T) {
+		source := newSource()
+		cached, _ := newWriteBehind(t, source)
+		cached.Insert(ctx, "1", &TestEntity{ID: "1"})
+
+		all, err := cached.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+	})
+
+	t.Run("Queued writes do not expire", func(t *testing.T) {
+		source := newSource()
+		clock := &clock{now: time.Now()}
+		cached, cache := newWriteBehind(t, source, WithTTL(time.Second))
+		cached.now = clock.Now
+
+		cached.Insert(ctx, "1", &TestEntity{ID: "1", Value: "queued"})
+		reads := source.readCount()
+		clock.now = clock.now.Add(time.Hour)
+		entity, err := cached.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "queued", entity.Value)
+
+		// Not even when the cache loses them
+		require.NoError(t, cache.Delete(ctx, "1"))
+		entity, err = cached.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "queued", entity.Value)
+		assert.Equal(t, reads, source.readCount())
+	})
+
+	t.Run("Failed writes stay queued", func(t *testing.T) {
+		source := newSource()
+		cached, _ := newWriteBehind(t, source)
+		cached.Insert(ctx, "1", &TestEntity{ID: "1"})
+
+		source.fail(errors.New("source down"))
+		assert.ErrorContains(t, cached.Flush(ctx), "source down")
+
+		source.fail(nil)
+		require.NoError(t, cached.Flush(ctx))
+		_, err := source.Store.GetByID(ctx, "1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Background flush", func(t *testing.T) {
+		source := newSource()
+		var mu sync.Mutex
+		reported := []error{}
+		cached, _ := newWriteBehind(t, source, WithFlushInterval(10*time.Millisecond), WithErrorHandler(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			reported = append(reported, err)
+		}))
+
+		source.fail(errors.New("source down"))
+		cached.Insert(ctx, "1", &TestEntity{ID: "1"})
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(reported) > 0
+		}, time.Second, 5*time.Millisecond)
+
+		source.fail(nil)
+		assert.Eventually(t, func() bool {
+			_, err := source.Store.GetByID(ctx, "1")
+			return err == nil
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("Close flushes", func(t *testing.T) {
+		source := newSource()
+		cached, _ := newWriteBehind(t, source)
+		cached.Insert(ctx, "1", &TestEntity{ID: "1"})
+
+		require.NoError(t, cached.Close(ctx))
+		require.NoError(t, cached.Close(ctx))
+		_, err := source.Store.GetByID(ctx, "1")
+		assert.NoError(t, err)
+
+		_, err = cached.Insert(ctx, "2", &TestEntity{ID: "2"})
+		assert.ErrorIs(t, err, ErrClosed)
+		_, err = cached.GetByID(ctx, "1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Close does nothing in the other modes", func(t *testing.T) {
+		cached := NewCachedStore[TestEntity](newSource(), memory.NewMemStore[TestEntity]())
+		assert.NoError(t, cached.Close(ctx))
+	})
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel errors shared by every Store backend. Backends wrap them in an
+// *Error, so callers should compare with errors.Is instead of the message.
+var (
+	ErrNotFound        = errors.New("entity not found")
+	ErrAlreadyExists   = errors.New("already existing key")
+	ErrInvalidID       = errors.New("invalid ID format")
+	ErrInvalidCursor   = errors.New("invalid page cursor")
+	ErrVersionConflict = errors.New("version conflict")
+	ErrInvalidPatch    = errors.New("invalid patch")
+	ErrUniqueViolation = errors.New("unique index violation")
+)
+
+// Error describes a failed store operation: which backend failed, on which
+// operation and key, and the underlying cause.
+type Error struct {
+	Op      string
+	Key     string
+	Backend string
+	Err     error
+}
+
+// NewError returns the *Error of op failing on backend. The key is formatted
+// with fmt.Sprint, and left empty when it is nil, for the operations on no
+// key in particular.
+func NewError(backend, op string, key any, err error) error {
+	storeErr := &Error{Op: op, Backend: backend, Err: err}
+	if key != nil {
+		storeErr.Key = fmt.Sprint(key)
+	}
+	return storeErr
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	if e.Backend != "" {
+		b.WriteString(e.Backend)
+		b.WriteString(": ")
+	}
+	if e.Op != "" {
+		b.WriteString(e.Op)
+		if e.Key != "" {
+			b.WriteString(" ")
+			b.WriteString(e.Key)
+		}
+		b.WriteString(": ")
+	}
+	if e.Err != nil {
+		b.WriteString(e.Err.Error())
+	}
+	return b.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
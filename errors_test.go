@@ -0,0 +1,43 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError(t *testing.T) {
+	t.Run("Error message", func(t *testing.T) {
+		err := &Error{Op: "GetByID", Key: "1", Backend: "memory", Err: ErrNotFound}
+		assert.Equal(t, "memory: GetByID 1: entity not found", err.Error())
+	})
+
+	t.Run("Error message without key", func(t *testing.T) {
+		err := &Error{Op: "GetAll", Backend: "mongo", Err: errors.New("connection refused")}
+		assert.Equal(t, "mongo: GetAll: connection refused", err.Error())
+	})
+
+	t.Run("NewError", func(t *testing.T) {
+		assert.Equal(t, &Error{Op: "GetByID", Key: "42", Backend: "memory", Err: ErrNotFound}, NewError("memory", "GetByID", 42, ErrNotFound))
+		assert.Equal(t, &Error{Op: "GetAll", Backend: "memory", Err: ErrNotFound}, NewError("memory", "GetAll", nil, ErrNotFound))
+	})
+
+	t.Run("Is and As through wrapping", func(t *testing.T) {
+		cause := errors.New("driver failure")
+		var err error = &Error{Op: "Insert", Key: "1", Backend: "mongo", Err: fmt.Errorf("%w: %w", ErrAlreadyExists, cause)}
+		err = fmt.Errorf("service: %w", err)
+
+		assert.ErrorIs(t, err, ErrAlreadyExists)
+		assert.ErrorIs(t, err, cause)
+		assert.NotErrorIs(t, err, ErrNotFound)
+
+		var storeErr *Error
+		require.ErrorAs(t, err, &storeErr)
+		assert.Equal(t, "Insert", storeErr.Op)
+		assert.Equal(t, "1", storeErr.Key)
+		assert.Equal(t, "mongo", storeErr.Backend)
+	})
+}
@@ -6,11 +6,13 @@ import (
 )
 
 type CarRepository struct {
-	memory.MemStore[model.Car]
+	*memory.MemStore[string, model.Car]
 }
 
 func NewCarRepository() *CarRepository {
 	return &CarRepository{
-		MemStore: *memory.NewMemStore[model.Car](),
+		MemStore: memory.NewMemStore[model.Car](
+			memory.WithIndex("model", func(c model.Car) string { return c.Model }),
+		),
 	}
 }
@@ -30,17 +30,7 @@ func (s CarServiceImpl) FindCarById(ctx context.Context, id string) (*model.Car,
 }
 
 func (s CarServiceImpl) FindCarsByModel(ctx context.Context, carModel string) ([]*model.Car, error) {
-	filterFunc := func(ctx context.Context, data map[string]model.Car) ([]*model.Car, error) {
-		var result []*model.Car
-		for _, car := range data {
-			if car.Model == carModel {
-				result = append(result, &car)
-			}
-		}
-		return result, nil
-	}
-
-	return s.repo.ExecuteQuery(ctx, filterFunc)
+	return s.repo.FindByIndex(ctx, "model", carModel)
 }
 
 func NewCarService(carRepo *repository.CarRepository) CarService {
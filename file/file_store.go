@@ -0,0 +1,235 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+
+	store "github.com/Silencevoice/go-store"
+)
+
+const backend = "file"
+
+// FileStore keeps its entities as JSON in a directory. Nothing is cached:
+// every call reads what it needs from disk, so several FileStores, even in
+// different processes, can share the directory. A lock file serializes their
+// writes, and every write replaces its file atomically.
+type FileStore[T any] struct {
+	mu     *sync.RWMutex
+	layout layout[T]
+	lock   string
+}
+
+// locks has the in-process lock of every lock file, shared by the FileStores
+// of the same directory.
+var locks sync.Map
+
+// NewFileStore returns a FileStore keeping one JSON file per entity in dir,
+// unless WithJSONLines says otherwise. dir is created when missing.
+func NewFileStore[T any](dir string, opts ...Option) (*FileStore[T], error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, store.NewError(backend, "Open", nil, err)
+	}
+
+	f := &FileStore[T]{layout: &entityFiles[T]{dir: dir}, lock: filepath.Join(dir, ".lock")}
+	if o.collection != "" {
+		path := filepath.Join(dir, o.collection+".jsonl")
+		f.layout, f.lock = &jsonLines[T]{path: path}, path+".lock"
+	}
+
+	lock, err := filepath.Abs(f.lock)
+	if err != nil {
+		return nil, store.NewError(backend, "Open", nil, err)
+	}
+	mu, _ := locks.LoadOrStore(lock, &sync.RWMutex{})
+	f.mu = mu.(*sync.RWMutex)
+	return f, nil
+}
+
+// acquire takes the lock of the store, shared for reads. The in-process lock
+// is taken too, because file locks are not available everywhere.
+func (f *FileStore[T]) acquire(ctx context.Context, write bool) (release func(), err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if write {
+		f.mu.Lock()
+	} else {
+		f.mu.RLock()
+	}
+	unlock, err := lockFile(f.lock, write)
+	if err != nil {
+		if write {
+			f.mu.Unlock()
+		} else {
+			f.mu.RUnlock()
+		}
+		return nil, err
+	}
+	return func() {
+		unlock()
+		if write {
+			f.mu.Unlock()
+		} else {
+			f.mu.RUnlock()
+		}
+	}, nil
+}
+
+func (f *FileStore[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	release, err := f.acquire(ctx, false)
+	if err != nil {
+		return nil, store.NewError(backend, "GetByID", id, err)
+	}
+	defer release()
+
+	ent, err := f.layout.get(id)
+	if err != nil {
+		return nil, store.NewError(backend, "GetByID", id, err)
+	}
+	if ent == nil {
+		return nil, store.NewError(backend, "GetByID", id, store.ErrNotFound)
+	}
+	return ent, nil
+}
+
+func (f *FileStore[T]) GetMultipleByID(ctx context.Context, ids []string) ([]*T, error) {
+	release, err := f.acquire(ctx, false)
+	if err != nil {
+		return nil, store.NewError(backend, "GetMultipleByID", nil, err)
+	}
+	defer release()
+
+	ents := make([]*T, len(ids))
+	for idx, id := range ids {
+		ent, err := f.layout.get(id)
+		if err != nil {
+			return nil, store.NewError(backend, "GetMultipleByID", id, err)
+		}
+		if ent == nil {
+			return nil, store.NewError(backend, "GetMultipleByID", id, store.ErrNotFound)
+		}
+		ents[idx] = ent
+	}
+
+	return ents, nil
+}
+
+func (f *FileStore[T]) GetAll(ctx context.Context) ([]*T, error) {
+	release, err := f.acquire(ctx, false)
+	if err != nil {
+		return nil, store.NewError(backend, "GetAll", nil, err)
+	}
+	defer release()
+
+	entries, err := f.layout.all()
+	if err != nil {
+		return nil, store.NewError(backend, "GetAll", nil, err)
+	}
+
+	ents := make([]*T, len(entries))
+	for i, entry := range entries {
+		ents[i] = entry.Entity
+	}
+	return ents, nil
+}
+
+func (f *FileStore[T]) Insert(ctx context.Context, id string, entity *T) (*T, error) {
+	release, err := f.acquire(ctx, true)
+	if err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
+	defer release()
+
+	current, err := f.layout.get(id)
+	if err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
+	if current != nil {
+		return nil, store.NewError(backend, "Insert", id, store.ErrAlreadyExists)
+	}
+
+	if err := f.layout.put(id, entity); err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
+
+	// Decode the file just written, so the result lacks what JSON leaves out,
+	// as every later read will
+	ent, err := f.layout.get(id)
+	if err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
+	return ent, nil
+}
+
+func (f *FileStore[T]) Delete(ctx context.Context, id string) error {
+	release, err := f.acquire(ctx, true)
+	if err != nil {
+		return store.NewError(backend, "Delete", id, err)
+	}
+	defer release()
+
+	ok, err := f.layout.remove(id)
+	if err != nil {
+		return store.NewError(backend, "Delete", id, err)
+	}
+	if !ok {
+		return store.NewError(backend, "Delete", id, store.ErrNotFound)
+	}
+	return nil
+}
+
+func (f *FileStore[T]) Update(ctx context.Context, id string, entity *T) error {
+	return f.replace(ctx, "Update", id, entity)
+}
+
+// Replace writes a new file over the one of the entity, exactly as Update
+// does.
+func (f *FileStore[T]) Replace(ctx context.Context, id string, entity *T) error {
+	return f.replace(ctx, "Replace", id, entity)
+}
+
+func (f *FileStore[T]) replace(ctx context.Context, op string, id string, entity *T) error {
+	release, err := f.acquire(ctx, true)
+	if err != nil {
+		return store.NewError(backend, op, id, err)
+	}
+	defer release()
+
+	current, err := f.layout.get(id)
+	if err != nil {
+		return store.NewError(backend, op, id, err)
+	}
+	if current == nil {
+		return store.NewError(backend, op, id, store.ErrNotFound)
+	}
+
+	if err := f.layout.put(id, entity); err != nil {
+		return store.NewError(backend, op, id, err)
+	}
+	return nil
+}
+
+func (f *FileStore[T]) Upsert(ctx context.Context, id string, entity *T) (bool, error) {
+	release, err := f.acquire(ctx, true)
+	if err != nil {
+		return false, store.NewError(backend, "Upsert", id, err)
+	}
+	defer release()
+
+	current, err := f.layout.get(id)
+	if err != nil {
+		return false, store.NewError(backend, "Upsert", id, err)
+	}
+	if err := f.layout.put(id, entity); err != nil {
+		return false, store.NewError(backend, "Upsert", id, err)
+	}
+	return current == nil, nil
+}
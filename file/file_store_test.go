@@ -0,0 +1,76 @@
+package file
+
+import (
+	"context"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestEntity struct {
+	ID    string
+	Value string
+}
+
+var _ gostore.Store[TestEntity] = (*FileStore[TestEntity])(nil)
+
+func TestConformance(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"Entity files": nil,
+		"JSON lines":   {WithJSONLines("entities")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			storetest.Run(t, func(t *testing.T) gostore.KeyedStore[string, storetest.Entity] {
+				store, err := NewFileStore[storetest.Entity](t.TempDir(), opts...)
+				require.NoError(t, err)
+				return store
+			})
+		})
+	}
+}
+
+func TestSharedDirectory(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	t.Run("Stores see each other's writes", func(t *testing.T) {
+		a, err := NewFileStore[TestEntity](dir)
+		require.NoError(t, err)
+		b, err := NewFileStore[TestEntity](dir)
+		require.NoError(t, err)
+
+		a.Insert(ctx, "1", &TestEntity{ID: "1"})
+		_, err = b.Insert(ctx, "1", &TestEntity{ID: "1"})
+		assert.ErrorIs(t, err, gostore.ErrAlreadyExists)
+	})
+
+	t.Run("Collections are independent", func(t *testing.T) {
+		cars, err := NewFileStore[TestEntity](dir, WithJSONLines("cars"))
+		require.NoError(t, err)
+		bikes, err := NewFileStore[TestEntity](dir, WithJSONLines("bikes"))
+		require.NoError(t, err)
+
+		cars.Insert(ctx, "1", &TestEntity{ID: "1"})
+		_, err = bikes.GetByID(ctx, "1")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+
+		entities, err := NewFileStore[TestEntity](dir)
+		require.NoError(t, err)
+		all, err := entities.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+	})
+
+	t.Run("Canceled context", func(t *testing.T) {
+		store, err := NewFileStore[TestEntity](dir)
+		require.NoError(t, err)
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = store.GetByID(canceled, "1")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,227 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Silencevoice/go-store/internal/fsync"
+)
+
+// layout is how the entities are laid out on disk. get returns nil when the
+// entity does not exist.
+type layout[T any] interface {
+	get(id string) (*T, error)
+	all() ([]entry[T], error)
+	put(id string, entity *T) error
+	remove(id string) (bool, error)
+}
+
+// entry is also the format of the lines of a JSONL file.
+type entry[T any] struct {
+	ID     string `json:"id"`
+	Entity *T     `json:"entity"`
+}
+
+// entityFiles keeps every entity in its own id.json file, with the id
+// escaped so any string is a valid file name. Ids differing only in case
+// share the file on case insensitive file systems.
+type entityFiles[T any] struct {
+	dir string
+}
+
+const extension = ".json"
+
+func (l *entityFiles[T]) path(id string) string {
+	return filepath.Join(l.dir, url.QueryEscape(id)+extension)
+}
+
+func (l *entityFiles[T]) get(id string) (*T, error) {
+	data, err := os.ReadFile(l.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return decode[T](data)
+}
+
+func (l *entityFiles[T]) all() ([]entry[T], error) {
+	files, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []entry[T]{}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, extension) {
+			continue
+		}
+		id, err := url.QueryUnescape(strings.TrimSuffix(name, extension))
+		if err != nil {
+			// Not one of ours
+			continue
+		}
+
+		ent, err := l.get(id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		// Deleted by someone not holding the lock
+		if ent != nil {
+			entries = append(entries, entry[T]{ID: id, Entity: ent})
+		}
+	}
+	return entries, nil
+}
+
+func (l *entityFiles[T]) put(id string, entity *T) error {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return err
+	}
+	return writeFile(l.path(id), data)
+}
+
+func (l *entityFiles[T]) remove(id string) (bool, error) {
+	err := os.Remove(l.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, fsync.Dir(filepath.Dir(l.path(id)))
+}
+
+// jsonLines keeps every entity in a single file, one entry per line.
+type jsonLines[T any] struct {
+	path string
+}
+
+func (l *jsonLines[T]) get(id string) (*T, error) {
+	entries, err := l.all()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.ID == id {
+			return e.Entity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (l *jsonLines[T]) all() ([]entry[T], error) {
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []entry[T]{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	entries := []entry[T]{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var e entry[T]
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", filepath.Base(l.path), line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+func (l *jsonLines[T]) put(id string, entity *T) error {
+	entries, err := l.all()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i := range entries {
+		if entries[i].ID == id {
+			entries[i].Entity, found = entity, true
+		}
+	}
+	if !found {
+		entries = append(entries, entry[T]{ID: id, Entity: entity})
+	}
+	return l.write(entries)
+}
+
+func (l *jsonLines[T]) remove(id string) (bool, error) {
+	entries, err := l.all()
+	if err != nil {
+		return false, err
+	}
+
+	kept := entries[:0]
+	for _, e := range entries {
+		if e.ID != id {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == len(entries) {
+		return false, nil
+	}
+	return true, l.write(kept)
+}
+
+func (l *jsonLines[T]) write(entries []entry[T]) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return writeFile(l.path, buf.Bytes())
+}
+
+func decode[T any](data []byte) (*T, error) {
+	var ent T
+	if err := json.Unmarshal(data, &ent); err != nil {
+		return nil, err
+	}
+	return &ent, nil
+}
+
+// writeFile replaces path atomically: data is written to a temporary file in
+// the same directory, which then takes the place of path. The directory is
+// synced too, or the rename could be lost in a crash after the write
+// returned.
+func writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	return fsync.Dir(filepath.Dir(path))
+}
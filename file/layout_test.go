@@ -0,0 +1,121 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntityFiles(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Any id is a file name", func(t *testing.T) {
+		dir := t.TempDir()
+		store, err := NewFileStore[TestEntity](dir)
+		require.NoError(t, err)
+
+		ids := []string{"a/b", "../up", "with space", "", "ünï", "a+b"}
+		for _, id := range ids {
+			_, err := store.Insert(ctx, id, &TestEntity{ID: id})
+			require.NoError(t, err, id)
+		}
+		for _, id := range ids {
+			entity, err := store.GetByID(ctx, id)
+			require.NoError(t, err, id)
+			assert.Equal(t, id, entity.ID)
+		}
+
+		files, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		names := []string{}
+		for _, file := range files {
+			names = append(names, file.Name())
+		}
+		assert.Contains(t, names, "a%2Fb.json")
+		assert.Contains(t, names, "..%2Fup.json")
+		_, err = os.Stat(filepath.Join(filepath.Dir(dir), "up.json"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Other files are ignored", func(t *testing.T) {
+		dir := t.TempDir()
+		store, err := NewFileStore[TestEntity](dir)
+		require.NoError(t, err)
+		store.Insert(ctx, "1", &TestEntity{ID: "1"})
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello"), 0o644))
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "sub.json"), 0o755))
+
+		all, err := store.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+	})
+
+	t.Run("Corrupted file", func(t *testing.T) {
+		dir := t.TempDir()
+		store, err := NewFileStore[TestEntity](dir)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "1.json"), []byte("{"), 0o644))
+
+		_, err = store.GetByID(ctx, "1")
+		assert.Error(t, err)
+		_, err = store.GetAll(ctx)
+		assert.ErrorContains(t, err, "1.json")
+	})
+}
+
+func TestJSONLines(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("One line per entity", func(t *testing.T) {
+		dir := t.TempDir()
+		store, err := NewFileStore[TestEntity](dir, WithJSONLines("cars"))
+		require.NoError(t, err)
+		store.Insert(ctx, "1", &TestEntity{ID: "1", Value: "a"})
+		store.Insert(ctx, "2", &TestEntity{ID: "2", Value: "b"})
+		store.Update(ctx, "1", &TestEntity{ID: "1", Value: "c"})
+
+		data, err := os.ReadFile(filepath.Join(dir, "cars.jsonl"))
+		require.NoError(t, err)
+		assert.Equal(t, `{"id":"1","entity":{"ID":"1","Value":"c"}}
+{"id":"2","entity":{"ID":"2","Value":"b"}}
+`, string(data))
+	})
+
+	t.Run("Corrupted line", func(t *testing.T) {
+		dir := t.TempDir()
+		store, err := NewFileStore[TestEntity](dir, WithJSONLines("cars"))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "cars.jsonl"), []byte("{\"id\":\"1\"}\n\n{\n"), 0o644))
+
+		_, err = store.GetAll(ctx)
+		assert.ErrorContains(t, err, "cars.jsonl line 3")
+		_, err = store.Insert(ctx, "2", &TestEntity{})
+		assert.Error(t, err)
+	})
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+
+	require.NoError(t, writeFile(path, []byte("old")))
+	require.NoError(t, writeFile(path, []byte("new")))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.False(t, strings.HasSuffix(files[0].Name(), ".tmp"))
+
+	err = writeFile(filepath.Join(dir, "missing", "data.json"), []byte("x"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package file
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".lock")
+
+	t.Run("Readers share the lock", func(t *testing.T) {
+		first, err := lockFile(path, false)
+		require.NoError(t, err)
+		second, err := lockFile(path, false)
+		require.NoError(t, err)
+		first()
+		second()
+	})
+
+	t.Run("Writers wait for the holder", func(t *testing.T) {
+		reader, err := lockFile(path, false)
+		require.NoError(t, err)
+
+		acquired := make(chan struct{})
+		go func() {
+			unlock, err := lockFile(path, true)
+			assert.NoError(t, err)
+			close(acquired)
+			unlock()
+		}()
+
+		select {
+		case <-acquired:
+			t.Fatal("write lock taken while a reader holds it")
+		case <-time.After(50 * time.Millisecond):
+		}
+		reader()
+
+		select {
+		case <-acquired:
+		case <-time.After(time.Second):
+			t.Fatal("write lock not taken after the reader released it")
+		}
+	})
+
+	t.Run("Missing directory", func(t *testing.T) {
+		_, err := lockFile(filepath.Join(t.TempDir(), "missing", ".lock"), true)
+		assert.Error(t, err)
+	})
+}
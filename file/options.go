@@ -0,0 +1,18 @@
+package file
+
+// Option configures a FileStore.
+type Option func(*options)
+
+type options struct {
+	collection string
+}
+
+// WithJSONLines keeps every entity in a single collection.jsonl file, one
+// line per entity, instead of one file each. Every write rewrites the whole
+// file, so it suits small collections, and several of them can share a
+// directory.
+func WithJSONLines(collection string) Option {
+	return func(o *options) {
+		o.collection = collection
+	}
+}
@@ -0,0 +1,119 @@
+package instrument
+
+import (
+	"context"
+	"time"
+
+	store "github.com/Silencevoice/go-store"
+)
+
+// Option configures an InstrumentedStore.
+type Option func(*options)
+
+type options struct {
+	metrics Metrics
+	name    string
+}
+
+// WithMetrics sets where the measures go. The default is Nop.
+func WithMetrics(metrics Metrics) Option {
+	return func(o *options) {
+		o.metrics = metrics
+	}
+}
+
+// WithName sets the name the measures of the store are reported under, so
+// several stores can share the same Metrics.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// InstrumentedStore measures the calls to another store, which it passes on
+// untouched, errors included. Every error counts as a failure, ErrNotFound
+// too, so its kind tells the expected ones apart.
+type InstrumentedStore[K comparable, T any] struct {
+	store   store.KeyedStore[K, T]
+	metrics Metrics
+	name    string
+	now     func() time.Time
+}
+
+// NewInstrumentedStore returns a string keyed InstrumentedStore.
+func NewInstrumentedStore[T any](s store.Store[T], opts ...Option) *InstrumentedStore[string, T] {
+	return NewKeyedInstrumentedStore[string, T](s, opts...)
+}
+
+// NewKeyedInstrumentedStore returns an InstrumentedStore of s.
+func NewKeyedInstrumentedStore[K comparable, T any](s store.KeyedStore[K, T], opts ...Option) *InstrumentedStore[K, T] {
+	o := options{metrics: Nop}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &InstrumentedStore[K, T]{store: s, metrics: o.metrics, name: o.name, now: time.Now}
+}
+
+func (s *InstrumentedStore[K, T]) GetByID(ctx context.Context, id K) (*T, error) {
+	start := s.now()
+	entity, err := s.store.GetByID(ctx, id)
+	s.record("GetByID", start, err)
+	return entity, err
+}
+
+func (s *InstrumentedStore[K, T]) GetMultipleByID(ctx context.Context, ids []K) ([]*T, error) {
+	start := s.now()
+	ents, err := s.store.GetMultipleByID(ctx, ids)
+	s.record("GetMultipleByID", start, err)
+	return ents, err
+}
+
+func (s *InstrumentedStore[K, T]) GetAll(ctx context.Context) ([]*T, error) {
+	start := s.now()
+	ents, err := s.store.GetAll(ctx)
+	s.record("GetAll", start, err)
+	return ents, err
+}
+
+func (s *InstrumentedStore[K, T]) Insert(ctx context.Context, id K, entity *T) (*T, error) {
+	start := s.now()
+	inserted, err := s.store.Insert(ctx, id, entity)
+	s.record("Insert", start, err)
+	return inserted, err
+}
+
+func (s *InstrumentedStore[K, T]) Delete(ctx context.Context, id K) error {
+	start := s.now()
+	err := s.store.Delete(ctx, id)
+	s.record("Delete", start, err)
+	return err
+}
+
+func (s *InstrumentedStore[K, T]) Update(ctx context.Context, id K, entity *T) error {
+	start := s.now()
+	err := s.store.Update(ctx, id, entity)
+	s.record("Update", start, err)
+	return err
+}
+
+func (s *InstrumentedStore[K, T]) Replace(ctx context.Context, id K, entity *T) error {
+	start := s.now()
+	err := s.store.Replace(ctx, id, entity)
+	s.record("Replace", start, err)
+	return err
+}
+
+func (s *InstrumentedStore[K, T]) Upsert(ctx context.Context, id K, entity *T) (bool, error) {
+	start := s.now()
+	created, err := s.store.Upsert(ctx, id, entity)
+	s.record("Upsert", start, err)
+	return created, err
+}
+
+func (s *InstrumentedStore[K, T]) record(op string, start time.Time, err error) {
+	s.metrics.Count(s.name, op)
+	s.metrics.Observe(s.name, op, s.now().Sub(start))
+	if err != nil {
+		s.metrics.Fail(s.name, op, Kind(err))
+	}
+}
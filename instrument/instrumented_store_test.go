@@ -0,0 +1,114 @@
+package instrument
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/memory"
+	"github.com/Silencevoice/go-store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestEntity struct {
+	ID    string
+	Value string
+}
+
+var _ gostore.Store[TestEntity] = (*InstrumentedStore[string, TestEntity])(nil)
+
+// recorder keeps every measure it receives.
+type recorder struct {
+	mu       sync.Mutex
+	calls    []string
+	failures []string
+	latency  []time.Duration
+}
+
+func (r *recorder) Count(store, op string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, store+"."+op)
+}
+
+func (r *recorder) Fail(store, op string, kind ErrorKind) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = append(r.failures, store+"."+op+":"+string(kind))
+}
+
+func (r *recorder) Observe(store, op string, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latency = append(r.latency, latency)
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) gostore.KeyedStore[string, storetest.Entity] {
+		return NewInstrumentedStore[storetest.Entity](memory.NewMemStore[storetest.Entity](), WithMetrics(&recorder{}))
+	})
+}
+
+func TestInstrumentedStore(t *testing.T) {
+	ctx := context.Background()
+	metrics := &recorder{}
+	store := NewInstrumentedStore[TestEntity](memory.NewMemStore[TestEntity](), WithMetrics(metrics), WithName("cars"))
+
+	// Every call takes a second
+	now := time.Now()
+	store.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	inserted, err := store.Insert(ctx, "1", &TestEntity{ID: "1", Value: "v1"})
+	require.NoError(t, err)
+	assert.Equal(t, &TestEntity{ID: "1", Value: "v1"}, inserted)
+	entity, err := store.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", entity.Value)
+	_, err = store.GetMultipleByID(ctx, []string{"1"})
+	require.NoError(t, err)
+	all, err := store.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+	require.NoError(t, store.Update(ctx, "1", &TestEntity{ID: "1", Value: "v2"}))
+	require.NoError(t, store.Replace(ctx, "1", &TestEntity{ID: "1", Value: "v3"}))
+	created, err := store.Upsert(ctx, "2", &TestEntity{ID: "2"})
+	require.NoError(t, err)
+	assert.True(t, created)
+	require.NoError(t, store.Delete(ctx, "2"))
+
+	assert.Equal(t, []string{
+		"cars.Insert", "cars.GetByID", "cars.GetMultipleByID", "cars.GetAll",
+		"cars.Update", "cars.Replace", "cars.Upsert", "cars.Delete",
+	}, metrics.calls)
+	assert.Empty(t, metrics.failures)
+	for _, latency := range metrics.latency {
+		assert.Equal(t, time.Second, latency)
+	}
+
+	t.Run("Errors are passed on", func(t *testing.T) {
+		_, err := store.GetByID(ctx, "missing")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+		var storeErr *gostore.Error
+		require.ErrorAs(t, err, &storeErr)
+		assert.Equal(t, "memory", storeErr.Backend)
+
+		_, err = store.Insert(ctx, "1", &TestEntity{})
+		assert.ErrorIs(t, err, gostore.ErrAlreadyExists)
+
+		assert.Equal(t, []string{"cars.GetByID:not_found", "cars.Insert:already_exists"}, metrics.failures)
+		assert.Len(t, metrics.calls, 10)
+	})
+
+	t.Run("No metrics by default", func(t *testing.T) {
+		store := NewInstrumentedStore[TestEntity](memory.NewMemStore[TestEntity]())
+		_, err := store.GetByID(ctx, "missing")
+		assert.True(t, errors.Is(err, gostore.ErrNotFound))
+	})
+}
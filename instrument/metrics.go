@@ -0,0 +1,72 @@
+package instrument
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	store "github.com/Silencevoice/go-store"
+)
+
+// Metrics receives what an InstrumentedStore measures. Every call of op on
+// the store named store is counted, and its latency observed, and the failed
+// ones are also counted by the kind of their error. Implementations must be
+// safe for concurrent use.
+type Metrics interface {
+	Count(store, op string)
+	Fail(store, op string, kind ErrorKind)
+	Observe(store, op string, latency time.Duration)
+}
+
+type nop struct{}
+
+func (nop) Count(store, op string)                          {}
+func (nop) Fail(store, op string, kind ErrorKind)           {}
+func (nop) Observe(store, op string, latency time.Duration) {}
+
+// Nop discards everything. It is the default of an InstrumentedStore.
+var Nop Metrics = nop{}
+
+// ErrorKind classifies an error by the sentinel errors of the store package,
+// so the failures can be counted without using messages as labels.
+type ErrorKind string
+
+const (
+	KindNotFound         ErrorKind = "not_found"
+	KindAlreadyExists    ErrorKind = "already_exists"
+	KindInvalidID        ErrorKind = "invalid_id"
+	KindInvalidCursor    ErrorKind = "invalid_cursor"
+	KindVersionConflict  ErrorKind = "version_conflict"
+	KindInvalidPatch     ErrorKind = "invalid_patch"
+	KindUniqueViolation  ErrorKind = "unique_violation"
+	KindCanceled         ErrorKind = "canceled"
+	KindDeadlineExceeded ErrorKind = "deadline_exceeded"
+	// KindOther is every error the rest do not match, like the ones of the
+	// database driver.
+	KindOther ErrorKind = "other"
+)
+
+var kinds = []struct {
+	err  error
+	kind ErrorKind
+}{
+	{store.ErrNotFound, KindNotFound},
+	{store.ErrAlreadyExists, KindAlreadyExists},
+	{store.ErrInvalidID, KindInvalidID},
+	{store.ErrInvalidCursor, KindInvalidCursor},
+	{store.ErrVersionConflict, KindVersionConflict},
+	{store.ErrInvalidPatch, KindInvalidPatch},
+	{store.ErrUniqueViolation, KindUniqueViolation},
+	{context.Canceled, KindCanceled},
+	{context.DeadlineExceeded, KindDeadlineExceeded},
+}
+
+// Kind returns the kind of err, which must not be nil.
+func Kind(err error) ErrorKind {
+	for _, k := range kinds {
+		if errors.Is(err, k.err) {
+			return k.kind
+		}
+	}
+	return KindOther
+}
@@ -0,0 +1,34 @@
+package instrument
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKind(t *testing.T) {
+	tests := map[error]ErrorKind{
+		gostore.ErrNotFound:            KindNotFound,
+		gostore.ErrAlreadyExists:       KindAlreadyExists,
+		gostore.ErrInvalidID:           KindInvalidID,
+		gostore.ErrInvalidCursor:       KindInvalidCursor,
+		gostore.ErrVersionConflict:     KindVersionConflict,
+		gostore.ErrInvalidPatch:        KindInvalidPatch,
+		gostore.ErrUniqueViolation:     KindUniqueViolation,
+		context.Canceled:               KindCanceled,
+		context.DeadlineExceeded:       KindDeadlineExceeded,
+		errors.New("connection reset"): KindOther,
+	}
+	for err, kind := range tests {
+		assert.Equal(t, kind, Kind(err), err.Error())
+	}
+
+	t.Run("Wrapped errors", func(t *testing.T) {
+		err := &gostore.Error{Op: "GetByID", Backend: "sql", Err: fmt.Errorf("query: %w", gostore.ErrNotFound)}
+		assert.Equal(t, KindNotFound, Kind(err))
+	})
+}
@@ -0,0 +1,213 @@
+package instrument
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency histogram
+// buckets of NewPrometheus, from half a millisecond to five seconds.
+var DefaultBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// Prometheus keeps the measures in memory and writes them in the Prometheus
+// text format, so they can be scraped without the Prometheus client library:
+//
+//	metrics := instrument.NewPrometheus()
+//	http.Handle("/metrics", metrics)
+//
+// It writes three metric families: gostore_operations_total and
+// gostore_operation_errors_total, counters labeled by store and op (and kind
+// for the errors), and gostore_operation_duration_seconds, a histogram.
+type Prometheus struct {
+	mu      sync.Mutex
+	buckets []float64
+	calls   map[series]uint64
+	errors  map[errorSeries]uint64
+	latency map[series]*histogram
+}
+
+type series struct {
+	store, op string
+}
+
+type errorSeries struct {
+	series
+	kind ErrorKind
+}
+
+type histogram struct {
+	// counts has one count per bucket, not cumulative, and the last one is
+	// +Inf
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewPrometheus returns a Prometheus with the given histogram buckets, in
+// seconds, or DefaultBuckets when there are none. They are sorted, and the
+// repeated and non-finite ones dropped: the +Inf bucket is always written.
+func NewPrometheus(buckets ...float64) *Prometheus {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	bounds := make([]float64, 0, len(buckets))
+	for _, bound := range buckets {
+		if !math.IsInf(bound, 0) && !math.IsNaN(bound) {
+			bounds = append(bounds, bound)
+		}
+	}
+	sort.Float64s(bounds)
+	bounds = slices.Compact(bounds)
+
+	return &Prometheus{
+		buckets: bounds,
+		calls:   map[series]uint64{},
+		errors:  map[errorSeries]uint64{},
+		latency: map[series]*histogram{},
+	}
+}
+
+func (p *Prometheus) Count(store, op string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls[series{store, op}]++
+}
+
+func (p *Prometheus) Fail(store, op string, kind ErrorKind) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.errors[errorSeries{series{store, op}, kind}]++
+}
+
+func (p *Prometheus) Observe(store, op string, latency time.Duration) {
+	seconds := latency.Seconds()
+	bucket := sort.SearchFloat64s(p.buckets, seconds)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h, ok := p.latency[series{store, op}]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(p.buckets)+1)}
+		p.latency[series{store, op}] = h
+	}
+	h.counts[bucket]++
+	h.sum += seconds
+	h.count++
+}
+
+// WriteTo writes every metric in the Prometheus text format, with the series
+// sorted by their labels.
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	fmt.Fprintln(cw, "# HELP gostore_operations_total Store operations.")
+	fmt.Fprintln(cw, "# TYPE gostore_operations_total counter")
+	for _, s := range sortedSeries(p.calls) {
+		fmt.Fprintf(cw, "gostore_operations_total{%s} %d\n", s.labels(), p.calls[s])
+	}
+
+	fmt.Fprintln(cw, "# HELP gostore_operation_errors_total Failed store operations, by error kind.")
+	fmt.Fprintln(cw, "# TYPE gostore_operation_errors_total counter")
+	failed := make([]errorSeries, 0, len(p.errors))
+	for s := range p.errors {
+		failed = append(failed, s)
+	}
+	sort.Slice(failed, func(i, j int) bool {
+		if failed[i].series != failed[j].series {
+			return failed[i].series.less(failed[j].series)
+		}
+		return failed[i].kind < failed[j].kind
+	})
+	for _, s := range failed {
+		fmt.Fprintf(cw, "gostore_operation_errors_total{%s,kind=%s} %d\n", s.labels(), quote(string(s.kind)), p.errors[s])
+	}
+
+	fmt.Fprintln(cw, "# HELP gostore_operation_duration_seconds Latency of the store operations.")
+	fmt.Fprintln(cw, "# TYPE gostore_operation_duration_seconds histogram")
+	for _, s := range sortedSeries(p.latency) {
+		h := p.latency[s]
+		var cumulative uint64
+		for i, bound := range p.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(cw, "gostore_operation_duration_seconds_bucket{%s,le=%s} %d\n", s.labels(), quote(formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(cw, "gostore_operation_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", s.labels(), h.count)
+		fmt.Fprintf(cw, "gostore_operation_duration_seconds_sum{%s} %s\n", s.labels(), formatFloat(h.sum))
+		fmt.Fprintf(cw, "gostore_operation_duration_seconds_count{%s} %d\n", s.labels(), h.count)
+	}
+
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+// ServeHTTP writes the metrics, to be scraped by Prometheus.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+func (s series) labels() string {
+	return "store=" + quote(s.store) + ",op=" + quote(s.op)
+}
+
+func (s series) less(other series) bool {
+	if s.store != other.store {
+		return s.store < other.store
+	}
+	return s.op < other.op
+}
+
+func sortedSeries[V any](m map[series]V) []series {
+	keys := make([]series, 0, len(m))
+	for s := range m {
+		keys = append(keys, s)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].less(keys[j]) })
+	return keys
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quote quotes a label value as the text format wants it.
+func quote(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// countingWriter counts the bytes written, and stops writing after the first
+// error.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
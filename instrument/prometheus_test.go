@@ -0,0 +1,114 @@
+package instrument
+
+import (
+	"math"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheus(t *testing.T) {
+	p := NewPrometheus(0.1, 0.01)
+	p.Count("cars", "GetByID")
+	p.Count("cars", "GetByID")
+	p.Count("bikes", "Insert")
+	p.Fail("cars", "GetByID", KindNotFound)
+	p.Observe("cars", "GetByID", 5*time.Millisecond)
+	p.Observe("cars", "GetByID", 50*time.Millisecond)
+	p.Observe("bikes", "Insert", 100*time.Millisecond)
+	p.Observe("bikes", "Insert", time.Second)
+
+	var out strings.Builder
+	n, err := p.WriteTo(&out)
+	require.NoError(t, err)
+	assert.Equal(t, int64(out.Len()), n)
+	assert.Equal(t, `# HELP gostore_operations_total Store operations.
+# TYPE gostore_operations_total counter
+gostore_operations_total{store="bikes",op="Insert"} 1
+gostore_operations_total{store="cars",op="GetByID"} 2
+# HELP gostore_operation_errors_total Failed store operations, by error kind.
+# TYPE gostore_operation_errors_total counter
+gostore_operation_errors_total{store="cars",op="GetByID",kind="not_found"} 1
+# HELP gostore_operation_duration_seconds Latency of the store operations.
+# TYPE gostore_operation_duration_seconds histogram
+gostore_operation_duration_seconds_bucket{store="bikes",op="Insert",le="0.01"} 0
+gostore_operation_duration_seconds_bucket{store="bikes",op="Insert",le="0.1"} 1
+gostore_operation_duration_seconds_bucket{store="bikes",op="Insert",le="+Inf"} 2
+gostore_operation_duration_seconds_sum{store="bikes",op="Insert"} 1.1
+gostore_operation_duration_seconds_count{store="bikes",op="Insert"} 2
+gostore_operation_duration_seconds_bucket{store="cars",op="GetByID",le="0.01"} 1
+gostore_operation_duration_seconds_bucket{store="cars",op="GetByID",le="0.1"} 2
+gostore_operation_duration_seconds_bucket{store="cars",op="GetByID",le="+Inf"} 2
+gostore_operation_duration_seconds_sum{store="cars",op="GetByID"} 0.055
+gostore_operation_duration_seconds_count{store="cars",op="GetByID"} 2
+`, out.String())
+
+	t.Run("Label values are escaped", func(t *testing.T) {
+		p := NewPrometheus()
+		p.Count("a \"quoted\"\\\nname", "GetAll")
+
+		var out strings.Builder
+		_, err := p.WriteTo(&out)
+		require.NoError(t, err)
+		assert.Contains(t, out.String(), `gostore_operations_total{store="a \"quoted\"\\\nname",op="GetAll"} 1`)
+	})
+
+	t.Run("HTTP handler", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, out.String(), rec.Body.String())
+	})
+
+	t.Run("Default buckets", func(t *testing.T) {
+		p := NewPrometheus()
+		p.Observe("cars", "GetByID", time.Millisecond)
+
+		var out strings.Builder
+		_, err := p.WriteTo(&out)
+		require.NoError(t, err)
+		assert.Equal(t, len(DefaultBuckets)+1, strings.Count(out.String(), "_bucket{"))
+		assert.Contains(t, out.String(), `le="0.0005"} 0`)
+		assert.Contains(t, out.String(), `le="0.001"} 1`)
+	})
+
+	t.Run("Repeated and non-finite buckets are dropped", func(t *testing.T) {
+		p := NewPrometheus(0.1, math.Inf(1), 0.01, 0.1, math.NaN(), math.Inf(-1))
+		assert.Equal(t, []float64{0.01, 0.1}, p.buckets)
+		p.Observe("cars", "GetByID", time.Second)
+
+		var out strings.Builder
+		_, err := p.WriteTo(&out)
+		require.NoError(t, err)
+		assert.Equal(t, 3, strings.Count(out.String(), "_bucket{"))
+		assert.Equal(t, 1, strings.Count(out.String(), `le="+Inf"`))
+	})
+}
+
+func TestPrometheus_Race(t *testing.T) {
+	p := NewPrometheus()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				p.Count("cars", "GetByID")
+				p.Observe("cars", "GetByID", time.Millisecond)
+				p.Fail("cars", "GetByID", KindOther)
+				p.WriteTo(&strings.Builder{})
+			}
+		}()
+	}
+	wg.Wait()
+
+	var out strings.Builder
+	_, err := p.WriteTo(&out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), `gostore_operations_total{store="cars",op="GetByID"} 400`)
+}
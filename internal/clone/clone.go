@@ -0,0 +1,84 @@
+// Package clone deep copies values with reflection.
+package clone
+
+import "reflect"
+
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// Deep returns a copy of v that shares no pointers, slices or maps with it.
+// Unexported struct fields, channels and functions are copied as they are,
+// and shared or cyclic pointers keep their shape in the copy.
+func Deep[T any](v T) T {
+	src := reflect.ValueOf(&v).Elem()
+	dst := deep(src, map[visit]reflect.Value{})
+	return dst.Interface().(T)
+}
+
+func deep(src reflect.Value, visited map[visit]reflect.Value) reflect.Value {
+	dst := reflect.New(src.Type()).Elem()
+
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			return dst
+		}
+		key := visit{ptr: src.Pointer(), typ: src.Type()}
+		if cp, ok := visited[key]; ok {
+			return cp
+		}
+		cp := reflect.New(src.Type().Elem())
+		visited[key] = cp
+		cp.Elem().Set(deep(src.Elem(), visited))
+		return cp
+	case reflect.Interface:
+		if src.IsNil() {
+			return dst
+		}
+		dst.Set(deep(src.Elem(), visited))
+	case reflect.Struct:
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if field := dst.Field(i); field.CanSet() {
+				field.Set(deep(src.Field(i), visited))
+			}
+		}
+	case reflect.Slice:
+		if src.IsNil() {
+			return dst
+		}
+		key := visit{ptr: src.Pointer(), typ: src.Type()}
+		if cp, ok := visited[key]; ok && cp.Len() == src.Len() {
+			return cp
+		}
+		dst.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Len()))
+		visited[key] = dst
+		for i := 0; i < src.Len(); i++ {
+			dst.Index(i).Set(deep(src.Index(i), visited))
+		}
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			dst.Index(i).Set(deep(src.Index(i), visited))
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return dst
+		}
+		key := visit{ptr: src.Pointer(), typ: src.Type()}
+		if cp, ok := visited[key]; ok {
+			return cp
+		}
+		dst.Set(reflect.MakeMapWithSize(src.Type(), src.Len()))
+		visited[key] = dst
+		iter := src.MapRange()
+		for iter.Next() {
+			dst.SetMapIndex(deep(iter.Key(), visited), deep(iter.Value(), visited))
+		}
+	default:
+		dst.Set(src)
+	}
+
+	return dst
+}
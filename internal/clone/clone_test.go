@@ -0,0 +1,83 @@
+package clone
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type engine struct {
+	Size float64
+}
+
+type node struct {
+	Name string
+	Next *node
+}
+
+type car struct {
+	Model    string
+	Tags     []string
+	Extras   map[string][]int
+	Engine   *engine
+	Spare    *engine
+	Any      any
+	Built    time.Time
+	Matrix   [2][]int
+	internal []string
+}
+
+func TestDeep(t *testing.T) {
+	t.Run("Nothing is shared", func(t *testing.T) {
+		e := &engine{Size: 1.6}
+		original := car{
+			Model:    "Corolla",
+			Tags:     []string{"a"},
+			Extras:   map[string][]int{"x": {1}},
+			Engine:   e,
+			Spare:    e,
+			Any:      []string{"b"},
+			Built:    time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			Matrix:   [2][]int{{1}, {2}},
+			internal: []string{"c"},
+		}
+
+		cp := Deep(original)
+		assert.Equal(t, original, cp)
+
+		cp.Tags[0] = "changed"
+		cp.Extras["x"][0] = 100
+		cp.Engine.Size = 2.0
+		cp.Any.([]string)[0] = "changed"
+		cp.Matrix[0][0] = 100
+
+		assert.Equal(t, "a", original.Tags[0])
+		assert.Equal(t, 1, original.Extras["x"][0])
+		assert.Equal(t, 1.6, original.Engine.Size)
+		assert.Equal(t, "b", original.Any.([]string)[0])
+		assert.Equal(t, 1, original.Matrix[0][0])
+	})
+
+	t.Run("Shared pointers stay shared", func(t *testing.T) {
+		e := &engine{Size: 1.6}
+		cp := Deep(car{Engine: e, Spare: e})
+		assert.Same(t, cp.Engine, cp.Spare)
+		assert.NotSame(t, e, cp.Engine)
+	})
+
+	t.Run("Cycles", func(t *testing.T) {
+		a := &node{Name: "a"}
+		a.Next = &node{Name: "b", Next: a}
+
+		cp := Deep(a)
+		assert.Equal(t, "b", cp.Next.Name)
+		assert.Same(t, cp, cp.Next.Next)
+		assert.NotSame(t, a, cp)
+	})
+
+	t.Run("Nil values", func(t *testing.T) {
+		assert.Equal(t, car{}, Deep(car{}))
+		assert.Nil(t, Deep[*car](nil))
+	})
+}
@@ -0,0 +1,315 @@
+// Package fields resolves dotted field paths on Go values the same way the
+// documents are laid out by the BSON and JSON encoders, so that in-process
+// backends can evaluate queries written against document field names.
+package fields
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Lookup returns the value found at path (segments separated by dots). A
+// segment matches a struct field by its bson tag, its json tag or its Go name
+// (case insensitive), and a map entry by its string key.
+func Lookup(v any, path string) (any, bool) {
+	value, ok := LookupValue(reflect.ValueOf(v), path)
+	if !ok {
+		return nil, false
+	}
+	return value.Interface(), true
+}
+
+// LookupValue is Lookup working on reflection values.
+func LookupValue(v reflect.Value, path string) (reflect.Value, bool) {
+	for _, segment := range strings.Split(path, ".") {
+		v = indirect(v)
+		if !v.IsValid() {
+			return reflect.Value{}, false
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			f, ok := structField(v.Type(), segment)
+			if !ok {
+				return reflect.Value{}, false
+			}
+			fv, err := v.FieldByIndexErr(f.index)
+			if err != nil {
+				return reflect.Value{}, false
+			}
+			if f.omitEmpty && fv.IsZero() {
+				return reflect.Value{}, false
+			}
+			v = fv
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, false
+			}
+			entry := v.MapIndex(reflect.ValueOf(segment).Convert(v.Type().Key()))
+			if !entry.IsValid() {
+				return reflect.Value{}, false
+			}
+			v = entry
+		default:
+			return reflect.Value{}, false
+		}
+	}
+
+	return v, true
+}
+
+type field struct {
+	index     []int
+	omitEmpty bool
+}
+
+func structField(t reflect.Type, name string) (field, bool) {
+	var byGoName *field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		bsonName, bsonOpts := parseTag(sf.Tag.Get("bson"))
+		jsonName, jsonOpts := parseTag(sf.Tag.Get("json"))
+		inline := hasOption(bsonOpts, "inline")
+		if (!sf.IsExported() && !(sf.Anonymous && inline)) || bsonName == "-" || (bsonName == "" && jsonName == "-") {
+			continue
+		}
+
+		if inline {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if inner, ok := structField(ft, name); ok {
+					inner.index = append([]int{i}, inner.index...)
+					return inner, true
+				}
+			}
+			continue
+		}
+
+		omitEmpty := hasOption(bsonOpts, "omitempty") || (bsonName == "" && hasOption(jsonOpts, "omitempty"))
+		f := field{index: []int{i}, omitEmpty: omitEmpty}
+		if bsonName == name || (bsonName == "" && jsonName == name) {
+			return f, true
+		}
+		if byGoName == nil && strings.EqualFold(sf.Name, name) {
+			byGoName = &f
+		}
+	}
+
+	if byGoName != nil {
+		return *byGoName, true
+	}
+	return field{}, false
+}
+
+func parseTag(tag string) (string, []string) {
+	parts := strings.Split(tag, ",")
+	return parts[0], parts[1:]
+}
+
+func hasOption(opts []string, option string) bool {
+	for _, opt := range opts {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// Compare orders two scalar values. Numbers of any type are compared by
+// value, strings lexicographically, booleans with false first and times
+// chronologically. ok is false when the values are not comparable.
+func Compare(a, b any) (result int, ok bool) {
+	av, bv := indirect(reflect.ValueOf(a)), indirect(reflect.ValueOf(b))
+	if !av.IsValid() || !bv.IsValid() {
+		return 0, false
+	}
+
+	if at, ok := av.Interface().(time.Time); ok {
+		bt, ok := bv.Interface().(time.Time)
+		if !ok {
+			return 0, false
+		}
+		return at.Compare(bt), true
+	}
+
+	switch {
+	case isInt(av) && isInt(bv):
+		return compareOrdered(av.Int(), bv.Int()), true
+	case isUint(av) && isUint(bv):
+		return compareOrdered(av.Uint(), bv.Uint()), true
+	case isNumber(av) && isNumber(bv):
+		return compareOrdered(toFloat(av), toFloat(bv)), true
+	case av.Kind() == reflect.String && bv.Kind() == reflect.String:
+		return strings.Compare(av.String(), bv.String()), true
+	case av.Kind() == reflect.Bool && bv.Kind() == reflect.Bool:
+		x, y := 0, 0
+		if av.Bool() {
+			x = 1
+		}
+		if bv.Bool() {
+			y = 1
+		}
+		return compareOrdered(x, y), true
+	}
+
+	return 0, false
+}
+
+// Equal reports whether two values are equal, comparing scalars with Compare
+// and anything else structurally.
+func Equal(a, b any) bool {
+	av, bv := indirect(reflect.ValueOf(a)), indirect(reflect.ValueOf(b))
+	if !av.IsValid() || !bv.IsValid() {
+		return !av.IsValid() && !bv.IsValid()
+	}
+	if c, ok := Compare(a, b); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(av.Interface(), bv.Interface())
+}
+
+// IsList reports whether v holds a slice or array other than a byte string.
+func IsList(v any) bool {
+	rv := indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return false
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return false
+	}
+	return rv.Type().Elem().Kind() != reflect.Uint8
+}
+
+// Elements returns the elements of a list value.
+func Elements(v any) []any {
+	rv := indirect(reflect.ValueOf(v))
+	elems := make([]any, rv.Len())
+	for i := range elems {
+		elems[i] = rv.Index(i).Interface()
+	}
+	return elems
+}
+
+func compareOrdered[N int | int64 | uint64 | float64](a, b N) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUint(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func isNumber(v reflect.Value) bool {
+	return isInt(v) || isUint(v) || v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch {
+	case isInt(v):
+		return float64(v.Int())
+	case isUint(v):
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+// LookupType returns the static type of the field found at path, following
+// the same resolution rules as Lookup. Paths going through maps or
+// interfaces resolve to the map element or interface type.
+func LookupType(t reflect.Type, path string) (reflect.Type, bool) {
+	for _, segment := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+
+		switch t.Kind() {
+		case reflect.Struct:
+			f, ok := structField(t, segment)
+			if !ok {
+				return nil, false
+			}
+			t = t.FieldByIndex(f.index).Type
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Interface:
+			return t, true
+		default:
+			return nil, false
+		}
+	}
+
+	return t, true
+}
+
+// SortCompare is a total order over values that mirrors how MongoDB sorts
+// mixed types: missing and null values first, then numbers, strings,
+// documents, lists, booleans and dates. Values of the same kind are ordered
+// with Compare.
+func SortCompare(a, b any) int {
+	ra, rb := sortRank(a), sortRank(b)
+	if ra != rb {
+		return compareOrdered(ra, rb)
+	}
+	if c, ok := Compare(a, b); ok {
+		return c
+	}
+	return 0
+}
+
+func sortRank(v any) int {
+	rv := indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return 0
+	}
+	if _, ok := rv.Interface().(time.Time); ok {
+		return 7
+	}
+
+	switch {
+	case isNumber(rv):
+		return 1
+	case rv.Kind() == reflect.String:
+		return 2
+	case rv.Kind() == reflect.Struct || rv.Kind() == reflect.Map:
+		return 3
+	case rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return 5
+		}
+		return 4
+	case rv.Kind() == reflect.Bool:
+		return 6
+	}
+	return 8
+}
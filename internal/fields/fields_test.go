@@ -0,0 +1,79 @@
+package fields
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type base struct {
+	CreatedBy string `bson:"created_by"`
+}
+
+type entity struct {
+	base    `bson:",inline"`
+	Name    string `bson:"name"`
+	Ignored string `bson:"-"`
+	Label   string `json:"label"`
+}
+
+func TestLookup(t *testing.T) {
+	e := entity{base: base{CreatedBy: "admin"}, Name: "n", Ignored: "i", Label: "l"}
+
+	t.Run("Inline struct fields", func(t *testing.T) {
+		value, ok := Lookup(e, "created_by")
+		assert.True(t, ok)
+		assert.Equal(t, "admin", value)
+	})
+
+	t.Run("Skipped fields", func(t *testing.T) {
+		_, ok := Lookup(e, "Ignored")
+		assert.False(t, ok)
+	})
+
+	t.Run("Json names", func(t *testing.T) {
+		value, ok := Lookup(e, "label")
+		assert.True(t, ok)
+		assert.Equal(t, "l", value)
+	})
+
+	t.Run("Unexported embedded struct", func(t *testing.T) {
+		type hidden struct{ base }
+
+		_, ok := Lookup(hidden{}, "base")
+		assert.False(t, ok)
+	})
+
+	t.Run("Path through a scalar", func(t *testing.T) {
+		_, ok := Lookup(e, "name.first")
+		assert.False(t, ok)
+	})
+}
+
+func TestCompare(t *testing.T) {
+	type fuel string
+
+	tests := []struct {
+		name     string
+		a, b     any
+		expected int
+		ok       bool
+	}{
+		{"Ints", 1, int64(2), -1, true},
+		{"Uints", uint8(3), uint(2), 1, true},
+		{"Mixed numbers", 2, 2.0, 0, true},
+		{"Strings", "b", "a", 1, true},
+		{"Named strings", fuel("diesel"), "diesel", 0, true},
+		{"Bools", false, true, -1, true},
+		{"Incomparable", "1", 1, 0, false},
+		{"Nil", nil, 1, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := Compare(tt.a, tt.b)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package fields
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// Set stores value at path in v, which must be addressable. Values of a
+// different type are converted: numbers when they fit and anything else
+// through its JSON encoding.
+func Set(v reflect.Value, path string, value any) error {
+	return update(v, strings.Split(path, "."), func(current reflect.Value, t reflect.Type) (reflect.Value, error) {
+		return Convert(value, t)
+	})
+}
+
+// Unset sets the field at path to its zero value, or removes it from a map.
+func Unset(v reflect.Value, path string) error {
+	return update(v, strings.Split(path, "."), func(current reflect.Value, t reflect.Type) (reflect.Value, error) {
+		return reflect.Value{}, nil
+	})
+}
+
+// Inc adds delta to the number at path. A missing map entry counts as zero.
+func Inc(v reflect.Value, path string, delta any) error {
+	return update(v, strings.Split(path, "."), func(current reflect.Value, t reflect.Type) (reflect.Value, error) {
+		d := indirect(reflect.ValueOf(delta))
+		if !d.IsValid() || !isNumber(d) {
+			return reflect.Value{}, fmt.Errorf("cannot increment by %T", delta)
+		}
+
+		if current.IsValid() {
+			current = indirect(current)
+		}
+		if !current.IsValid() {
+			elem := t
+			for elem.Kind() == reflect.Pointer {
+				elem = elem.Elem()
+			}
+			if elem.Kind() == reflect.Interface {
+				elem = d.Type()
+			}
+			current = reflect.Zero(elem)
+		}
+		if !isNumber(current) {
+			return reflect.Value{}, fmt.Errorf("cannot increment a %s", current.Type())
+		}
+
+		switch {
+		case isInt(current) && (isInt(d) || isUint(d)):
+			return Convert(current.Int()+toInt(d), t)
+		case isUint(current) && isUint(d):
+			return Convert(current.Uint()+d.Uint(), t)
+		case isUint(current) && isInt(d):
+			return Convert(int64(current.Uint())+d.Int(), t)
+		}
+		return Convert(toFloat(current)+toFloat(d), t)
+	})
+}
+
+// EnsureObject makes the value at path an empty struct or map, unless it is
+// a struct or a map already.
+func EnsureObject(v reflect.Value, path string) error {
+	return update(v, strings.Split(path, "."), func(current reflect.Value, t reflect.Type) (reflect.Value, error) {
+		if current.IsValid() {
+			if c := indirect(current); c.Kind() == reflect.Struct || (c.Kind() == reflect.Map && !c.IsNil()) {
+				return current, nil
+			}
+		}
+
+		elem := t
+		for elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		switch elem.Kind() {
+		case reflect.Struct, reflect.Map, reflect.Interface:
+			return Convert(map[string]any{}, t)
+		}
+		return reflect.Value{}, fmt.Errorf("cannot make a %s an object", t)
+	})
+}
+
+func toInt(v reflect.Value) int64 {
+	if isUint(v) {
+		return int64(v.Uint())
+	}
+	return v.Int()
+}
+
+// update replaces the value at the end of path with the result of fn, which
+// receives the current value (invalid when missing) and its static type and
+// returns an invalid value to clear it. Pointers and maps along the path are
+// copied before being modified, so v never changes values shared with other
+// copies of the entity.
+func update(v reflect.Value, path []string, fn func(current reflect.Value, t reflect.Type) (reflect.Value, error)) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		cp := reflect.New(v.Type().Elem())
+		if !v.IsNil() {
+			cp.Elem().Set(v.Elem())
+		}
+		if err := update(cp.Elem(), path, fn); err != nil {
+			return err
+		}
+		v.Set(cp)
+		return nil
+	case reflect.Interface:
+		if v.IsNil() {
+			v.Set(reflect.ValueOf(map[string]any{}))
+		}
+		cp := reflect.New(v.Elem().Type()).Elem()
+		cp.Set(v.Elem())
+		if err := update(cp, path, fn); err != nil {
+			return err
+		}
+		v.Set(cp)
+		return nil
+	case reflect.Struct:
+		f, ok := structField(v.Type(), path[0])
+		if !ok {
+			return fmt.Errorf("unknown field %q in %s", path[0], v.Type())
+		}
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok {
+			return fmt.Errorf("cannot set field %q in %s", path[0], v.Type())
+		}
+		if len(path) > 1 {
+			return update(fv, path[1:], fn)
+		}
+
+		result, err := fn(fv, fv.Type())
+		if err != nil {
+			return fmt.Errorf("field %q: %w", path[0], err)
+		}
+		if !result.IsValid() {
+			result = reflect.Zero(fv.Type())
+		}
+		fv.Set(result)
+		return nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("cannot set %q in a %s", path[0], v.Type())
+		}
+
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len()+1)
+		iter := v.MapRange()
+		for iter.Next() {
+			cp.SetMapIndex(iter.Key(), iter.Value())
+		}
+
+		key := reflect.ValueOf(path[0]).Convert(v.Type().Key())
+		current := cp.MapIndex(key)
+		if len(path) > 1 {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if current.IsValid() {
+				elem.Set(current)
+			}
+			if err := update(elem, path[1:], fn); err != nil {
+				return err
+			}
+			cp.SetMapIndex(key, elem)
+		} else {
+			result, err := fn(current, v.Type().Elem())
+			if err != nil {
+				return fmt.Errorf("field %q: %w", path[0], err)
+			}
+			// An invalid result deletes the entry
+			cp.SetMapIndex(key, result)
+		}
+
+		v.Set(cp)
+		return nil
+	}
+
+	return fmt.Errorf("cannot set %q in a %s", path[0], v.Type())
+}
+
+// fieldByIndex is FieldByIndex copying the embedded struct pointers it goes
+// through. ok is false when the field cannot be set.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if !v.CanSet() {
+				return reflect.Value{}, false
+			}
+			cp := reflect.New(v.Type().Elem())
+			if !v.IsNil() {
+				cp.Elem().Set(v.Elem())
+			}
+			v.Set(cp)
+			v = cp.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, v.CanSet()
+}
+
+// Convert returns value as a t.
+func Convert(value any, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(t), nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(t) {
+		result := reflect.New(t).Elem()
+		result.Set(v)
+		return result, nil
+	}
+
+	target := reflect.Zero(t)
+	if isNumber(v) && isNumber(target) {
+		return convertNumber(v, t)
+	}
+	if v.Kind() == reflect.String && t.Kind() == reflect.String {
+		return v.Convert(t), nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	result := reflect.New(t)
+	if err := json.Unmarshal(raw, result.Interface()); err != nil {
+		return reflect.Value{}, fmt.Errorf("cannot use %T as %s: %w", value, t, err)
+	}
+	return result.Elem(), nil
+}
+
+func convertNumber(v reflect.Value, t reflect.Type) (reflect.Value, error) {
+	result := reflect.New(t).Elem()
+	switch {
+	case isInt(result):
+		var n int64
+		switch {
+		case isInt(v):
+			n = v.Int()
+		case isUint(v):
+			if v.Uint() > math.MaxInt64 {
+				return reflect.Value{}, fmt.Errorf("%v overflows %s", v, t)
+			}
+			n = int64(v.Uint())
+		default:
+			f := v.Float()
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				return reflect.Value{}, fmt.Errorf("%v is not a valid %s", f, t)
+			}
+			n = int64(f)
+		}
+		if result.OverflowInt(n) {
+			return reflect.Value{}, fmt.Errorf("%v overflows %s", n, t)
+		}
+		result.SetInt(n)
+	case isUint(result):
+		var n uint64
+		switch {
+		case isInt(v):
+			if v.Int() < 0 {
+				return reflect.Value{}, fmt.Errorf("%v is not a valid %s", v, t)
+			}
+			n = uint64(v.Int())
+		case isUint(v):
+			n = v.Uint()
+		default:
+			f := v.Float()
+			if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+				return reflect.Value{}, fmt.Errorf("%v is not a valid %s", f, t)
+			}
+			n = uint64(f)
+		}
+		if result.OverflowUint(n) {
+			return reflect.Value{}, fmt.Errorf("%v overflows %s", n, t)
+		}
+		result.SetUint(n)
+	default:
+		f := toFloat(v)
+		if result.OverflowFloat(f) {
+			return reflect.Value{}, fmt.Errorf("%v overflows %s", f, t)
+		}
+		result.SetFloat(f)
+	}
+	return result, nil
+}
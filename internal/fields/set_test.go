@@ -0,0 +1,124 @@
+package fields
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type engine struct {
+	Size  float64 `bson:"size"`
+	Power int     `bson:"power"`
+}
+
+type car struct {
+	Model    string         `bson:"model"`
+	Price    int            `bson:"price"`
+	Mileage  uint32         `bson:"mileage"`
+	Engine   *engine        `bson:"engine"`
+	Tags     []string       `bson:"tags"`
+	Extra    map[string]any `bson:"extra"`
+	Previous *int           `bson:"previous"`
+}
+
+func TestSet(t *testing.T) {
+	t.Run("Converts numbers", func(t *testing.T) {
+		var c car
+		require.NoError(t, Set(reflect.ValueOf(&c).Elem(), "price", int64(100)))
+		assert.Equal(t, 100, c.Price)
+
+		require.NoError(t, Set(reflect.ValueOf(&c).Elem(), "price", float64(200)))
+		assert.Equal(t, 200, c.Price)
+
+		assert.Error(t, Set(reflect.ValueOf(&c).Elem(), "price", 1.5))
+		assert.Error(t, Set(reflect.ValueOf(&c).Elem(), "mileage", -1))
+	})
+
+	t.Run("Converts through JSON", func(t *testing.T) {
+		var c car
+		require.NoError(t, Set(reflect.ValueOf(&c).Elem(), "tags", []any{"a", "b"}))
+		assert.Equal(t, []string{"a", "b"}, c.Tags)
+
+		assert.Error(t, Set(reflect.ValueOf(&c).Elem(), "model", []any{"a"}))
+	})
+
+	t.Run("Copies pointers on write", func(t *testing.T) {
+		shared := &engine{Size: 1.6, Power: 100}
+		original := car{Engine: shared}
+		c := original
+
+		require.NoError(t, Set(reflect.ValueOf(&c).Elem(), "engine.power", 120))
+		assert.Equal(t, 120, c.Engine.Power)
+		assert.Equal(t, 1.6, c.Engine.Size)
+		assert.Equal(t, 100, shared.Power)
+	})
+
+	t.Run("Allocates missing pointers", func(t *testing.T) {
+		var c car
+		require.NoError(t, Set(reflect.ValueOf(&c).Elem(), "engine.size", 2.0))
+		assert.Equal(t, &engine{Size: 2.0}, c.Engine)
+	})
+
+	t.Run("Copies maps on write", func(t *testing.T) {
+		shared := map[string]any{"color": "red"}
+		c := car{Extra: shared}
+
+		require.NoError(t, Set(reflect.ValueOf(&c).Elem(), "extra.seats.front", 2))
+		assert.Equal(t, map[string]any{"color": "red", "seats": map[string]any{"front": 2}}, c.Extra)
+		assert.Equal(t, map[string]any{"color": "red"}, shared)
+	})
+
+	t.Run("Unknown field", func(t *testing.T) {
+		var c car
+		assert.Error(t, Set(reflect.ValueOf(&c).Elem(), "color", "red"))
+		assert.Error(t, Set(reflect.ValueOf(&c).Elem(), "model.name", "red"))
+	})
+}
+
+func TestUnset(t *testing.T) {
+	c := car{Model: "Corolla", Engine: &engine{Size: 1.6}, Extra: map[string]any{"color": "red", "seats": 5}}
+
+	require.NoError(t, Unset(reflect.ValueOf(&c).Elem(), "model"))
+	require.NoError(t, Unset(reflect.ValueOf(&c).Elem(), "engine"))
+	require.NoError(t, Unset(reflect.ValueOf(&c).Elem(), "extra.color"))
+	assert.Equal(t, car{Extra: map[string]any{"seats": 5}}, c)
+}
+
+func TestInc(t *testing.T) {
+	c := car{Price: 100, Engine: &engine{Size: 1.6}, Extra: map[string]any{}}
+	v := reflect.ValueOf(&c).Elem()
+
+	require.NoError(t, Inc(v, "price", 10))
+	require.NoError(t, Inc(v, "mileage", uint(5)))
+	require.NoError(t, Inc(v, "engine.size", 0.4))
+	require.NoError(t, Inc(v, "extra.visits", 1))
+	require.NoError(t, Inc(v, "previous", 3))
+
+	assert.Equal(t, 110, c.Price)
+	assert.Equal(t, uint32(5), c.Mileage)
+	assert.InDelta(t, 2.0, c.Engine.Size, 1e-9)
+	assert.Equal(t, int64(1), c.Extra["visits"])
+	assert.Equal(t, 3, *c.Previous)
+
+	assert.Error(t, Inc(v, "price", 0.5))
+	assert.Error(t, Inc(v, "model", 1))
+	assert.Error(t, Inc(v, "price", "1"))
+}
+
+func TestEnsureObject(t *testing.T) {
+	c := car{Extra: map[string]any{"a": map[string]any{"b": 1}, "c": "x"}}
+	v := reflect.ValueOf(&c).Elem()
+	require.NoError(t, EnsureObject(v, "engine"))
+	require.NoError(t, EnsureObject(v, "extra.a"))
+	require.NoError(t, EnsureObject(v, "extra.c"))
+	require.NoError(t, EnsureObject(v, "extra.d"))
+	assert.Equal(t, &engine{}, c.Engine)
+	assert.Equal(t, map[string]any{"a": map[string]any{"b": 1}, "c": map[string]any{}, "d": map[string]any{}}, c.Extra)
+
+	var empty car
+	require.NoError(t, EnsureObject(reflect.ValueOf(&empty).Elem(), "extra"))
+	assert.Equal(t, map[string]any{}, empty.Extra)
+	assert.Error(t, EnsureObject(reflect.ValueOf(&empty).Elem(), "model"))
+}
@@ -0,0 +1,21 @@
+// Package fsync makes file system changes durable.
+package fsync
+
+import (
+	"os"
+	"runtime"
+)
+
+// Dir makes the files created, renamed or removed in dir durable. It does
+// nothing on Windows, which cannot sync directories.
+func Dir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
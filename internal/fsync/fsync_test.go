@@ -0,0 +1,14 @@
+package fsync
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, Dir(dir))
+	assert.Error(t, Dir(filepath.Join(dir, "missing")))
+}
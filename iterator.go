@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+
+	"github.com/Silencevoice/go-store/query"
+)
+
+// Iterator streams entities one at a time:
+//
+//	it, err := repo.Iterate(ctx, nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer it.Close()
+//	for it.Next() {
+//		process(it.Value())
+//	}
+//	return it.Err()
+//
+// Next returns false once the results are exhausted, an error happens or the
+// context used to create the iterator is done.
+type Iterator[T any] interface {
+	Next() bool
+	Value() *T
+	Err() error
+	Close() error
+}
+
+type Iterable[K comparable, T any] interface {
+	Iterate(ctx context.Context, filter query.Expr) (Iterator[T], error)
+	IterateByID(ctx context.Context, ids []K) (Iterator[T], error)
+}
+
+type sliceIterator[T any] struct {
+	ctx   context.Context
+	items []*T
+	pos   int
+	err   error
+}
+
+// NewSliceIterator returns an Iterator over items, for backends that
+// materialize their results.
+func NewSliceIterator[T any](ctx context.Context, items []*T) Iterator[T] {
+	return &sliceIterator[T]{ctx: ctx, items: items, pos: -1}
+}
+
+func (it *sliceIterator[T]) Next() bool {
+	if it.err != nil || it.pos >= len(it.items) {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	it.pos++
+	return it.pos < len(it.items)
+}
+
+func (it *sliceIterator[T]) Value() *T {
+	if it.pos < 0 || it.pos >= len(it.items) {
+		return nil
+	}
+	return it.items[it.pos]
+}
+
+func (it *sliceIterator[T]) Err() error {
+	return it.err
+}
+
+func (it *sliceIterator[T]) Close() error {
+	it.pos = len(it.items)
+	it.items = nil
+	return nil
+}
+
+// Collect drains it into a slice and closes it.
+func Collect[T any](it Iterator[T]) ([]*T, error) {
+	defer it.Close()
+
+	results := []*T{}
+	for it.Next() {
+		results = append(results, it.Value())
+	}
+	return results, it.Err()
+}
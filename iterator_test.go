@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSliceIterator(t *testing.T) {
+	one, two := 1, 2
+
+	t.Run("Iterate all items", func(t *testing.T) {
+		it := NewSliceIterator(context.Background(), []*int{&one, &two})
+		assert.Nil(t, it.Value())
+
+		require.True(t, it.Next())
+		assert.Equal(t, &one, it.Value())
+		require.True(t, it.Next())
+		assert.Equal(t, &two, it.Value())
+		assert.False(t, it.Next())
+		assert.False(t, it.Next())
+		assert.NoError(t, it.Err())
+		assert.NoError(t, it.Close())
+	})
+
+	t.Run("Stop on context cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		it := NewSliceIterator(ctx, []*int{&one, &two})
+
+		require.True(t, it.Next())
+		cancel()
+		assert.False(t, it.Next())
+		assert.ErrorIs(t, it.Err(), context.Canceled)
+	})
+
+	t.Run("Closed iterator", func(t *testing.T) {
+		it := NewSliceIterator(context.Background(), []*int{&one, &two})
+		require.True(t, it.Next())
+		require.NoError(t, it.Close())
+		assert.False(t, it.Next())
+		assert.Nil(t, it.Value())
+	})
+}
+
+func TestCollect(t *testing.T) {
+	one, two := 1, 2
+
+	t.Run("Collect items", func(t *testing.T) {
+		items, err := Collect(NewSliceIterator(context.Background(), []*int{&one, &two}))
+		require.NoError(t, err)
+		assert.Equal(t, []*int{&one, &two}, items)
+	})
+
+	t.Run("Collect with cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := Collect(NewSliceIterator(ctx, []*int{&one}))
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+
+	store "github.com/Silencevoice/go-store"
+)
+
+func (m *MemStore[K, T]) InsertMany(ctx context.Context, entries []store.Entry[K, T], opts store.BulkOptions) (*store.BulkResult[K], error) {
+	v, release, err := m.view(ctx, true)
+	if err != nil {
+		return nil, store.NewError(backend, "InsertMany", nil, err)
+	}
+	defer release()
+
+	result, err := bulk(len(entries), opts, func(i int) (K, error) {
+		id := entries[i].ID
+		if _, _, ok := v.get(id); ok {
+			return id, store.NewError(backend, "InsertMany", id, store.ErrAlreadyExists)
+		}
+		if err := m.checkUnique(v, id, *entries[i].Entity); err != nil {
+			return id, store.NewError(backend, "InsertMany", id, err)
+		}
+		v.put(id, m.copy(*entries[i].Entity), 1)
+		return id, nil
+	})
+	if err := m.flush(v); err != nil {
+		return nil, store.NewError(backend, "InsertMany", nil, err)
+	}
+	return result, err
+}
+
+func (m *MemStore[K, T]) UpdateMany(ctx context.Context, entries []store.Entry[K, T], opts store.BulkOptions) (*store.BulkResult[K], error) {
+	v, release, err := m.view(ctx, true)
+	if err != nil {
+		return nil, store.NewError(backend, "UpdateMany", nil, err)
+	}
+	defer release()
+
+	result, err := bulk(len(entries), opts, func(i int) (K, error) {
+		id := entries[i].ID
+		_, version, ok := v.get(id)
+		if !ok {
+			return id, store.NewError(backend, "UpdateMany", id, store.ErrNotFound)
+		}
+		if err := m.checkUnique(v, id, *entries[i].Entity); err != nil {
+			return id, store.NewError(backend, "UpdateMany", id, err)
+		}
+		v.put(id, m.copy(*entries[i].Entity), version+1)
+		return id, nil
+	})
+	if err := m.flush(v); err != nil {
+		return nil, store.NewError(backend, "UpdateMany", nil, err)
+	}
+	return result, err
+}
+
+func (m *MemStore[K, T]) DeleteMany(ctx context.Context, ids []K, opts store.BulkOptions) (*store.BulkResult[K], error) {
+	v, release, err := m.view(ctx, true)
+	if err != nil {
+		return nil, store.NewError(backend, "DeleteMany", nil, err)
+	}
+	defer release()
+
+	result, err := bulk(len(ids), opts, func(i int) (K, error) {
+		id := ids[i]
+		if _, _, ok := v.get(id); !ok {
+			return id, store.NewError(backend, "DeleteMany", id, store.ErrNotFound)
+		}
+		v.remove(id)
+		return id, nil
+	})
+	if err := m.flush(v); err != nil {
+		return nil, store.NewError(backend, "DeleteMany", nil, err)
+	}
+	return result, err
+}
+
+// bulk runs write for every item, recording its failures.
+func bulk[K comparable](n int, opts store.BulkOptions, write func(i int) (K, error)) (*store.BulkResult[K], error) {
+	result := &store.BulkResult[K]{}
+	for i := 0; i < n; i++ {
+		id, err := write(i)
+		if err != nil {
+			result.Failures = append(result.Failures, store.BulkFailure[K]{Index: i, ID: id, Err: err})
+			if opts.Ordered {
+				break
+			}
+			continue
+		}
+		result.Succeeded++
+	}
+	return result, result.Err()
+}
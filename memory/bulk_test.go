@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ gostore.BulkWriter[string, TestEntity] = (*MemStore[string, TestEntity])(nil)
+
+func entries(ids ...string) []gostore.Entry[string, TestEntity] {
+	result := []gostore.Entry[string, TestEntity]{}
+	for _, id := range ids {
+		result = append(result, gostore.Entry[string, TestEntity]{ID: id, Entity: &TestEntity{ID: id, Value: "value-" + id}})
+	}
+	return result
+}
+
+func TestInsertMany(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Insert every entity", func(t *testing.T) {
+		store := NewMemStore[TestEntity]()
+		result, err := store.InsertMany(ctx, entries("1", "2", "3"), gostore.BulkOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Succeeded)
+		assert.Empty(t, result.Failures)
+
+		all, _ := store.GetAll(ctx)
+		assert.Len(t, all, 3)
+	})
+
+	t.Run("Unordered goes on after failures", func(t *testing.T) {
+		store := NewMemStore[TestEntity]()
+		store.Insert(ctx, "2", &TestEntity{ID: "2"})
+
+		result, err := store.InsertMany(ctx, entries("1", "2", "3", "1"), gostore.BulkOptions{})
+		assert.ErrorIs(t, err, gostore.ErrAlreadyExists)
+		assert.Equal(t, 2, result.Succeeded)
+		require.Len(t, result.Failures, 2)
+		assert.Equal(t, 1, result.Failures[0].Index)
+		assert.Equal(t, "2", result.Failures[0].ID)
+		assert.ErrorIs(t, result.Failures[0].Err, gostore.ErrAlreadyExists)
+		assert.Equal(t, 3, result.Failures[1].Index)
+	})
+
+	t.Run("Ordered stops at the first failure", func(t *testing.T) {
+		store := NewMemStore[TestEntity]()
+		store.Insert(ctx, "2", &TestEntity{ID: "2"})
+
+		result, err := store.InsertMany(ctx, entries("1", "2", "3"), gostore.BulkOptions{Ordered: true})
+		assert.Error(t, err)
+		assert.Equal(t, 1, result.Succeeded)
+		assert.Len(t, result.Failures, 1)
+
+		_, err = store.GetByID(ctx, "3")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+	})
+}
+
+func TestUpdateMany(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore[TestEntity]()
+	store.InsertMany(ctx, entries("1", "2"), gostore.BulkOptions{})
+
+	updates := entries("1", "missing", "2")
+	updates[0].Entity.Value = "updated"
+	result, err := store.UpdateMany(ctx, updates, gostore.BulkOptions{})
+	assert.ErrorIs(t, err, gostore.ErrNotFound)
+	assert.Equal(t, 2, result.Succeeded)
+	require.Len(t, result.Failures, 1)
+	assert.Equal(t, "missing", result.Failures[0].ID)
+
+	entity, version, _ := store.GetWithVersion(ctx, "1")
+	assert.Equal(t, "updated", entity.Value)
+	assert.Equal(t, int64(2), version)
+}
+
+func TestDeleteMany(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore[TestEntity]()
+	store.InsertMany(ctx, entries("1", "2", "3"), gostore.BulkOptions{})
+
+	result, err := store.DeleteMany(ctx, []string{"1", "missing", "3"}, gostore.BulkOptions{Ordered: true})
+	assert.ErrorIs(t, err, gostore.ErrNotFound)
+	assert.Equal(t, 1, result.Succeeded)
+
+	all, _ := store.GetAll(ctx)
+	assert.Len(t, all, 2)
+}
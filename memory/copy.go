@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/Silencevoice/go-store/internal/clone"
+)
+
+// CopyStrategy tells MemStore how to copy the entities it receives and
+// returns, so callers never share memory with the stored state.
+type CopyStrategy int
+
+const (
+	// DeepCopy copies entities with reflection, following pointers, slices,
+	// maps and interfaces. Unexported fields are copied as they are.
+	DeepCopy CopyStrategy = iota
+	// ShallowCopy only copies the entity struct. It is the fastest, and safe
+	// for entities without pointers, slices or maps.
+	ShallowCopy
+	// CloneMethod calls the Clone method of the entity, which must implement
+	// Cloner.
+	CloneMethod
+)
+
+func (s CopyStrategy) String() string {
+	switch s {
+	case DeepCopy:
+		return "DeepCopy"
+	case ShallowCopy:
+		return "ShallowCopy"
+	case CloneMethod:
+		return "CloneMethod"
+	}
+	return fmt.Sprintf("CopyStrategy(%d)", int(s))
+}
+
+// Cloner is implemented by entities that know how to copy themselves. The
+// method may have a value or a pointer receiver.
+type Cloner[T any] interface {
+	Clone() T
+}
+
+// copier returns the copy function for strategy. It panics when T cannot use
+// it, since that is a programming error.
+func copier[T any](strategy CopyStrategy) func(T) T {
+	switch strategy {
+	case DeepCopy:
+		return clone.Deep[T]
+	case ShallowCopy:
+		return func(v T) T { return v }
+	case CloneMethod:
+		var zero T
+		if _, ok := any(&zero).(Cloner[T]); !ok {
+			panic(fmt.Sprintf("memory: %T does not implement Cloner", zero))
+		}
+		return func(v T) T { return any(&v).(Cloner[T]).Clone() }
+	}
+	panic(fmt.Sprintf("memory: unknown %s", strategy))
+}
@@ -0,0 +1,240 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Garage struct {
+	Name   string
+	Cars   []string
+	Owners map[string]int
+	Main   *Car
+}
+
+type Cloneable struct {
+	Tags   []string
+	clones *int
+}
+
+func (c Cloneable) Clone() Cloneable {
+	*c.clones++
+	c.Tags = append([]string(nil), c.Tags...)
+	return c
+}
+
+func newGarage() *Garage {
+	return &Garage{
+		Name:   "north",
+		Cars:   []string{"corolla"},
+		Owners: map[string]int{"ana": 1},
+		Main:   &Car{Model: "corolla"},
+	}
+}
+
+func mutate(g *Garage) {
+	g.Cars[0] = "changed"
+	g.Owners["ana"] = 100
+	g.Main.Model = "changed"
+}
+
+func TestDeepCopy(t *testing.T) {
+	ctx := context.Background()
+
+	reads := map[string]func(store *MemStore[string, Garage]) *Garage{
+		"GetByID": func(store *MemStore[string, Garage]) *Garage {
+			g, _ := store.GetByID(ctx, "1")
+			return g
+		},
+		"GetMultipleByID": func(store *MemStore[string, Garage]) *Garage {
+			g, _ := store.GetMultipleByID(ctx, []string{"1"})
+			return g[0]
+		},
+		"GetAll": func(store *MemStore[string, Garage]) *Garage {
+			g, _ := store.GetAll(ctx)
+			return g[0]
+		},
+		"Find": func(store *MemStore[string, Garage]) *Garage {
+			g, _ := store.Find(ctx, nil)
+			return g[0]
+		},
+		"List": func(store *MemStore[string, Garage]) *Garage {
+			page, _ := store.List(ctx, nil, gostore.FindOptions{})
+			return page.Items[0]
+		},
+		"GetWithVersion": func(store *MemStore[string, Garage]) *Garage {
+			g, _, _ := store.GetWithVersion(ctx, "1")
+			return g
+		},
+	}
+
+	for name, read := range reads {
+		t.Run(name+" returns a copy", func(t *testing.T) {
+			store := NewMemStore[Garage]()
+			_, err := store.Insert(ctx, "1", newGarage())
+			require.NoError(t, err)
+
+			mutate(read(store))
+			assert.Equal(t, newGarage(), read(store))
+		})
+	}
+
+	t.Run("Insert keeps a copy", func(t *testing.T) {
+		store := NewMemStore[Garage]()
+		g := newGarage()
+		inserted, err := store.Insert(ctx, "1", g)
+		require.NoError(t, err)
+		assert.NotSame(t, g, inserted)
+
+		mutate(g)
+		mutate(inserted)
+		assert.Equal(t, newGarage(), reads["GetByID"](store))
+	})
+
+	t.Run("Writes keep a copy", func(t *testing.T) {
+		store := NewMemStore[Garage]()
+		writes := []func(g *Garage) error{
+			func(g *Garage) error { return store.Update(ctx, "1", g) },
+			func(g *Garage) error { return store.Replace(ctx, "1", g) },
+			func(g *Garage) error { _, err := store.Upsert(ctx, "1", g); return err },
+			func(g *Garage) error {
+				_, version, _ := store.GetWithVersion(ctx, "1")
+				return store.UpdateIfVersion(ctx, "1", version, g)
+			},
+			func(g *Garage) error {
+				_, err := store.UpdateMany(ctx, []gostore.Entry[string, Garage]{{ID: "1", Entity: g}}, gostore.BulkOptions{})
+				return err
+			},
+		}
+
+		_, err := store.InsertMany(ctx, []gostore.Entry[string, Garage]{{ID: "1", Entity: newGarage()}}, gostore.BulkOptions{})
+		require.NoError(t, err)
+		for _, write := range writes {
+			g := newGarage()
+			require.NoError(t, write(g))
+			mutate(g)
+			assert.Equal(t, newGarage(), reads["GetByID"](store))
+		}
+	})
+
+	t.Run("Patch keeps a copy", func(t *testing.T) {
+		store := NewMemStore[Garage]()
+		_, err := store.Insert(ctx, "1", newGarage())
+		require.NoError(t, err)
+
+		cars := []string{"corolla"}
+		require.NoError(t, store.Patch(ctx, "1", gostore.Fields(gostore.Set("cars", cars))))
+		cars[0] = "changed"
+		assert.Equal(t, newGarage(), reads["GetByID"](store))
+	})
+
+	t.Run("Transactions keep a copy", func(t *testing.T) {
+		store := NewMemStore[Garage]()
+		g := newGarage()
+		err := store.WithTx(ctx, func(ctx context.Context) error {
+			if _, err := store.Insert(ctx, "1", g); err != nil {
+				return err
+			}
+			staged, err := store.GetByID(ctx, "1")
+			if err != nil {
+				return err
+			}
+			mutate(staged)
+			return nil
+		})
+		require.NoError(t, err)
+
+		mutate(g)
+		assert.Equal(t, newGarage(), reads["GetByID"](store))
+	})
+}
+
+func TestShallowCopy(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore[Garage](WithCopy(ShallowCopy))
+	_, err := store.Insert(ctx, "1", newGarage())
+	require.NoError(t, err)
+
+	g, err := store.GetByID(ctx, "1")
+	require.NoError(t, err)
+	g.Name = "changed"
+	mutate(g)
+
+	stored, err := store.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "north", stored.Name)
+	assert.Equal(t, "changed", stored.Cars[0], "slices are shared")
+}
+
+func TestCloneMethod(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Clone is used on reads and writes", func(t *testing.T) {
+		clones := 0
+		store := NewMemStore[Cloneable](WithCopy(CloneMethod))
+		entity := &Cloneable{Tags: []string{"a"}, clones: &clones}
+		inserted, err := store.Insert(ctx, "1", entity)
+		require.NoError(t, err)
+		assert.Equal(t, 2, clones)
+
+		entity.Tags[0] = "changed"
+		inserted.Tags[0] = "changed"
+
+		stored, err := store.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a"}, stored.Tags)
+		assert.Equal(t, 3, clones)
+	})
+
+	t.Run("Entities without Clone", func(t *testing.T) {
+		assert.Panics(t, func() { NewMemStore[Garage](WithCopy(CloneMethod)) })
+	})
+
+	t.Run("Unknown strategy", func(t *testing.T) {
+		assert.PanicsWithValue(t, "memory: unknown CopyStrategy(42)", func() { NewMemStore[Garage](WithCopy(42)) })
+	})
+}
+
+// TestDeepCopy_Race mutates the entities given to and returned by the
+// store while other goroutines read and write it. Run with -race, it fails as
+// soon as any memory is shared with the stored state.
+func TestDeepCopy_Race(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore[Garage]()
+	_, err := store.Insert(ctx, "1", newGarage())
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if g, err := store.GetByID(ctx, "1"); err == nil {
+					mutate(g)
+				}
+				if all, err := store.Find(ctx, query.Eq("name", "north")); err == nil {
+					for _, g := range all {
+						mutate(g)
+					}
+				}
+
+				g := newGarage()
+				if err := store.Update(ctx, "1", g); err == nil {
+					mutate(g)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	g, err := store.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, newGarage(), g)
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+
+	store "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+)
+
+func (m *MemStore[K, T]) Exists(ctx context.Context, id K) (bool, error) {
+	v, release, err := m.viewKey(ctx, id, false)
+	if err != nil {
+		return false, store.NewError(backend, "Exists", id, err)
+	}
+	defer release()
+
+	_, _, ok := v.get(id)
+	return ok, nil
+}
+
+func (m *MemStore[K, T]) Count(ctx context.Context) (int64, error) {
+	v, release, err := m.view(ctx, false)
+	if err != nil {
+		return 0, store.NewError(backend, "Count", nil, err)
+	}
+	defer release()
+
+	return int64(v.count()), nil
+}
+
+// CountWhere has to evaluate filter on every entity, but does not copy them.
+func (m *MemStore[K, T]) CountWhere(ctx context.Context, filter query.Expr) (int64, error) {
+	if filter == nil {
+		return m.Count(ctx)
+	}
+
+	v, release, err := m.view(ctx, false)
+	if err != nil {
+		return 0, store.NewError(backend, "CountWhere", nil, err)
+	}
+	defer release()
+
+	var n int64
+	v.each(func(id K, value T) bool {
+		var ok bool
+		ok, err = query.Match(filter, value)
+		if ok {
+			n++
+		}
+		return err == nil
+	})
+	if err != nil {
+		return 0, store.NewError(backend, "CountWhere", nil, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ gostore.Counter[string] = (*MemStore[string, TestEntity])(nil)
+
+func TestCount(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore[TestEntity]()
+	store.Insert(ctx, "1", &TestEntity{ID: "1", Value: "value-1"})
+	store.Insert(ctx, "2", &TestEntity{ID: "2", Value: "value-2"})
+	store.Insert(ctx, "3", &TestEntity{ID: "3", Value: "other"})
+
+	t.Run("Exists", func(t *testing.T) {
+		ok, err := store.Exists(ctx, "1")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = store.Exists(ctx, "missing")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Count", func(t *testing.T) {
+		n, err := store.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+	})
+
+	t.Run("Count where", func(t *testing.T) {
+		n, err := store.CountWhere(ctx, query.Prefix("value", "value"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+
+		n, err = store.CountWhere(ctx, nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+
+		_, err = store.CountWhere(ctx, query.CompareExpr{Field: "value", Op: "like"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Count inside a transaction", func(t *testing.T) {
+		err := WithTx(ctx, func(ctx context.Context) error {
+			store.Delete(ctx, "1")
+			store.Insert(ctx, "4", &TestEntity{ID: "4"})
+			store.Insert(ctx, "5", &TestEntity{ID: "5"})
+			store.Update(ctx, "2", &TestEntity{ID: "2"})
+
+			n, err := store.Count(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, int64(4), n)
+
+			ok, _ := store.Exists(ctx, "1")
+			assert.False(t, ok)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,340 @@
+package memory
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// Eviction is the policy picking the entity to evict from a full store.
+type Eviction int
+
+const (
+	// LRU evicts the least recently used entity. It is the default.
+	LRU Eviction = iota
+	// LFU evicts the least frequently used entity, and of those the least
+	// recently used one.
+	LFU
+)
+
+// EvictionReason tells why an entity left a bounded store.
+type EvictionReason int
+
+const (
+	// Capacity means the store was full.
+	Capacity EvictionReason = iota
+	// Expired means the TTL of the entity was over.
+	Expired
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case Capacity:
+		return "capacity"
+	case Expired:
+		return "expired"
+	}
+	return fmt.Sprintf("EvictionReason(%d)", int(r))
+}
+
+// evictionSamples is how many entities are compared to pick the one to
+// evict. Like Redis, the store approximates LRU and LFU with a sample
+// instead of keeping the entities sorted, so reads only update a couple of
+// counters and never take the write lock.
+const evictionSamples = 16
+
+// WithMaxEntries bounds the store to n entities. Writes that go over it
+// evict other entities, picked by the WithEviction policy.
+func WithMaxEntries(n int) Option {
+	return func(o *options) {
+		o.limits.entries = n
+	}
+}
+
+// WithMaxBytes bounds the approximate memory held by the entities, counting
+// their fields and what their strings, slices, maps and pointers hold, but
+// not the overhead of the store itself.
+func WithMaxBytes(n int64) Option {
+	return func(o *options) {
+		o.limits.bytes = n
+	}
+}
+
+// WithEviction sets the policy of WithMaxEntries and WithMaxBytes.
+func WithEviction(policy Eviction) Option {
+	return func(o *options) {
+		o.limits.policy = policy
+	}
+}
+
+// WithOnEvict calls fn for every entity evicted or expired. It runs once the
+// write that removed the entity released the store, so it may use it.
+func WithOnEvict[K comparable, T any](fn func(id K, entity T, reason EvictionReason)) Option {
+	return func(o *options) {
+		o.onEvict = fn
+	}
+}
+
+type limits struct {
+	entries int
+	bytes   int64
+	policy  Eviction
+	ttl     time.Duration
+}
+
+func (l limits) evicting() bool {
+	return l.entries > 0 || l.bytes > 0
+}
+
+// usage is what the eviction policies know about an entity. Reads update it
+// with the store only locked for reading.
+type usage struct {
+	last atomic.Uint64
+	hits atomic.Uint64
+	size int64
+}
+
+type eviction[K comparable, T any] struct {
+	id     K
+	entity T
+	reason EvictionReason
+}
+
+func newOnEvict[K comparable, T any](fn any) func(K, T, EvictionReason) {
+	if fn == nil {
+		return nil
+	}
+	onEvict, ok := fn.(func(K, T, EvictionReason))
+	if !ok {
+		var id K
+		var zero T
+		panic(fmt.Sprintf("memory: eviction callback is not defined for %T keys and %T entities", id, zero))
+	}
+	return onEvict
+}
+
+// touch records a use of the entity in s.
+func (m *MemStore[K, T]) touch(s *shard[K, T], id K) {
+	if u := s.access[id]; u != nil {
+		u.last.Store(m.ticks.Add(1))
+		u.hits.Add(1)
+	}
+}
+
+// track keeps the usage and the size of a written entity.
+func (m *MemStore[K, T]) track(s *shard[K, T], id K, value T) {
+	if !m.limits.evicting() {
+		return
+	}
+	if s.access == nil {
+		s.access = map[K]*usage{}
+	}
+	u, ok := s.access[id]
+	if !ok {
+		u = &usage{}
+		s.access[id] = u
+	}
+	if m.limits.bytes > 0 {
+		size := sizeOf(value)
+		m.bytes += size - u.size
+		u.size = size
+	}
+	u.last.Store(m.ticks.Add(1))
+	u.hits.Add(1)
+}
+
+func (m *MemStore[K, T]) untrack(s *shard[K, T], id K) {
+	if u, ok := s.access[id]; ok {
+		m.bytes -= u.size
+		delete(s.access, id)
+	}
+}
+
+// bounded tells whether the writes have to go through put, which keeps the
+// bounds. It must be called with the whole store locked.
+func (m *MemStore[K, T]) bounded() bool {
+	return m.limits.evicting() || m.limits.ttl > 0 || m.expiring()
+}
+
+// full tells whether the store went over its bounds.
+func (m *MemStore[K, T]) full() bool {
+	if m.limits.entries > 0 {
+		n := 0
+		for _, s := range m.shards {
+			n += len(s.data)
+		}
+		if n > m.limits.entries {
+			return true
+		}
+	}
+	return m.limits.bytes > 0 && m.bytes > m.limits.bytes
+}
+
+// evict removes entities until the store is within its bounds again, never
+// keep, which is the one just written. It must be called with the whole
+// store locked for writing.
+func (m *MemStore[K, T]) evict(keep *K) {
+	if !m.limits.evicting() {
+		return
+	}
+
+	now := m.now()
+	for m.full() {
+		id, reason, ok := m.victim(keep, now)
+		if !ok {
+			return
+		}
+		m.drop(id, reason)
+	}
+}
+
+// victim picks the entity to evict among a sample of every shard, or the
+// first expired one it finds.
+func (m *MemStore[K, T]) victim(keep *K, now time.Time) (K, EvictionReason, bool) {
+	per := max(1, evictionSamples/len(m.shards))
+
+	var victim K
+	var coldest *usage
+	for _, s := range m.shards {
+		n := 0
+		for id, u := range s.access {
+			if keep != nil && id == *keep {
+				continue
+			}
+			if s.expired(id, now) {
+				return id, Expired, true
+			}
+			if coldest == nil || m.colder(u, coldest) {
+				victim, coldest = id, u
+			}
+			if n++; n == per {
+				break
+			}
+		}
+	}
+	return victim, Capacity, coldest != nil
+}
+
+func (m *MemStore[K, T]) colder(a, b *usage) bool {
+	if m.limits.policy == LFU {
+		if ha, hb := a.hits.Load(), b.hits.Load(); ha != hb {
+			return ha < hb
+		}
+	}
+	return a.last.Load() < b.last.Load()
+}
+
+// drop removes an entity the store evicted. Durable stores log it like any
+// delete.
+func (m *MemStore[K, T]) drop(id K, reason EvictionReason) {
+	value := m.shard(id).data[id]
+	m.remove(id)
+	m.evicted(id, value, reason)
+}
+
+func (m *MemStore[K, T]) evicted(id K, value T, reason EvictionReason) {
+	if m.onEvict == nil {
+		return
+	}
+	m.evictMu.Lock()
+	defer m.evictMu.Unlock()
+	m.evictions = append(m.evictions, eviction[K, T]{id: id, entity: value, reason: reason})
+}
+
+// forgetEvictions drops the evictions of ids not notified yet.
+func (m *MemStore[K, T]) forgetEvictions(ids map[K]bool) {
+	if m.onEvict == nil {
+		return
+	}
+	m.evictMu.Lock()
+	defer m.evictMu.Unlock()
+	kept := m.evictions[:0]
+	for _, e := range m.evictions {
+		if !ids[e.id] {
+			kept = append(kept, e)
+		}
+	}
+	m.evictions = kept
+}
+
+// notify calls the WithOnEvict callback for the entities evicted so far. It
+// must be called without holding the store lock.
+func (m *MemStore[K, T]) notify() {
+	if m.onEvict == nil {
+		return
+	}
+	m.evictMu.Lock()
+	evictions := m.evictions
+	m.evictions = nil
+	m.evictMu.Unlock()
+
+	for _, e := range evictions {
+		m.onEvict(e.id, e.entity, e.reason)
+	}
+}
+
+// sizeOf estimates the bytes held by value.
+func sizeOf[T any](value T) int64 {
+	v := reflect.ValueOf(&value).Elem()
+	return int64(v.Type().Size()) + indirectSize(v, map[uintptr]struct{}{})
+}
+
+// indirectSize is the size of what v points to, counting the memory shared
+// by several pointers once.
+func indirectSize(v reflect.Value, seen map[uintptr]struct{}) int64 {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Pointer:
+		if v.IsNil() || visited(v.Pointer(), seen) {
+			return 0
+		}
+		return int64(v.Type().Elem().Size()) + indirectSize(v.Elem(), seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return int64(v.Elem().Type().Size()) + indirectSize(v.Elem(), seen)
+	case reflect.Slice:
+		if v.IsNil() || visited(v.Pointer(), seen) {
+			return 0
+		}
+		n := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		for i := 0; i < v.Len(); i++ {
+			n += indirectSize(v.Index(i), seen)
+		}
+		return n
+	case reflect.Array:
+		var n int64
+		for i := 0; i < v.Len(); i++ {
+			n += indirectSize(v.Index(i), seen)
+		}
+		return n
+	case reflect.Struct:
+		var n int64
+		for i := 0; i < v.NumField(); i++ {
+			n += indirectSize(v.Field(i), seen)
+		}
+		return n
+	case reflect.Map:
+		if v.IsNil() || visited(v.Pointer(), seen) {
+			return 0
+		}
+		n := int64(v.Len()) * int64(v.Type().Key().Size()+v.Type().Elem().Size())
+		iter := v.MapRange()
+		for iter.Next() {
+			n += indirectSize(iter.Key(), seen) + indirectSize(iter.Value(), seen)
+		}
+		return n
+	}
+	return 0
+}
+
+func visited(p uintptr, seen map[uintptr]struct{}) bool {
+	if _, ok := seen[p]; ok {
+		return true
+	}
+	seen[p] = struct{}{}
+	return false
+}
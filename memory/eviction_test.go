@@ -0,0 +1,230 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type evicted struct {
+	id     string
+	reason EvictionReason
+}
+
+func recordEvictions(evictions *[]evicted) Option {
+	return WithOnEvict(func(id string, _ TestEntity, reason EvictionReason) {
+		*evictions = append(*evictions, evicted{id: id, reason: reason})
+	})
+}
+
+func TestWithMaxEntries(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("LRU", func(t *testing.T) {
+		evictions := []evicted{}
+		store := NewMemStore[TestEntity](WithMaxEntries(3), recordEvictions(&evictions))
+		for _, id := range []string{"1", "2", "3"} {
+			store.Insert(ctx, id, &TestEntity{ID: id})
+		}
+		_, err := store.GetByID(ctx, "1")
+		require.NoError(t, err)
+
+		_, err = store.Insert(ctx, "4", &TestEntity{ID: "4"})
+		require.NoError(t, err)
+		assert.Equal(t, []evicted{{id: "2", reason: Capacity}}, evictions)
+		_, err = store.GetByID(ctx, "2")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+
+		// Writes are uses too
+		require.NoError(t, store.Update(ctx, "3", &TestEntity{ID: "3", Value: "v2"}))
+		store.Insert(ctx, "5", &TestEntity{ID: "5"})
+		assert.Equal(t, evicted{id: "1", reason: Capacity}, evictions[1])
+
+		n, err := store.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+	})
+
+	t.Run("LFU", func(t *testing.T) {
+		evictions := []evicted{}
+		store := NewMemStore[TestEntity](WithMaxEntries(3), WithEviction(LFU), recordEvictions(&evictions))
+		for _, id := range []string{"1", "2", "3"} {
+			store.Insert(ctx, id, &TestEntity{ID: id})
+		}
+		for i := 0; i < 3; i++ {
+			store.GetByID(ctx, "1")
+			store.GetByID(ctx, "3")
+		}
+		store.GetByID(ctx, "2")
+		store.GetByID(ctx, "1")
+
+		// 2 is the most recent, but the least used
+		store.Insert(ctx, "4", &TestEntity{ID: "4"})
+		assert.Equal(t, []evicted{{id: "2", reason: Capacity}}, evictions)
+
+		// The new entity is never the one evicted
+		store.Insert(ctx, "5", &TestEntity{ID: "5"})
+		assert.Equal(t, evicted{id: "4", reason: Capacity}, evictions[1])
+	})
+
+	t.Run("Bulk writes and ExecuteUpdate", func(t *testing.T) {
+		store := NewKeyedMemStore[int, Counter](WithMaxEntries(10))
+		entries := make([]gostore.Entry[int, Counter], 50)
+		for i := range entries {
+			entries[i] = gostore.Entry[int, Counter]{ID: i, Entity: &Counter{Count: i}}
+		}
+		_, err := store.InsertMany(ctx, entries, gostore.BulkOptions{})
+		require.NoError(t, err)
+
+		_, err = store.ExecuteUpdate(ctx, func(ctx context.Context, data map[int]Counter) (int, error) {
+			for i := 100; i < 120; i++ {
+				data[i] = Counter{Count: i}
+			}
+			return 20, nil
+		})
+		require.NoError(t, err)
+
+		n, err := store.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), n)
+	})
+
+	t.Run("Shards", func(t *testing.T) {
+		store := NewKeyedMemStore[int, Counter](WithShards(4), WithMaxEntries(20))
+		for i := 0; i < 100; i++ {
+			_, err := store.Insert(ctx, i, &Counter{Count: i})
+			require.NoError(t, err)
+		}
+		n, err := store.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(20), n)
+		_, err = store.GetByID(ctx, 99)
+		assert.NoError(t, err)
+	})
+
+	t.Run("The callback may use the store", func(t *testing.T) {
+		var store *MemStore[string, TestEntity]
+		counts := []int64{}
+		store = NewMemStore[TestEntity](WithMaxEntries(1), WithOnEvict(func(id string, entity TestEntity, reason EvictionReason) {
+			n, _ := store.Count(ctx)
+			counts = append(counts, n)
+		}))
+		store.Insert(ctx, "1", &TestEntity{ID: "1"})
+		store.Insert(ctx, "2", &TestEntity{ID: "2"})
+		assert.Equal(t, []int64{1}, counts)
+	})
+
+	t.Run("Durable", func(t *testing.T) {
+		dir := t.TempDir()
+		store, err := OpenDurable[TestEntity](dir, WithMaxEntries(2))
+		require.NoError(t, err)
+		for _, id := range []string{"1", "2", "3"} {
+			store.Insert(ctx, id, &TestEntity{ID: id})
+		}
+		require.NoError(t, store.Close())
+
+		store, err = OpenDurable[TestEntity](dir, WithMaxEntries(2))
+		require.NoError(t, err)
+		defer store.Close()
+		all, err := store.GetAll(ctx)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []*TestEntity{{ID: "2"}, {ID: "3"}}, all)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		source := NewMemStore[TestEntity]()
+		for i := 0; i < 10; i++ {
+			id := strconv.Itoa(i)
+			source.Insert(ctx, id, &TestEntity{ID: id})
+		}
+		var snapshot strings.Builder
+		require.NoError(t, source.Snapshot(&snapshot))
+
+		store := NewMemStore[TestEntity](WithMaxEntries(4))
+		require.NoError(t, store.Restore(strings.NewReader(snapshot.String())))
+		n, err := store.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), n)
+	})
+}
+
+func TestWithMaxBytes(t *testing.T) {
+	ctx := context.Background()
+	entity := func(id string) *TestEntity {
+		return &TestEntity{ID: id, Value: strings.Repeat("x", 100)}
+	}
+	size := sizeOf(*entity("1"))
+
+	evictions := []evicted{}
+	store := NewMemStore[TestEntity](WithMaxBytes(3*size), recordEvictions(&evictions))
+	for _, id := range []string{"1", "2", "3", "4"} {
+		store.Insert(ctx, id, entity(id))
+	}
+	assert.Equal(t, []evicted{{id: "1", reason: Capacity}}, evictions)
+	assert.Equal(t, 3*size, store.bytes)
+
+	// A bigger entity takes the room of two
+	big := &TestEntity{ID: "5", Value: strings.Repeat("x", 150)}
+	store.Insert(ctx, "5", big)
+	assert.Len(t, evictions, 3)
+	n, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	require.NoError(t, store.Delete(ctx, "5"))
+	assert.Equal(t, size, store.bytes)
+
+	t.Run("An entity over the limit stays", func(t *testing.T) {
+		store := NewMemStore[TestEntity](WithMaxBytes(10))
+		store.Insert(ctx, "1", entity("1"))
+		_, err := store.GetByID(ctx, "1")
+		assert.NoError(t, err)
+	})
+}
+
+func TestWithMaxEntries_Race(t *testing.T) {
+	ctx := context.Background()
+	store := NewKeyedMemStore[int, Counter](WithShards(4), WithMaxEntries(50), WithEviction(LFU))
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				id := (w*200 + i) % 120
+				store.Upsert(ctx, id, &Counter{Count: i})
+				store.GetByID(ctx, id/2)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	n, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), n)
+}
+
+func TestSizeOf(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+
+	assert.Equal(t, int64(16+3), sizeOf("abc"))
+	assert.Equal(t, int64(24+4*8), sizeOf(make([]int, 2, 4)))
+	assert.Equal(t, int64(24+2*16+2), sizeOf([]string{"a", "b"}))
+	assert.Equal(t, int64(8+2*(16+8)+2), sizeOf(map[string]int{"a": 1, "b": 2}))
+	assert.Equal(t, int64(16+8), sizeOf[any](int64(1)))
+
+	// Shared memory is counted once
+	loop := &node{Name: "a"}
+	loop.Next = loop
+	assert.Equal(t, int64(8+24+1), sizeOf(loop))
+}
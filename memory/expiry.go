@@ -0,0 +1,143 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	store "github.com/Silencevoice/go-store"
+)
+
+// lazyExpirySamples is how many deadlines of its shard a write checks,
+// removing the expired ones, so expired entities do not pile up between the
+// runs of AutoExpire.
+const lazyExpirySamples = 16
+
+// WithTTL expires every entity ttl after it was last written. Expired
+// entities are no longer found, and are removed by the writes to their
+// shard, by RemoveExpired and by AutoExpire.
+//
+// The deadlines are only kept in memory: snapshots and the log of a durable
+// store do not have them, so the entities loaded from them get a new TTL.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.limits.ttl = ttl
+	}
+}
+
+// Expire sets the TTL of a single entity, counting from now, or removes it
+// when ttl is 0. Like in Redis, the next write of the entity sets the TTL of
+// WithTTL again. It applies right away, even inside a transaction.
+func (m *MemStore[K, T]) Expire(ctx context.Context, id K, ttl time.Duration) error {
+	release := m.lockKey(id, true)
+	defer release()
+
+	if _, _, ok := m.get(id); !ok {
+		return store.NewError(backend, "Expire", id, store.ErrNotFound)
+	}
+	m.setDeadline(m.shard(id), id, ttl)
+	return nil
+}
+
+func (m *MemStore[K, T]) setDeadline(s *shard[K, T], id K, ttl time.Duration) {
+	if ttl <= 0 {
+		delete(s.expires, id)
+		return
+	}
+	if s.expires == nil {
+		s.expires = map[K]time.Time{}
+	}
+	s.expires[id] = m.now().Add(ttl)
+}
+
+// restoreDeadline puts back a deadline saved before a write, zero meaning
+// there was none.
+func (m *MemStore[K, T]) restoreDeadline(s *shard[K, T], id K, deadline time.Time) {
+	if deadline.IsZero() {
+		delete(s.expires, id)
+		return
+	}
+	if s.expires == nil {
+		s.expires = map[K]time.Time{}
+	}
+	s.expires[id] = deadline
+}
+
+// RemoveExpired removes every expired entity, returning how many. It locks
+// a shard at a time, unless the writes of the store lock the whole store.
+func (m *MemStore[K, T]) RemoveExpired() (int, error) {
+	n := 0
+	for _, s := range m.shards {
+		removed, err := m.removeExpired(s)
+		if err != nil {
+			return n, store.NewError(backend, "RemoveExpired", nil, err)
+		}
+		n += removed
+	}
+	return n, nil
+}
+
+func (m *MemStore[K, T]) removeExpired(s *shard[K, T]) (int, error) {
+	release := m.lockShard(s, true)
+	defer release()
+
+	n := m.dropExpired(s, m.now(), 0)
+	if err := m.flush(m); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// dropExpired removes the expired entities of s, checking at most limit
+// deadlines unless limit is 0.
+func (m *MemStore[K, T]) dropExpired(s *shard[K, T], now time.Time, limit int) int {
+	n, checked := 0, 0
+	for id, deadline := range s.expires {
+		if limit > 0 && checked == limit {
+			break
+		}
+		checked++
+		if !now.Before(deadline) {
+			m.drop(id, Expired)
+			n++
+		}
+	}
+	return n
+}
+
+// AutoExpire calls RemoveExpired every interval until ctx is done. It stops
+// at the first error, which only durable stores may have, returning it. It
+// is meant to run in its own goroutine:
+//
+//	go store.AutoExpire(ctx, time.Minute)
+func (m *MemStore[K, T]) AutoExpire(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := m.RemoveExpired(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *shard[K, T]) expired(id K, now time.Time) bool {
+	deadline, ok := s.expires[id]
+	return ok && !now.Before(deadline)
+}
+
+// expiring tells whether some entity has a deadline, in which case the
+// operations cannot hand out the maps of the shards. It must be called with
+// the whole store locked.
+func (m *MemStore[K, T]) expiring() bool {
+	for _, s := range m.shards {
+		if len(s.expires) > 0 {
+			return true
+		}
+	}
+	return false
+}
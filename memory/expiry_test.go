@@ -0,0 +1,252 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a time.Now that only moves when told to.
+type clock struct {
+	sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestWithTTL(t *testing.T) {
+	ctx := context.Background()
+	newStore := func(opts ...Option) (*MemStore[string, TestEntity], *clock) {
+		clock := &clock{now: time.Now()}
+		store := NewMemStore[TestEntity](append([]Option{WithTTL(time.Minute)}, opts...)...)
+		store.now = clock.Now
+		return store, clock
+	}
+
+	t.Run("Expired entities are not found", func(t *testing.T) {
+		store, clock := newStore(WithIndex("value", func(e TestEntity) string { return e.Value }))
+		store.Insert(ctx, "1", &TestEntity{ID: "1", Value: "a"})
+		clock.Add(30 * time.Second)
+		store.Insert(ctx, "2", &TestEntity{ID: "2", Value: "a"})
+		clock.Add(30 * time.Second)
+
+		_, err := store.GetByID(ctx, "1")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+		exists, err := store.Exists(ctx, "1")
+		require.NoError(t, err)
+		assert.False(t, exists)
+		_, err = store.GetMultipleByID(ctx, []string{"1", "2"})
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+
+		all, err := store.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*TestEntity{{ID: "2", Value: "a"}}, all)
+		n, err := store.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+		found, err := store.FindByIndex(ctx, "value", "a")
+		require.NoError(t, err)
+		assert.Len(t, found, 1)
+		_, err = store.ExecuteQuery(ctx, func(ctx context.Context, data map[string]TestEntity) ([]*TestEntity, error) {
+			assert.Len(t, data, 1)
+			return nil, nil
+		})
+		require.NoError(t, err)
+
+		var snapshot strings.Builder
+		require.NoError(t, store.Snapshot(&snapshot))
+		restored := NewMemStore[TestEntity]()
+		require.NoError(t, restored.Restore(strings.NewReader(snapshot.String())))
+		n, err = restored.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+
+	t.Run("Writes start the TTL again", func(t *testing.T) {
+		store, clock := newStore()
+		store.Insert(ctx, "1", &TestEntity{ID: "1"})
+		clock.Add(50 * time.Second)
+		require.NoError(t, store.Update(ctx, "1", &TestEntity{ID: "1", Value: "v2"}))
+		clock.Add(50 * time.Second)
+
+		entity, err := store.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "v2", entity.Value)
+	})
+
+	t.Run("Expired entities can be written again", func(t *testing.T) {
+		evictions := []evicted{}
+		store, clock := newStore(recordEvictions(&evictions),
+			WithUniqueIndex("value", func(e TestEntity) string { return e.Value }))
+		store.Insert(ctx, "1", &TestEntity{ID: "1", Value: "a"})
+		clock.Add(time.Minute)
+
+		err := store.Update(ctx, "1", &TestEntity{ID: "1"})
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+		_, err = store.Insert(ctx, "2", &TestEntity{ID: "2", Value: "a"})
+		require.NoError(t, err)
+		_, err = store.Insert(ctx, "1", &TestEntity{ID: "1", Value: "b"})
+		require.NoError(t, err)
+
+		_, version, err := store.GetWithVersion(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), version)
+		assert.Equal(t, []evicted{{id: "1", reason: Expired}}, evictions)
+	})
+
+	t.Run("Writes remove expired entities", func(t *testing.T) {
+		evictions := []evicted{}
+		store, clock := newStore(recordEvictions(&evictions))
+		store.Insert(ctx, "1", &TestEntity{ID: "1"})
+		clock.Add(time.Minute)
+
+		store.Insert(ctx, "2", &TestEntity{ID: "2"})
+		assert.Equal(t, []evicted{{id: "1", reason: Expired}}, evictions)
+		assert.Len(t, store.shards[0].data, 1)
+	})
+
+	t.Run("ExecuteUpdate", func(t *testing.T) {
+		store, clock := newStore()
+		store.Insert(ctx, "1", &TestEntity{ID: "1"})
+		clock.Add(30 * time.Second)
+		_, err := store.ExecuteUpdate(ctx, func(ctx context.Context, data map[string]TestEntity) (int, error) {
+			data["2"] = TestEntity{ID: "2"}
+			return 1, nil
+		})
+		require.NoError(t, err)
+		clock.Add(30 * time.Second)
+
+		all, err := store.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*TestEntity{{ID: "2"}}, all)
+	})
+
+	t.Run("Transactions", func(t *testing.T) {
+		store, clock := newStore()
+		store.Insert(ctx, "1", &TestEntity{ID: "1"})
+		clock.Add(time.Minute)
+
+		err := WithTx(ctx, func(ctx context.Context) error {
+			_, err := store.GetByID(ctx, "1")
+			assert.ErrorIs(t, err, gostore.ErrNotFound)
+			_, err = store.Insert(ctx, "1", &TestEntity{ID: "1", Value: "new"})
+			return err
+		})
+		require.NoError(t, err)
+
+		entity, err := store.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "new", entity.Value)
+	})
+}
+
+func TestExpire(t *testing.T) {
+	ctx := context.Background()
+	clock := &clock{now: time.Now()}
+	store := NewMemStore[TestEntity](WithShards(4))
+	store.now = clock.Now
+	for _, id := range []string{"1", "2", "3"} {
+		store.Insert(ctx, id, &TestEntity{ID: id})
+	}
+
+	require.NoError(t, store.Expire(ctx, "1", time.Second))
+	require.NoError(t, store.Expire(ctx, "2", time.Hour))
+	require.NoError(t, store.Expire(ctx, "3", time.Second))
+	require.NoError(t, store.Expire(ctx, "3", 0))
+	clock.Add(time.Second)
+
+	all, err := store.GetAll(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*TestEntity{{ID: "2"}, {ID: "3"}}, all)
+
+	err = store.Expire(ctx, "1", time.Second)
+	assert.ErrorIs(t, err, gostore.ErrNotFound)
+
+	// Writes without WithTTL remove it
+	require.NoError(t, store.Update(ctx, "2", &TestEntity{ID: "2"}))
+	clock.Add(time.Hour)
+	_, err = store.GetByID(ctx, "2")
+	assert.NoError(t, err)
+}
+
+func TestRemoveExpired(t *testing.T) {
+	ctx := context.Background()
+	clock := &clock{now: time.Now()}
+	evictions := []evicted{}
+	store := NewMemStore[TestEntity](WithShards(4), WithTTL(time.Minute), recordEvictions(&evictions))
+	store.now = clock.Now
+	store.Insert(ctx, "1", &TestEntity{ID: "1"})
+	store.Insert(ctx, "2", &TestEntity{ID: "2"})
+	clock.Add(30 * time.Second)
+	store.Insert(ctx, "3", &TestEntity{ID: "3"})
+	clock.Add(30 * time.Second)
+
+	n, err := store.RemoveExpired()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.ElementsMatch(t, []evicted{{id: "1", reason: Expired}, {id: "2", reason: Expired}}, evictions)
+
+	left := 0
+	for _, s := range store.shards {
+		left += len(s.data)
+	}
+	assert.Equal(t, 1, left)
+
+	t.Run("Durable", func(t *testing.T) {
+		dir := t.TempDir()
+		store, err := OpenDurable[TestEntity](dir, WithTTL(time.Minute))
+		require.NoError(t, err)
+		store.now = clock.Now
+		store.Insert(ctx, "1", &TestEntity{ID: "1"})
+		store.Insert(ctx, "2", &TestEntity{ID: "2"})
+		require.NoError(t, store.Expire(ctx, "2", time.Hour))
+		clock.Add(time.Minute)
+		_, err = store.RemoveExpired()
+		require.NoError(t, err)
+		require.NoError(t, store.Close())
+
+		store, err = OpenDurable[TestEntity](dir)
+		require.NoError(t, err)
+		defer store.Close()
+		all, err := store.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*TestEntity{{ID: "2"}}, all)
+	})
+}
+
+func TestAutoExpire(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := &clock{now: time.Now()}
+	store := NewMemStore[TestEntity](WithTTL(time.Minute))
+	store.now = clock.Now
+	store.Insert(ctx, "1", &TestEntity{ID: "1"})
+
+	done := make(chan error)
+	go func() { done <- store.AutoExpire(ctx, time.Millisecond) }()
+
+	clock.Add(time.Minute)
+	assert.Eventually(t, func() bool {
+		release := store.lockAll(false)
+		defer release()
+		return len(store.shards[0].data) == 0
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,184 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	store "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/internal/fields"
+)
+
+// ErrUnknownIndex is returned by FindByIndex for an index the store does not
+// have.
+var ErrUnknownIndex = errors.New("unknown index")
+
+type indexDef[T any] struct {
+	name   string
+	unique bool
+	typ    reflect.Type
+	key    func(T) any
+}
+
+// WithIndex adds a secondary index on the value returned by key, which
+// FindByIndex uses to find entities without scanning the whole store:
+//
+//	memory.NewMemStore[Car](memory.WithIndex("model", func(c Car) string { return c.Model }))
+func WithIndex[T any, V comparable](name string, key func(T) V) Option {
+	return withIndex(name, false, key)
+}
+
+// WithUniqueIndex is WithIndex for values that cannot be repeated. Writes
+// that would store two entities with the same value fail with
+// store.ErrUniqueViolation.
+func WithUniqueIndex[T any, V comparable](name string, key func(T) V) Option {
+	return withIndex(name, true, key)
+}
+
+func withIndex[T any, V comparable](name string, unique bool, key func(T) V) Option {
+	def := indexDef[T]{
+		name:   name,
+		unique: unique,
+		typ:    reflect.TypeOf((*V)(nil)).Elem(),
+		key:    func(v T) any { return key(v) },
+	}
+	return func(o *options) {
+		o.indexes = append(o.indexes, def)
+	}
+}
+
+// index maps every value of an indexDef to the ids of the entities that have
+// it.
+type index[K comparable, T any] struct {
+	indexDef[T]
+	entries map[any]map[K]struct{}
+}
+
+// newIndexes builds the indexes of a store. It panics when an index does
+// not match T or the same name is used twice.
+func newIndexes[K comparable, T any](defs []any) []*index[K, T] {
+	indexes := make([]*index[K, T], 0, len(defs))
+	for _, d := range defs {
+		def, ok := d.(indexDef[T])
+		if !ok {
+			var zero T
+			panic(fmt.Sprintf("memory: index is not defined for %T", zero))
+		}
+		for _, idx := range indexes {
+			if idx.name == def.name {
+				panic(fmt.Sprintf("memory: duplicate index %q", def.name))
+			}
+		}
+		indexes = append(indexes, &index[K, T]{indexDef: def, entries: map[any]map[K]struct{}{}})
+	}
+	return indexes
+}
+
+func (idx *index[K, T]) add(id K, value T) {
+	key := idx.key(value)
+	ids, ok := idx.entries[key]
+	if !ok {
+		ids = map[K]struct{}{}
+		idx.entries[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func (idx *index[K, T]) remove(id K, value T) {
+	key := idx.key(value)
+	delete(idx.entries[key], id)
+	if len(idx.entries[key]) == 0 {
+		delete(idx.entries, key)
+	}
+}
+
+func (m *MemStore[K, T]) index(name string) (*index[K, T], bool) {
+	for _, idx := range m.indexes {
+		if idx.name == name {
+			return idx, true
+		}
+	}
+	return nil, false
+}
+
+// FindByIndex returns the entities whose value in the named index is value,
+// ordered by id. value is converted to the type of the index when needed.
+func (m *MemStore[K, T]) FindByIndex(ctx context.Context, name string, value any) ([]*T, error) {
+	idx, ok := m.index(name)
+	if !ok {
+		return nil, store.NewError(backend, "FindByIndex", nil, fmt.Errorf("%w %q", ErrUnknownIndex, name))
+	}
+	key, err := fields.Convert(value, idx.typ)
+	if err != nil {
+		return nil, store.NewError(backend, "FindByIndex", nil, fmt.Errorf("index %q: %w", name, err))
+	}
+
+	v, release, err := m.view(ctx, false)
+	if err != nil {
+		return nil, store.NewError(backend, "FindByIndex", nil, err)
+	}
+	defer release()
+
+	type match struct {
+		id    K
+		value T
+	}
+	matches := []match{}
+	v.byIndex(idx, key.Interface(), func(id K, value T) bool {
+		matches = append(matches, match{id: id, value: value})
+		return true
+	})
+	sort.Slice(matches, func(i, j int) bool {
+		return comparePositions(nil, nil, matches[i].id, nil, matches[j].id) < 0
+	})
+
+	ents := make([]*T, len(matches))
+	for i, match := range matches {
+		value := m.copy(match.value)
+		ents[i] = &value
+	}
+	return ents, nil
+}
+
+// checkUnique fails when storing value as id in v would repeat a value of a
+// unique index.
+func (m *MemStore[K, T]) checkUnique(v view[K, T], id K, value T) error {
+	for _, idx := range m.indexes {
+		if !idx.unique {
+			continue
+		}
+
+		var err error
+		v.byIndex(idx, idx.key(value), func(other K, _ T) bool {
+			if other != id {
+				err = fmt.Errorf("%w: index %q", store.ErrUniqueViolation, idx.name)
+			}
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkUniqueAll is checkUnique for a whole set of entities.
+func (m *MemStore[K, T]) checkUniqueAll(data map[K]T) error {
+	for _, idx := range m.indexes {
+		if !idx.unique {
+			continue
+		}
+
+		seen := make(map[any]struct{}, len(data))
+		for _, value := range data {
+			key := idx.key(value)
+			if _, ok := seen[key]; ok {
+				return fmt.Errorf("%w: index %q", store.ErrUniqueViolation, idx.name)
+			}
+			seen[key] = struct{}{}
+		}
+	}
+	return nil
+}
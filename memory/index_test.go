@@ -0,0 +1,244 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Plate struct {
+	Number string
+	Model  string
+	Year   int
+}
+
+func newPlateStore() *MemStore[string, Plate] {
+	return NewMemStore[Plate](
+		WithIndex("model", func(p Plate) string { return p.Model }),
+		WithIndex("year", func(p Plate) int { return p.Year }),
+		WithUniqueIndex("number", func(p Plate) string { return p.Number }),
+	)
+}
+
+func numbers(t *testing.T, plates []*Plate) []string {
+	t.Helper()
+	result := make([]string, len(plates))
+	for i, p := range plates {
+		result[i] = p.Number
+	}
+	return result
+}
+
+func TestFindByIndex(t *testing.T) {
+	ctx := context.Background()
+	store := newPlateStore()
+	store.Insert(ctx, "3", &Plate{Number: "C", Model: "Corolla", Year: 2020})
+	store.Insert(ctx, "1", &Plate{Number: "A", Model: "Corolla", Year: 2021})
+	store.Insert(ctx, "2", &Plate{Number: "B", Model: "Yaris", Year: 2020})
+
+	t.Run("Find by value", func(t *testing.T) {
+		plates, err := store.FindByIndex(ctx, "model", "Corolla")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"A", "C"}, numbers(t, plates))
+	})
+
+	t.Run("Values are converted", func(t *testing.T) {
+		plates, err := store.FindByIndex(ctx, "year", int64(2020))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"B", "C"}, numbers(t, plates))
+	})
+
+	t.Run("Missing value", func(t *testing.T) {
+		plates, err := store.FindByIndex(ctx, "model", "Prius")
+		require.NoError(t, err)
+		assert.Empty(t, plates)
+	})
+
+	t.Run("Index follows writes", func(t *testing.T) {
+		store := newPlateStore()
+		store.Insert(ctx, "1", &Plate{Number: "A", Model: "Corolla"})
+		store.Insert(ctx, "2", &Plate{Number: "B", Model: "Corolla"})
+		require.NoError(t, store.Update(ctx, "1", &Plate{Number: "A", Model: "Yaris"}))
+		require.NoError(t, store.Patch(ctx, "2", gostore.Fields(gostore.Set("model", "Prius"))))
+		_, err := store.Upsert(ctx, "3", &Plate{Number: "C", Model: "Prius"})
+		require.NoError(t, err)
+
+		plates, err := store.FindByIndex(ctx, "model", "Corolla")
+		require.NoError(t, err)
+		assert.Empty(t, plates)
+
+		plates, err = store.FindByIndex(ctx, "model", "Prius")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"B", "C"}, numbers(t, plates))
+
+		require.NoError(t, store.Delete(ctx, "3"))
+		plates, err = store.FindByIndex(ctx, "model", "Prius")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"B"}, numbers(t, plates))
+	})
+
+	t.Run("Index follows ExecuteUpdate", func(t *testing.T) {
+		store := newPlateStore()
+		store.Insert(ctx, "1", &Plate{Number: "A", Model: "Corolla"})
+		_, err := store.ExecuteUpdate(ctx, func(ctx context.Context, data map[string]Plate) (int, error) {
+			p := data["1"]
+			p.Model = "Yaris"
+			data["1"] = p
+			data["2"] = Plate{Number: "B", Model: "Yaris"}
+			return 2, nil
+		})
+		require.NoError(t, err)
+
+		plates, err := store.FindByIndex(ctx, "model", "Yaris")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"A", "B"}, numbers(t, plates))
+
+		_, version, err := store.GetWithVersion(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), version)
+	})
+
+	t.Run("Unknown index", func(t *testing.T) {
+		_, err := store.FindByIndex(ctx, "color", "red")
+		assert.ErrorIs(t, err, ErrUnknownIndex)
+	})
+
+	t.Run("Invalid value", func(t *testing.T) {
+		_, err := store.FindByIndex(ctx, "year", "twenty")
+		assert.Error(t, err)
+	})
+}
+
+func TestUniqueIndex(t *testing.T) {
+	ctx := context.Background()
+	store := newPlateStore()
+	store.Insert(ctx, "1", &Plate{Number: "A"})
+	store.Insert(ctx, "2", &Plate{Number: "B"})
+
+	t.Run("Duplicate values are rejected", func(t *testing.T) {
+		writes := map[string]func() error{
+			"Insert":          func() error { _, err := store.Insert(ctx, "3", &Plate{Number: "A"}); return err },
+			"Update":          func() error { return store.Update(ctx, "2", &Plate{Number: "A"}) },
+			"Replace":         func() error { return store.Replace(ctx, "2", &Plate{Number: "A"}) },
+			"Upsert":          func() error { _, err := store.Upsert(ctx, "3", &Plate{Number: "A"}); return err },
+			"UpdateIfVersion": func() error { return store.UpdateIfVersion(ctx, "2", 1, &Plate{Number: "A"}) },
+			"Patch":           func() error { return store.Patch(ctx, "2", gostore.Fields(gostore.Set("number", "A"))) },
+			"InsertMany": func() error {
+				_, err := store.InsertMany(ctx, []gostore.Entry[string, Plate]{{ID: "3", Entity: &Plate{Number: "A"}}}, gostore.BulkOptions{})
+				return err
+			},
+			"UpdateMany": func() error {
+				_, err := store.UpdateMany(ctx, []gostore.Entry[string, Plate]{{ID: "2", Entity: &Plate{Number: "A"}}}, gostore.BulkOptions{})
+				return err
+			},
+			"ExecuteUpdate": func() error {
+				_, err := store.ExecuteUpdate(ctx, func(ctx context.Context, data map[string]Plate) (int, error) {
+					data["2"] = Plate{Number: "A"}
+					return 1, nil
+				})
+				return err
+			},
+		}
+
+		for name, write := range writes {
+			t.Run(name, func(t *testing.T) {
+				err := write()
+				assert.ErrorIs(t, err, gostore.ErrUniqueViolation)
+				assert.ErrorContains(t, err, `index "number"`)
+
+				plates, err := store.GetAll(ctx)
+				require.NoError(t, err)
+				assert.ElementsMatch(t, []string{"A", "B"}, numbers(t, plates))
+			})
+		}
+	})
+
+	t.Run("An entity keeps its own value", func(t *testing.T) {
+		require.NoError(t, store.Update(ctx, "1", &Plate{Number: "A", Model: "Yaris"}))
+	})
+
+	t.Run("Freed values can be reused", func(t *testing.T) {
+		require.NoError(t, store.Update(ctx, "2", &Plate{Number: "C"}))
+		_, err := store.Insert(ctx, "3", &Plate{Number: "B"})
+		require.NoError(t, err)
+	})
+}
+
+func TestIndex_Tx(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Reads see staged writes", func(t *testing.T) {
+		store := newPlateStore()
+		store.Insert(ctx, "1", &Plate{Number: "A", Model: "Corolla"})
+		store.Insert(ctx, "2", &Plate{Number: "B", Model: "Corolla"})
+
+		err := WithTx(ctx, func(ctx context.Context) error {
+			require.NoError(t, store.Update(ctx, "1", &Plate{Number: "A", Model: "Yaris"}))
+			require.NoError(t, store.Delete(ctx, "2"))
+			_, err := store.Insert(ctx, "3", &Plate{Number: "C", Model: "Yaris"})
+			require.NoError(t, err)
+
+			plates, err := store.FindByIndex(ctx, "model", "Corolla")
+			require.NoError(t, err)
+			assert.Empty(t, plates)
+
+			plates, err = store.FindByIndex(ctx, "model", "Yaris")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"A", "C"}, numbers(t, plates))
+
+			// Number B was freed by the delete
+			_, err = store.Insert(ctx, "4", &Plate{Number: "B"})
+			require.NoError(t, err)
+			_, err = store.Insert(ctx, "5", &Plate{Number: "C"})
+			assert.ErrorIs(t, err, gostore.ErrUniqueViolation)
+			return nil
+		})
+		require.NoError(t, err)
+
+		plates, err := store.FindByIndex(ctx, "model", "Yaris")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"A", "C"}, numbers(t, plates))
+	})
+
+	t.Run("Commit checks unique indexes again", func(t *testing.T) {
+		store := newPlateStore()
+
+		err := WithTx(ctx, func(ctx context.Context) error {
+			if _, err := store.Insert(ctx, "1", &Plate{Number: "A"}); err != nil {
+				return err
+			}
+			_, err := store.Insert(context.Background(), "2", &Plate{Number: "A"})
+			return err
+		})
+		assert.ErrorIs(t, err, gostore.ErrUniqueViolation)
+
+		var storeErr *gostore.Error
+		require.True(t, errors.As(err, &storeErr))
+		assert.Equal(t, "Commit", storeErr.Op)
+
+		exists, err := store.Exists(ctx, "1")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
+
+func TestIndex_Options(t *testing.T) {
+	t.Run("Index for another type", func(t *testing.T) {
+		assert.Panics(t, func() {
+			NewMemStore[TestEntity](WithIndex("model", func(p Plate) string { return p.Model }))
+		})
+	})
+
+	t.Run("Duplicate index", func(t *testing.T) {
+		assert.PanicsWithValue(t, `memory: duplicate index "model"`, func() {
+			NewMemStore[Plate](
+				WithIndex("model", func(p Plate) string { return p.Model }),
+				WithUniqueIndex("model", func(p Plate) string { return p.Model }),
+			)
+		})
+	})
+}
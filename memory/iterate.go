@@ -0,0 +1,26 @@
+package memory
+
+import (
+	"context"
+
+	store "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+)
+
+// Iterate streams the entities matching filter. The matches are copied under
+// the read lock, so later writes do not affect an open iterator.
+func (m *MemStore[K, T]) Iterate(ctx context.Context, filter query.Expr) (store.Iterator[T], error) {
+	ents, err := m.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return store.NewSliceIterator(ctx, ents), nil
+}
+
+func (m *MemStore[K, T]) IterateByID(ctx context.Context, ids []K) (store.Iterator[T], error) {
+	ents, err := m.GetMultipleByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return store.NewSliceIterator(ctx, ents), nil
+}
@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIterate(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore[TestEntity]()
+	store.Insert(ctx, "1", &TestEntity{ID: "1", Value: "value-1"})
+	store.Insert(ctx, "2", &TestEntity{ID: "2", Value: "value-2"})
+
+	t.Run("Iterate over a snapshot", func(t *testing.T) {
+		it, err := store.Iterate(ctx, query.Prefix("value", "value"))
+		require.NoError(t, err)
+
+		_, err = store.Insert(ctx, "3", &TestEntity{ID: "3", Value: "value-3"})
+		require.NoError(t, err)
+
+		entities, err := gostore.Collect(it)
+		require.NoError(t, err)
+		assert.Len(t, entities, 2)
+	})
+
+	t.Run("Iterate by ID", func(t *testing.T) {
+		it, err := store.IterateByID(ctx, []string{"2", "1"})
+		require.NoError(t, err)
+		defer it.Close()
+
+		require.True(t, it.Next())
+		assert.Equal(t, "value-2", it.Value().Value)
+		require.True(t, it.Next())
+		assert.Equal(t, "value-1", it.Value().Value)
+		assert.False(t, it.Next())
+	})
+
+	t.Run("Iterate by missing ID", func(t *testing.T) {
+		_, err := store.IterateByID(ctx, []string{"missing"})
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+	})
+
+	t.Run("Stop when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		it, err := store.Iterate(ctx, nil)
+		require.NoError(t, err)
+
+		require.True(t, it.Next())
+		cancel()
+		assert.False(t, it.Next())
+		assert.ErrorIs(t, it.Err(), context.Canceled)
+	})
+}
@@ -0,0 +1,175 @@
+package memory
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	store "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/internal/fields"
+	"github.com/Silencevoice/go-store/query"
+)
+
+type listEntry[K comparable, T any] struct {
+	key    K
+	value  T
+	values []any
+}
+
+type listCursor struct {
+	Values []json.RawMessage `json:"v"`
+	Key    json.RawMessage   `json:"k"`
+}
+
+func (m *MemStore[K, T]) List(ctx context.Context, filter query.Expr, opts store.FindOptions) (*store.Page[T], error) {
+	v, release, err := m.view(ctx, false)
+	if err != nil {
+		return nil, store.NewError(backend, "List", nil, err)
+	}
+	entries := []listEntry[K, T]{}
+	v.each(func(key K, value T) bool {
+		var ok bool
+		ok, err = query.Match(filter, value)
+		if ok {
+			entries = append(entries, listEntry[K, T]{key: key, value: value, values: sortValues(value, opts.Sort)})
+		}
+		return err == nil
+	})
+	release()
+	if err != nil {
+		return nil, store.NewError(backend, "List", nil, err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return comparePositions(opts.Sort, entries[i].values, entries[i].key, entries[j].values, entries[j].key) < 0
+	})
+
+	start := 0
+	if opts.Cursor != "" {
+		values, key, err := decodeCursor[K, T](opts.Cursor, opts.Sort)
+		if err != nil {
+			return nil, store.NewError(backend, "List", nil, err)
+		}
+		start = sort.Search(len(entries), func(i int) bool {
+			return comparePositions(opts.Sort, entries[i].values, entries[i].key, values, key) > 0
+		})
+	}
+	start = min(start+max(opts.Offset, 0), len(entries))
+	end := len(entries)
+	if opts.Limit > 0 {
+		end = min(start+opts.Limit, end)
+	}
+
+	page := &store.Page[T]{Items: make([]*T, 0, end-start), Total: int64(len(entries))}
+	for _, entry := range entries[start:end] {
+		value := m.copy(entry.value)
+		page.Items = append(page.Items, &value)
+	}
+	if end < len(entries) && end > start {
+		cursor, err := encodeCursor(entries[end-1])
+		if err != nil {
+			return nil, store.NewError(backend, "List", nil, err)
+		}
+		page.NextCursor = cursor
+	}
+
+	return page, nil
+}
+
+func sortValues(value any, sortFields []store.SortField) []any {
+	values := make([]any, len(sortFields))
+	for i, field := range sortFields {
+		values[i], _ = fields.Lookup(value, field.Field)
+	}
+	return values
+}
+
+// comparePositions orders two entities by their sort values and then by key,
+// which keeps the order total and equal to the one used by MongoStore.
+func comparePositions[K comparable](sortFields []store.SortField, aValues []any, aKey K, bValues []any, bKey K) int {
+	for i, field := range sortFields {
+		c := fields.SortCompare(aValues[i], bValues[i])
+		if field.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	c := fields.SortCompare(aKey, bKey)
+	if c == 0 && aKey != bKey {
+		c = strings.Compare(fmt.Sprint(aKey), fmt.Sprint(bKey))
+	}
+	return c
+}
+
+func encodeCursor[K comparable, T any](entry listEntry[K, T]) (string, error) {
+	cursor := listCursor{Values: make([]json.RawMessage, len(entry.values))}
+	for i, value := range entry.values {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		cursor.Values[i] = raw
+	}
+
+	key, err := json.Marshal(entry.key)
+	if err != nil {
+		return "", err
+	}
+	cursor.Key = key
+
+	raw, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodeCursor restores the cursor values with the static type of their
+// fields, so they compare exactly as the values read from the entities.
+func decodeCursor[K comparable, T any](token string, sortFields []store.SortField) ([]any, K, error) {
+	var key K
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, key, fmt.Errorf("%w: %w", store.ErrInvalidCursor, err)
+	}
+
+	var cursor listCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, key, fmt.Errorf("%w: %w", store.ErrInvalidCursor, err)
+	}
+	if len(cursor.Values) != len(sortFields) {
+		return nil, key, fmt.Errorf("%w: sort fields do not match", store.ErrInvalidCursor)
+	}
+
+	if err := json.Unmarshal(cursor.Key, &key); err != nil {
+		return nil, key, fmt.Errorf("%w: %w", store.ErrInvalidCursor, err)
+	}
+
+	entityType := reflect.TypeOf((*T)(nil)).Elem()
+	values := make([]any, len(sortFields))
+	for i, field := range sortFields {
+		if string(cursor.Values[i]) == "null" {
+			continue
+		}
+
+		fieldType, ok := fields.LookupType(entityType, field.Field)
+		if !ok {
+			fieldType = reflect.TypeOf((*any)(nil)).Elem()
+		}
+		value := reflect.New(fieldType)
+		if err := json.Unmarshal(cursor.Values[i], value.Interface()); err != nil {
+			return nil, key, fmt.Errorf("%w: %w", store.ErrInvalidCursor, err)
+		}
+		values[i] = value.Elem().Interface()
+	}
+
+	return values, key, nil
+}
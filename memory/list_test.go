@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type listEntity struct {
+	ID      string    `bson:"_id"`
+	Model   string    `bson:"model"`
+	Year    int       `bson:"year"`
+	Created time.Time `bson:"created"`
+}
+
+func newListStore(t *testing.T) *MemStore[string, listEntity] {
+	ctx := context.Background()
+	store := NewMemStore[listEntity]()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, e := range []listEntity{
+		{ID: "a", Model: "Yaris", Year: 2019},
+		{ID: "b", Model: "Corolla", Year: 2021},
+		{ID: "c", Model: "Corolla", Year: 2020},
+		{ID: "d", Model: "Auris", Year: 2021},
+		{ID: "e", Model: "Corolla", Year: 2021},
+	} {
+		e.Created = base.Add(time.Duration(i) * time.Hour)
+		_, err := store.Insert(ctx, e.ID, &e)
+		require.NoError(t, err)
+	}
+	return store
+}
+
+func ids(items []*listEntity) []string {
+	result := []string{}
+	for _, item := range items {
+		result = append(result, item.ID)
+	}
+	return result
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	store := newListStore(t)
+
+	t.Run("Order by key without sort fields", func(t *testing.T) {
+		page, err := store.List(ctx, nil, gostore.FindOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "c", "d", "e"}, ids(page.Items))
+		assert.Equal(t, int64(5), page.Total)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("Sort by several fields", func(t *testing.T) {
+		page, err := store.List(ctx, nil, gostore.FindOptions{Sort: []gostore.SortField{gostore.Desc("year"), gostore.Asc("model")}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"d", "b", "e", "c", "a"}, ids(page.Items))
+	})
+
+	t.Run("Filter, limit and offset", func(t *testing.T) {
+		page, err := store.List(ctx, query.Eq("model", "Corolla"), gostore.FindOptions{
+			Limit:  1,
+			Offset: 1,
+			Sort:   []gostore.SortField{gostore.Asc("year")},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b"}, ids(page.Items))
+		assert.Equal(t, int64(3), page.Total)
+		assert.NotEmpty(t, page.NextCursor)
+	})
+
+	t.Run("Offset past the end", func(t *testing.T) {
+		page, err := store.List(ctx, nil, gostore.FindOptions{Offset: 10})
+		require.NoError(t, err)
+		assert.Empty(t, page.Items)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("Keyset pagination", func(t *testing.T) {
+		opts := gostore.FindOptions{Limit: 2, Sort: []gostore.SortField{gostore.Desc("created")}}
+		visited := []string{}
+		for {
+			page, err := store.List(ctx, nil, opts)
+			require.NoError(t, err)
+			visited = append(visited, ids(page.Items)...)
+			if page.NextCursor == "" {
+				break
+			}
+			opts.Cursor = page.NextCursor
+		}
+		assert.Equal(t, []string{"e", "d", "c", "b", "a"}, visited)
+	})
+
+	t.Run("Cursor survives concurrent inserts", func(t *testing.T) {
+		store := newListStore(t)
+		opts := gostore.FindOptions{Limit: 2, Sort: []gostore.SortField{gostore.Asc("model")}}
+		page, err := store.List(ctx, nil, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"d", "b"}, ids(page.Items))
+
+		_, err = store.Insert(ctx, "0", &listEntity{ID: "0", Model: "Aygo"})
+		require.NoError(t, err)
+
+		opts.Cursor = page.NextCursor
+		page, err = store.List(ctx, nil, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"c", "e"}, ids(page.Items))
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		_, err := store.List(ctx, nil, gostore.FindOptions{Cursor: "not a cursor"})
+		assert.ErrorIs(t, err, gostore.ErrInvalidCursor)
+
+		page, err := store.List(ctx, nil, gostore.FindOptions{Limit: 1})
+		require.NoError(t, err)
+		_, err = store.List(ctx, nil, gostore.FindOptions{Cursor: page.NextCursor, Sort: []gostore.SortField{gostore.Asc("year")}})
+		assert.ErrorIs(t, err, gostore.ErrInvalidCursor)
+	})
+
+	t.Run("Invalid filter", func(t *testing.T) {
+		_, err := store.List(ctx, query.CompareExpr{Field: "year", Op: "like"}, gostore.FindOptions{})
+		assert.Error(t, err)
+	})
+}
@@ -2,110 +2,294 @@ package memory
 
 import (
 	"context"
-	"errors"
+	"hash/maphash"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	store "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
 )
 
-type MemStore[T any] struct {
-	sync.RWMutex
-	data map[string]T
+const backend = "memory"
+
+type MemStore[K comparable, T any] struct {
+	id      uint64
+	shards  []*shard[K, T]
+	seed    maphash.Seed
+	copy    func(T) T
+	indexes []*index[K, T]
+	codec   Codec
+	// changes counts the writes, so AutoSnapshot knows when to save
+	changes atomic.Uint64
+	// wal is only set for durable stores, which keep the changes of the
+	// current write in pending until they reach the log
+	wal     *wal
+	pending []pendingChange[K, T]
+
+	// The bounds, see eviction.go and expiry.go. ticks orders the uses of
+	// the entities for LRU, and bytes is their size when WithMaxBytes is set.
+	limits    limits
+	now       func() time.Time
+	ticks     atomic.Uint64
+	bytes     int64
+	onEvict   func(id K, entity T, reason EvictionReason)
+	evictMu   sync.Mutex
+	evictions []eviction[K, T]
+}
+
+// NewMemStore returns a string keyed MemStore.
+func NewMemStore[T any](opts ...Option) *MemStore[string, T] {
+	return NewKeyedMemStore[string, T](opts...)
+}
+
+// NewKeyedMemStore returns a MemStore keyed by K. Entities are copied on every
+// read and write according to WithCopy, indexed as told by WithIndex and
+// WithUniqueIndex, and kept in a single map unless WithShards says otherwise.
+// The store grows without bound, unless WithMaxEntries, WithMaxBytes or
+// WithTTL say otherwise.
+func NewKeyedMemStore[K comparable, T any](opts ...Option) *MemStore[K, T] {
+	return newMemStore[K, T](newOptions(opts))
 }
 
-func NewMemStore[T any]() *MemStore[T] {
-	return &MemStore[T]{
-		data: make(map[string]T),
+func newMemStore[K comparable, T any](o options) *MemStore[K, T] {
+	return &MemStore[K, T]{
+		id:      storeIDs.Add(1),
+		seed:    maphash.MakeSeed(),
+		copy:    copier[T](o.copy),
+		indexes: newIndexes[K, T](o.indexes),
+		codec:   o.codec,
+		limits:  o.limits,
+		now:     time.Now,
+		onEvict: newOnEvict[K, T](o.onEvict),
+		shards:  newShards[K, T](o.shards),
 	}
 }
 
-func (m *MemStore[T]) GetByID(ctx context.Context, id string) (*T, error) {
-	m.RLock()
-	defer m.RUnlock()
+func (m *MemStore[K, T]) GetByID(ctx context.Context, id K) (*T, error) {
+	v, release, err := m.viewKey(ctx, id, false)
+	if err != nil {
+		return nil, store.NewError(backend, "GetByID", id, err)
+	}
+	defer release()
 
-	ent, ok := m.data[id]
+	ent, _, ok := v.get(id)
 	if !ok {
-		return nil, errors.New("entity not found")
+		return nil, store.NewError(backend, "GetByID", id, store.ErrNotFound)
 	}
 
+	ent = m.copy(ent)
 	return &ent, nil
 }
 
-func (m *MemStore[T]) GetMultipleByID(ctx context.Context, ids []string) ([]*T, error) {
-	m.RLock()
-	defer m.RUnlock()
+func (m *MemStore[K, T]) GetMultipleByID(ctx context.Context, ids []K) ([]*T, error) {
+	v, release, err := m.view(ctx, false)
+	if err != nil {
+		return nil, store.NewError(backend, "GetMultipleByID", nil, err)
+	}
+	defer release()
 
 	ents := make([]*T, len(ids))
 	for idx, id := range ids {
-		ent, ok := m.data[id]
+		ent, _, ok := v.get(id)
 		if !ok {
-			return nil, errors.New("entity not found")
+			return nil, store.NewError(backend, "GetMultipleByID", id, store.ErrNotFound)
 		}
+		ent = m.copy(ent)
 		ents[idx] = &ent
 	}
 
 	return ents, nil
 }
 
-func (m *MemStore[T]) GetAll(ctx context.Context) ([]*T, error) {
-	m.RLock()
-	defer m.RUnlock()
+func (m *MemStore[K, T]) GetAll(ctx context.Context) ([]*T, error) {
+	v, release, err := m.view(ctx, false)
+	if err != nil {
+		return nil, store.NewError(backend, "GetAll", nil, err)
+	}
+	defer release()
 
 	ents := []*T{}
-	for _, value := range m.data {
+	v.each(func(id K, value T) bool {
+		value = m.copy(value)
 		ents = append(ents, &value)
-	}
+		return true
+	})
 
 	return ents, nil
 }
 
-func (m *MemStore[T]) Insert(ctx context.Context, id string, entity *T) (*T, error) {
-	m.Lock()
-	defer m.Unlock()
+func (m *MemStore[K, T]) Insert(ctx context.Context, id K, entity *T) (*T, error) {
+	v, release, err := m.viewKey(ctx, id, true)
+	if err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
+	defer release()
 
-	_, ok := m.data[id]
+	_, _, ok := v.get(id)
 	if ok {
-		return nil, errors.New("already existing key")
+		return nil, store.NewError(backend, "Insert", id, store.ErrAlreadyExists)
+	}
+
+	if err := m.checkUnique(v, id, *entity); err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
 	}
 
-	m.data[id] = *entity
+	ent := m.copy(*entity)
+	v.put(id, ent, 1)
+	if err := m.flush(v); err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
 
-	return entity, nil
+	ent = m.copy(ent)
+	return &ent, nil
 }
 
-func (m *MemStore[T]) Delete(ctx context.Context, id string) error {
-	m.Lock()
-	defer m.Unlock()
+func (m *MemStore[K, T]) Delete(ctx context.Context, id K) error {
+	v, release, err := m.viewKey(ctx, id, true)
+	if err != nil {
+		return store.NewError(backend, "Delete", id, err)
+	}
+	defer release()
 
-	_, ok := m.data[id]
+	_, _, ok := v.get(id)
 	if !ok {
-		return errors.New("entity not found")
+		return store.NewError(backend, "Delete", id, store.ErrNotFound)
 	}
 
-	delete(m.data, id)
+	v.remove(id)
+	if err := m.flush(v); err != nil {
+		return store.NewError(backend, "Delete", id, err)
+	}
 
 	return nil
 }
 
-func (m *MemStore[T]) Update(ctx context.Context, id string, entity *T) error {
-	m.Lock()
-	defer m.Unlock()
+func (m *MemStore[K, T]) Update(ctx context.Context, id K, entity *T) error {
+	return m.replaceExisting(ctx, "Update", id, entity)
+}
 
-	_, ok := m.data[id]
+// Replace stores a copy of the entity in place of the previous one, as
+// Update does: there are no fields unknown to T for Update to keep.
+func (m *MemStore[K, T]) Replace(ctx context.Context, id K, entity *T) error {
+	return m.replaceExisting(ctx, "Replace", id, entity)
+}
+
+func (m *MemStore[K, T]) replaceExisting(ctx context.Context, op string, id K, entity *T) error {
+	v, release, err := m.viewKey(ctx, id, true)
+	if err != nil {
+		return store.NewError(backend, op, id, err)
+	}
+	defer release()
+
+	_, version, ok := v.get(id)
 	if !ok {
-		return errors.New("entity not found")
+		return store.NewError(backend, op, id, store.ErrNotFound)
+	}
+	if err := m.checkUnique(v, id, *entity); err != nil {
+		return store.NewError(backend, op, id, err)
 	}
 
-	m.data[id] = *entity
+	v.put(id, m.copy(*entity), version+1)
+	if err := m.flush(v); err != nil {
+		return store.NewError(backend, op, id, err)
+	}
 	return nil
 }
 
-func (m *MemStore[T]) ExecuteQuery(ctx context.Context, f func(ctx context.Context, data map[string]T) ([]*T, error)) ([]*T, error) {
-	m.RLock()
-	defer m.RUnlock()
-	return f(ctx, m.data)
+func (m *MemStore[K, T]) Upsert(ctx context.Context, id K, entity *T) (bool, error) {
+	v, release, err := m.viewKey(ctx, id, true)
+	if err != nil {
+		return false, store.NewError(backend, "Upsert", id, err)
+	}
+	defer release()
+
+	_, version, ok := v.get(id)
+	if err := m.checkUnique(v, id, *entity); err != nil {
+		return false, store.NewError(backend, "Upsert", id, err)
+	}
+	v.put(id, m.copy(*entity), version+1)
+	if err := m.flush(v); err != nil {
+		return false, store.NewError(backend, "Upsert", id, err)
+	}
+
+	return !ok, nil
+}
+
+// ExecuteQuery gives f direct access to the entities, which are not copied:
+// f must not keep or modify what they point to. Inside a transaction, or when
+// the store has shards or entities with a TTL, f receives a map built for the
+// call, which includes the staged writes.
+func (m *MemStore[K, T]) ExecuteQuery(ctx context.Context, f func(ctx context.Context, data map[K]T) ([]*T, error)) ([]*T, error) {
+	v, release, err := m.view(ctx, false)
+	if err != nil {
+		return nil, store.NewError(backend, "ExecuteQuery", nil, err)
+	}
+	defer release()
+
+	if _, ok := v.(*overlay[K, T]); ok || len(m.shards) > 1 || m.expiring() {
+		return f(ctx, snapshot(v))
+	}
+	return f(ctx, m.shards[0].data)
+}
+
+// ExecuteUpdate gives f direct access to the entities, which are not copied
+// either. There is no way to tell which ones f changed, so every entity gets
+// a new version.
+//
+// Inside a transaction, or when the store has indexes, shards or bounds or is
+// durable, f works on a copy of the map instead. Only the entities it changed
+// are written, once the unique indexes are checked.
+func (m *MemStore[K, T]) ExecuteUpdate(ctx context.Context, f func(ctx context.Context, data map[K]T) (int, error)) (int, error) {
+	v, release, err := m.view(ctx, true)
+	if err != nil {
+		return 0, store.NewError(backend, "ExecuteUpdate", nil, err)
+	}
+	defer release()
+
+	if _, ok := v.(*overlay[K, T]); ok || len(m.indexes) > 0 || len(m.shards) > 1 || m.wal != nil || m.bounded() {
+		before, after := snapshot(v), snapshot(v)
+		for id, value := range after {
+			after[id] = m.copy(value)
+		}
+		n, err := f(ctx, after)
+		if err != nil {
+			return n, err
+		}
+		if err := m.checkUniqueAll(after); err != nil {
+			return n, store.NewError(backend, "ExecuteUpdate", nil, err)
+		}
+		stageChanges(v, before, after)
+		if err := m.flush(v); err != nil {
+			return n, store.NewError(backend, "ExecuteUpdate", nil, err)
+		}
+		return n, nil
+	}
+
+	defer m.bumpVersions()
+	return f(ctx, m.shards[0].data)
 }
 
-func (m *MemStore[T]) ExecuteUpdate(ctx context.Context, f func(ctx context.Context, data map[string]T) (int, error)) (int, error) {
-	m.Lock()
-	defer m.Unlock()
-	return f(ctx, m.data)
+func (m *MemStore[K, T]) Find(ctx context.Context, filter query.Expr) ([]*T, error) {
+	v, release, err := m.view(ctx, false)
+	if err != nil {
+		return nil, store.NewError(backend, "Find", nil, err)
+	}
+	defer release()
+
+	ents := []*T{}
+	v.each(func(id K, value T) bool {
+		var ok bool
+		ok, err = query.Match(filter, value)
+		if ok {
+			value = m.copy(value)
+			ents = append(ents, &value)
+		}
+		return err == nil
+	})
+	if err != nil {
+		return nil, store.NewError(backend, "Find", nil, err)
+	}
+
+	return ents, nil
 }
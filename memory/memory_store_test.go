@@ -5,6 +5,9 @@ import (
 	"errors"
 	"testing"
 
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+	"github.com/Silencevoice/go-store/storetest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -14,6 +17,27 @@ type TestEntity struct {
 	Value string
 }
 
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) gostore.KeyedStore[string, storetest.Entity] {
+		return NewMemStore[storetest.Entity]()
+	})
+
+	t.Run("Shards", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) gostore.KeyedStore[string, storetest.Entity] {
+			return NewMemStore[storetest.Entity](WithShards(4))
+		})
+	})
+
+	t.Run("Durable", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) gostore.KeyedStore[string, storetest.Entity] {
+			store, err := OpenDurable[storetest.Entity](t.TempDir())
+			require.NoError(t, err)
+			t.Cleanup(func() { store.Close() })
+			return store
+		})
+	})
+}
+
 func TestInsert(t *testing.T) {
 	ctx := context.Background()
 	store := NewMemStore[TestEntity]()
@@ -29,7 +53,7 @@ func TestInsert(t *testing.T) {
 		entity := TestEntity{ID: "1", Value: "test-value-duplicate"}
 		_, err := store.Insert(ctx, entity.ID, &entity)
 		assert.Error(t, err)
-		assert.Equal(t, "already existing key", err.Error())
+		assert.ErrorIs(t, err, gostore.ErrAlreadyExists)
 	})
 }
 
@@ -47,7 +71,17 @@ func TestGetByID(t *testing.T) {
 	t.Run("Get non-existent entity", func(t *testing.T) {
 		_, err := store.GetByID(ctx, "non-existent")
 		assert.Error(t, err)
-		assert.Equal(t, "entity not found", err.Error())
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+	})
+
+	t.Run("Error carries operation details", func(t *testing.T) {
+		_, err := store.GetByID(ctx, "non-existent")
+
+		var storeErr *gostore.Error
+		require.ErrorAs(t, err, &storeErr)
+		assert.Equal(t, "GetByID", storeErr.Op)
+		assert.Equal(t, "non-existent", storeErr.Key)
+		assert.Equal(t, "memory", storeErr.Backend)
 	})
 }
 
@@ -66,7 +100,7 @@ func TestGetMultipleByID(t *testing.T) {
 	t.Run("Get with some non-existent IDs", func(t *testing.T) {
 		_, err := store.GetMultipleByID(ctx, []string{"1", "non-existent"})
 		assert.Error(t, err)
-		assert.Equal(t, "entity not found", err.Error())
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
 	})
 }
 
@@ -89,7 +123,72 @@ func TestUpdate(t *testing.T) {
 		entity := TestEntity{ID: "non-existent", Value: "value"}
 		err := store.Update(ctx, "non-existent", &entity)
 		assert.Error(t, err)
-		assert.Equal(t, "entity not found", err.Error())
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+	})
+}
+
+func TestReplace(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore[TestEntity]()
+	store.Insert(ctx, "1", &TestEntity{ID: "1", Value: "test-value"})
+
+	t.Run("Replace existing entity", func(t *testing.T) {
+		err := store.Replace(ctx, "1", &TestEntity{ID: "1"})
+		require.NoError(t, err)
+
+		entity, err := store.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, &TestEntity{ID: "1"}, entity)
+	})
+
+	t.Run("Replace non-existent entity", func(t *testing.T) {
+		err := store.Replace(ctx, "non-existent", &TestEntity{})
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+	})
+}
+
+func TestUpsert(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore[TestEntity]()
+
+	t.Run("Upsert creates the entity", func(t *testing.T) {
+		created, err := store.Upsert(ctx, "1", &TestEntity{ID: "1", Value: "created"})
+		require.NoError(t, err)
+		assert.True(t, created)
+
+		entity, version, err := store.GetWithVersion(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "created", entity.Value)
+		assert.Equal(t, int64(1), version)
+	})
+
+	t.Run("Upsert overwrites the entity", func(t *testing.T) {
+		created, err := store.Upsert(ctx, "1", &TestEntity{ID: "1", Value: "overwritten"})
+		require.NoError(t, err)
+		assert.False(t, created)
+
+		entity, version, err := store.GetWithVersion(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "overwritten", entity.Value)
+		assert.Equal(t, int64(2), version)
+	})
+
+	t.Run("Concurrent upserts create once", func(t *testing.T) {
+		results := make(chan bool, 10)
+		for i := 0; i < 10; i++ {
+			go func() {
+				created, _ := store.Upsert(ctx, "2", &TestEntity{ID: "2"})
+				results <- created
+			}()
+		}
+
+		creations := 0
+		for i := 0; i < 10; i++ {
+			if <-results {
+				creations++
+			}
+		}
+		assert.Equal(t, 1, creations)
 	})
 }
 
@@ -104,13 +203,13 @@ func TestDelete(t *testing.T) {
 
 		_, err = store.GetByID(ctx, "1")
 		assert.Error(t, err)
-		assert.Equal(t, "entity not found", err.Error())
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
 	})
 
 	t.Run("Delete non-existent entity", func(t *testing.T) {
 		err := store.Delete(ctx, "non-existent")
 		assert.Error(t, err)
-		assert.Equal(t, "entity not found", err.Error())
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
 	})
 }
 
@@ -159,6 +258,38 @@ func TestExecuteQuery(t *testing.T) {
 	})
 }
 
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore[TestEntity]()
+	store.Insert(ctx, "1", &TestEntity{ID: "1", Value: "value-1"})
+	store.Insert(ctx, "2", &TestEntity{ID: "2", Value: "value-2"})
+	store.Insert(ctx, "3", &TestEntity{ID: "3", Value: "other"})
+
+	t.Run("Find matching entities", func(t *testing.T) {
+		result, err := store.Find(ctx, query.And(query.Prefix("value", "value"), query.Ne("id", "1")))
+		require.NoError(t, err)
+		assert.Len(t, result, 1)
+		assert.Equal(t, "value-2", result[0].Value)
+	})
+
+	t.Run("Find without filter", func(t *testing.T) {
+		result, err := store.Find(ctx, nil)
+		require.NoError(t, err)
+		assert.Len(t, result, 3)
+	})
+
+	t.Run("Find without matches", func(t *testing.T) {
+		result, err := store.Find(ctx, query.Eq("value", "missing"))
+		require.NoError(t, err)
+		assert.Empty(t, result)
+	})
+
+	t.Run("Find with invalid expression", func(t *testing.T) {
+		_, err := store.Find(ctx, query.CompareExpr{Field: "value", Op: "like"})
+		assert.Error(t, err)
+	})
+}
+
 func TestExecuteUpdate(t *testing.T) {
 	ctx := context.Background()
 	store := NewMemStore[TestEntity]()
@@ -184,3 +315,41 @@ func TestExecuteUpdate(t *testing.T) {
 		assert.Equal(t, "updated-value-1", ent.Value)
 	})
 }
+
+func TestKeyedMemStore(t *testing.T) {
+	ctx := context.Background()
+
+	var _ gostore.Store[TestEntity] = NewMemStore[TestEntity]()
+	var _ gostore.KeyedStore[int64, TestEntity] = NewKeyedMemStore[int64, TestEntity]()
+
+	t.Run("Integer keys", func(t *testing.T) {
+		store := NewKeyedMemStore[int64, TestEntity]()
+		_, err := store.Insert(ctx, 42, &TestEntity{ID: "42", Value: "value-42"})
+		require.NoError(t, err)
+
+		entity, err := store.GetByID(ctx, 42)
+		require.NoError(t, err)
+		assert.Equal(t, "value-42", entity.Value)
+
+		_, err = store.GetByID(ctx, 43)
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+		assert.ErrorContains(t, err, "GetByID 43")
+	})
+
+	t.Run("Composite keys", func(t *testing.T) {
+		type orderLine struct {
+			OrderID string
+			Line    int
+		}
+		store := NewKeyedMemStore[orderLine, TestEntity]()
+		_, err := store.Insert(ctx, orderLine{"A", 1}, &TestEntity{ID: "A-1", Value: "first"})
+		require.NoError(t, err)
+		_, err = store.Insert(ctx, orderLine{"A", 2}, &TestEntity{ID: "A-2", Value: "second"})
+		require.NoError(t, err)
+
+		entities, err := store.GetMultipleByID(ctx, []orderLine{{"A", 2}, {"A", 1}})
+		require.NoError(t, err)
+		assert.Equal(t, "second", entities[0].Value)
+		assert.Equal(t, "first", entities[1].Value)
+	})
+}
@@ -0,0 +1,30 @@
+package memory
+
+// Option configures a MemStore.
+type Option func(*options)
+
+type options struct {
+	copy      CopyStrategy
+	indexes   []any
+	shards    int
+	codec     Codec
+	compactAt int64
+	limits    limits
+	onEvict   any
+}
+
+func newOptions(opts []Option) options {
+	o := options{shards: 1, codec: JSONCodec, compactAt: 64 << 20}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithCopy sets how entities are copied in and out of the store. The default
+// is DeepCopy.
+func WithCopy(strategy CopyStrategy) Option {
+	return func(o *options) {
+		o.copy = strategy
+	}
+}
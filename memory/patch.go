@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	store "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/internal/fields"
+)
+
+// Patch applies the field operations to a copy of the stored entity, which
+// only replaces it when every operation succeeds. The result is copied again,
+// so the values set by the patch are not shared with the caller.
+func (m *MemStore[K, T]) Patch(ctx context.Context, id K, patch store.Patch) error {
+	ops, err := patch.Ops()
+	if err == nil {
+		err = store.CheckOps(ops)
+	}
+	if err != nil {
+		return store.NewError(backend, "Patch", id, fmt.Errorf("%w: %w", store.ErrInvalidPatch, err))
+	}
+
+	view, release, err := m.viewKey(ctx, id, true)
+	if err != nil {
+		return store.NewError(backend, "Patch", id, err)
+	}
+	defer release()
+
+	entity, version, ok := view.get(id)
+	if !ok {
+		return store.NewError(backend, "Patch", id, store.ErrNotFound)
+	}
+
+	v := reflect.ValueOf(&entity).Elem()
+	for _, op := range ops {
+		if err := applyOp(v, op); err != nil {
+			return store.NewError(backend, "Patch", id, fmt.Errorf("%w: %w", store.ErrInvalidPatch, err))
+		}
+	}
+
+	if err := m.checkUnique(view, id, entity); err != nil {
+		return store.NewError(backend, "Patch", id, err)
+	}
+
+	view.put(id, m.copy(entity), version+1)
+	if err := m.flush(view); err != nil {
+		return store.NewError(backend, "Patch", id, err)
+	}
+	return nil
+}
+
+func applyOp(v reflect.Value, op store.FieldOp) error {
+	switch op.Op {
+	case store.PatchSet:
+		return fields.Set(v, op.Field, op.Value)
+	case store.PatchUnset:
+		return fields.Unset(v, op.Field)
+	case store.PatchInc:
+		return fields.Inc(v, op.Field, op.Value)
+	case store.PatchEnsureObject:
+		return fields.EnsureObject(v, op.Field)
+	}
+	return fmt.Errorf("unsupported patch operation %q", op.Op)
+}
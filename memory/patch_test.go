@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Car struct {
+	Model   string            `json:"model"`
+	Price   int               `json:"price"`
+	Color   string            `json:"color,omitempty"`
+	Mileage int               `json:"mileage"`
+	Extras  map[string]string `json:"extras,omitempty"`
+}
+
+var _ gostore.Patcher[string] = (*MemStore[string, Car])(nil)
+
+func TestPatch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Field patch", func(t *testing.T) {
+		store := NewMemStore[Car]()
+		store.Insert(ctx, "1", &Car{Model: "Corolla", Price: 90, Color: "red", Mileage: 1000})
+
+		err := store.Patch(ctx, "1", gostore.Fields(gostore.Set("price", 100), gostore.Unset("color"), gostore.Inc("mileage", 10)))
+		require.NoError(t, err)
+
+		car, version, err := store.GetWithVersion(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, &Car{Model: "Corolla", Price: 100, Mileage: 1010}, car)
+		assert.Equal(t, int64(2), version)
+	})
+
+	t.Run("Merge patch", func(t *testing.T) {
+		store := NewMemStore[Car]()
+		store.Insert(ctx, "1", &Car{Model: "Corolla", Price: 90, Color: "red", Extras: map[string]string{"gps": "yes", "roof": "yes"}})
+
+		err := store.Patch(ctx, "1", gostore.MergePatch(`{"price": 100, "color": null, "extras": {"roof": null, "seats": "leather"}}`))
+		require.NoError(t, err)
+
+		car, err := store.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, &Car{Model: "Corolla", Price: 100, Extras: map[string]string{"gps": "yes", "seats": "leather"}}, car)
+	})
+
+	t.Run("Merge patch with an empty object", func(t *testing.T) {
+		store := NewMemStore[Car]()
+		store.Insert(ctx, "1", &Car{Model: "Corolla"})
+
+		err := store.Patch(ctx, "1", gostore.MergePatch(`{"extras": {}}`))
+		require.NoError(t, err)
+
+		car, err := store.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, &Car{Model: "Corolla", Extras: map[string]string{}}, car)
+	})
+
+	t.Run("Conflicting operations", func(t *testing.T) {
+		store := NewMemStore[Car]()
+		store.Insert(ctx, "1", &Car{Model: "Corolla", Price: 90})
+
+		err := store.Patch(ctx, "1", gostore.Fields(gostore.Set("price", 100), gostore.Unset("price")))
+		assert.ErrorIs(t, err, gostore.ErrInvalidPatch)
+		err = store.Patch(ctx, "1", gostore.Fields(gostore.Unset("extras"), gostore.Set("extras.gps", "yes")))
+		assert.ErrorIs(t, err, gostore.ErrInvalidPatch)
+
+		car, _ := store.GetByID(ctx, "1")
+		assert.Equal(t, &Car{Model: "Corolla", Price: 90}, car)
+	})
+
+	t.Run("Failed patch keeps the entity", func(t *testing.T) {
+		extras := map[string]string{"gps": "yes"}
+		store := NewMemStore[Car]()
+		store.Insert(ctx, "1", &Car{Model: "Corolla", Price: 90, Extras: extras})
+
+		err := store.Patch(ctx, "1", gostore.Fields(gostore.Set("extras.roof", "yes"), gostore.Inc("model", 1)))
+		assert.ErrorIs(t, err, gostore.ErrInvalidPatch)
+
+		car, _, _ := store.GetWithVersion(ctx, "1")
+		assert.Equal(t, &Car{Model: "Corolla", Price: 90, Extras: map[string]string{"gps": "yes"}}, car)
+		assert.Equal(t, map[string]string{"gps": "yes"}, extras)
+	})
+
+	t.Run("Invalid merge patch", func(t *testing.T) {
+		store := NewMemStore[Car]()
+		store.Insert(ctx, "1", &Car{})
+
+		err := store.Patch(ctx, "1", gostore.MergePatch(`"price"`))
+		assert.ErrorIs(t, err, gostore.ErrInvalidPatch)
+	})
+
+	t.Run("Patch non-existent entity", func(t *testing.T) {
+		store := NewMemStore[Car]()
+
+		err := store.Patch(ctx, "1", gostore.Fields(gostore.Set("price", 100)))
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+	})
+}
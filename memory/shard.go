@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// shard holds a part of the entities of a MemStore, locked on its own so
+// writes to different shards do not wait for each other.
+type shard[K comparable, T any] struct {
+	mu       sync.RWMutex
+	data     map[K]T
+	versions map[K]int64
+	// expires has the deadlines of the entities with a TTL, and access the
+	// usage of every entity of a store with WithMaxEntries or WithMaxBytes.
+	// Both are nil until needed.
+	expires map[K]time.Time
+	access  map[K]*usage
+}
+
+func newShards[K comparable, T any](n int) []*shard[K, T] {
+	if n < 1 {
+		panic(fmt.Sprintf("memory: invalid number of shards %d", n))
+	}
+	shards := make([]*shard[K, T], n)
+	for i := range shards {
+		shards[i] = &shard[K, T]{data: make(map[K]T), versions: make(map[K]int64)}
+	}
+	return shards
+}
+
+// WithShards partitions the entities in n independently locked maps, picked
+// by a hash of the id. Operations on a single id only lock its shard, while
+// the ones reading or writing the whole store (GetAll, Find, List, bulk
+// writes, ExecuteQuery, ExecuteUpdate and commits) lock every shard, so they
+// still see and leave a consistent state.
+//
+// Writes to a store with indexes, bounds on its size, or to a durable one,
+// always lock the whole store, because the indexes, the bounds and the log
+// are shared by every shard.
+func WithShards(n int) Option {
+	return func(o *options) {
+		o.shards = n
+	}
+}
+
+func (m *MemStore[K, T]) shard(id K) *shard[K, T] {
+	if len(m.shards) == 1 {
+		return m.shards[0]
+	}
+	return m.shards[hashKey(m.seed, id)%uint64(len(m.shards))]
+}
+
+// lockAll locks every shard, always in the same order.
+func (m *MemStore[K, T]) lockAll(write bool) (release func()) {
+	for _, s := range m.shards {
+		if write {
+			s.mu.Lock()
+		} else {
+			s.mu.RLock()
+		}
+	}
+	return func() {
+		for _, s := range m.shards {
+			if write {
+				s.mu.Unlock()
+			} else {
+				s.mu.RUnlock()
+			}
+		}
+		if write {
+			m.notify()
+		}
+	}
+}
+
+// lockKey only locks the shard of id, except for writes to stores with
+// indexes, bounds or a log.
+func (m *MemStore[K, T]) lockKey(id K, write bool) (release func()) {
+	return m.lockShard(m.shard(id), write)
+}
+
+func (m *MemStore[K, T]) lockShard(s *shard[K, T], write bool) (release func()) {
+	if write && (len(m.indexes) > 0 || m.wal != nil || m.limits.evicting()) {
+		return m.lockAll(true)
+	}
+
+	if write {
+		s.mu.Lock()
+		if m.onEvict == nil {
+			return s.mu.Unlock
+		}
+		return func() {
+			s.mu.Unlock()
+			m.notify()
+		}
+	}
+	s.mu.RLock()
+	return s.mu.RUnlock
+}
+
+// hashKey hashes the ids of the common key kinds directly, and the others,
+// like composite keys, field by field and element by element. Keys equal
+// under == get equal hashes: 0 and -0 hash the same, every NaN too, and
+// pointers hash their address.
+func hashKey[K comparable](seed maphash.Seed, id K) uint64 {
+	switch k := any(id).(type) {
+	case string:
+		return maphash.String(seed, k)
+	case int:
+		return mix(uint64(k))
+	case int64:
+		return mix(uint64(k))
+	}
+	return hashValue(seed, reflect.ValueOf(id))
+}
+
+func hashValue(seed maphash.Seed, v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.String:
+		return maphash.String(seed, v.String())
+	case reflect.Bool:
+		if v.Bool() {
+			return mix(1)
+		}
+		return mix(0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return mix(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return mix(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return hashFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return mix(hashFloat(real(c)) ^ hashFloat(imag(c)))
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		return mix(uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return hashValue(seed, v.Elem())
+	case reflect.Array:
+		var h uint64
+		for i := 0; i < v.Len(); i++ {
+			h = mix(h ^ hashValue(seed, v.Index(i)))
+		}
+		return h
+	case reflect.Struct:
+		var h uint64
+		for i := 0; i < v.NumField(); i++ {
+			h = mix(h ^ hashValue(seed, v.Field(i)))
+		}
+		return h
+	}
+	// Comparable values have no other kinds
+	return 0
+}
+
+func hashFloat(f float64) uint64 {
+	switch {
+	case f == 0:
+		f = 0 // -0 is the same key
+	case math.IsNaN(f):
+		f = math.NaN()
+	}
+	return mix(math.Float64bits(f))
+}
+
+// mix is the finalizer of SplitMix64, which spreads consecutive ids across
+// the shards.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,280 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newShardedStore(t testing.TB, n int) *MemStore[int, Counter] {
+	t.Helper()
+	store := NewKeyedMemStore[int, Counter](WithShards(8))
+	for i := 0; i < n; i++ {
+		_, err := store.Insert(context.Background(), i, &Counter{Count: i})
+		require.NoError(t, err)
+	}
+	return store
+}
+
+func TestWithShards(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Ids are spread across the shards", func(t *testing.T) {
+		store := newShardedStore(t, 100)
+		for _, s := range store.shards {
+			assert.NotEmpty(t, s.data)
+		}
+	})
+
+	t.Run("Single ids", func(t *testing.T) {
+		store := newShardedStore(t, 100)
+		require.NoError(t, store.Update(ctx, 42, &Counter{Count: -1}))
+		require.NoError(t, store.Delete(ctx, 43))
+
+		c, err := store.GetByID(ctx, 42)
+		require.NoError(t, err)
+		assert.Equal(t, -1, c.Count)
+		_, err = store.GetByID(ctx, 43)
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+
+		exists, err := store.Exists(ctx, 41)
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("Whole store reads", func(t *testing.T) {
+		store := newShardedStore(t, 100)
+
+		all, err := store.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 100)
+
+		n, err := store.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), n)
+
+		found, err := store.Find(ctx, query.Lt("count", 10))
+		require.NoError(t, err)
+		assert.Len(t, found, 10)
+
+		page, err := store.List(ctx, nil, gostore.FindOptions{Limit: 3, Sort: []gostore.SortField{gostore.Desc("count")}})
+		require.NoError(t, err)
+		assert.Equal(t, []*Counter{{Count: 99}, {Count: 98}, {Count: 97}}, page.Items)
+
+		found, err = store.ExecuteQuery(ctx, func(ctx context.Context, data map[int]Counter) ([]*Counter, error) {
+			assert.Len(t, data, 100)
+			c := data[7]
+			return []*Counter{&c}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 7, found[0].Count)
+	})
+
+	t.Run("ExecuteUpdate", func(t *testing.T) {
+		store := newShardedStore(t, 100)
+
+		n, err := store.ExecuteUpdate(ctx, func(ctx context.Context, data map[int]Counter) (int, error) {
+			for id := range data {
+				if id%2 == 0 {
+					delete(data, id)
+				}
+			}
+			data[1] = Counter{Count: 1000}
+			data[100] = Counter{Count: 100}
+			return 52, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 52, n)
+
+		count, err := store.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(51), count)
+
+		c, version, err := store.GetWithVersion(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 1000, c.Count)
+		assert.Equal(t, int64(2), version)
+
+		_, version, err = store.GetWithVersion(ctx, 3)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), version)
+
+		_, err = store.GetByID(ctx, 100)
+		require.NoError(t, err)
+	})
+
+	t.Run("Transactions", func(t *testing.T) {
+		store := newShardedStore(t, 100)
+
+		err := store.WithTx(ctx, func(ctx context.Context) error {
+			for i := 0; i < 100; i++ {
+				if err := store.Update(ctx, i, &Counter{Count: 0}); err != nil {
+					return err
+				}
+			}
+			found, err := store.Find(ctx, query.Eq("count", 0))
+			require.NoError(t, err)
+			assert.Len(t, found, 100)
+			return nil
+		})
+		require.NoError(t, err)
+
+		n, err := store.CountWhere(ctx, query.Eq("count", 0))
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), n)
+	})
+
+	t.Run("Indexes", func(t *testing.T) {
+		store := NewKeyedMemStore[int, Counter](
+			WithShards(4),
+			WithUniqueIndex("count", func(c Counter) int { return c.Count }),
+		)
+		for i := 0; i < 20; i++ {
+			_, err := store.Insert(ctx, i, &Counter{Count: i})
+			require.NoError(t, err)
+		}
+
+		_, err := store.Insert(ctx, 20, &Counter{Count: 3})
+		assert.ErrorIs(t, err, gostore.ErrUniqueViolation)
+
+		found, err := store.FindByIndex(ctx, "count", 3)
+		require.NoError(t, err)
+		assert.Equal(t, []*Counter{{Count: 3}}, found)
+	})
+
+	t.Run("Invalid number of shards", func(t *testing.T) {
+		assert.Panics(t, func() { NewMemStore[Counter](WithShards(0)) })
+	})
+}
+
+// TestWithShards_Race mixes single id writes with whole store reads and
+// updates. Run with -race.
+func TestWithShards_Race(t *testing.T) {
+	ctx := context.Background()
+	store := newShardedStore(t, 64)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				id := rand.Intn(64)
+				switch i % 5 {
+				case 0:
+					store.GetAll(ctx)
+				case 1:
+					store.ExecuteUpdate(ctx, func(ctx context.Context, data map[int]Counter) (int, error) {
+						c := data[id]
+						c.Count++
+						data[id] = c
+						return 1, nil
+					})
+				case 2:
+					store.Upsert(ctx, id, &Counter{Count: i})
+				default:
+					store.GetByID(ctx, id)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	n, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(64), n)
+}
+
+func TestHashKey(t *testing.T) {
+	type named string
+	type composite struct {
+		Order string
+		Line  int
+		Ref   *int
+	}
+	seed := newShardedStore(t, 0).seed
+	ref := 1
+
+	assert.Equal(t, hashKey(seed, "a"), hashKey(seed, "a"))
+	assert.Equal(t, hashKey(seed, named("a")), hashKey(seed, named("a")))
+	assert.Equal(t, hashKey(seed, 0.0), hashKey(seed, math.Copysign(0, -1)))
+	assert.Equal(t, hashKey(seed, composite{"A", 1, &ref}), hashKey(seed, composite{"A", 1, &ref}))
+	assert.NotEqual(t, hashKey(seed, composite{"A", 1, &ref}), hashKey(seed, composite{"A", 2, &ref}))
+	assert.NotEqual(t, hashKey(seed, composite{"A", 1, &ref}), hashKey(seed, composite{"A", 1, new(int)}))
+	assert.NotEqual(t, hashKey(seed, 1), hashKey(seed, 2))
+
+	// Composite keys equal under == hash the same
+	type point struct {
+		X, Y float64
+		Tag  any
+	}
+	assert.Equal(t, hashKey(seed, point{0, 1, "a"}), hashKey(seed, point{math.Copysign(0, -1), 1, "a"}))
+	assert.Equal(t, hashKey(seed, point{X: math.NaN()}), hashKey(seed, point{X: -math.NaN()}))
+	assert.NotEqual(t, hashKey(seed, point{0, 1, nil}), hashKey(seed, point{1, 0, nil}))
+	assert.Equal(t, hashKey(seed, [2]float32{1, 0}), hashKey(seed, [2]float32{1, float32(math.Copysign(0, -1))}))
+}
+
+func benchmarkStores(b *testing.B, fn func(b *testing.B, store *MemStore[string, Counter], ids []string)) {
+	ids := make([]string, 10000)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i)
+	}
+
+	for _, shards := range []int{1, 4, 16, 64} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			store := NewMemStore[Counter](WithShards(shards), WithCopy(ShallowCopy))
+			for _, id := range ids {
+				store.Insert(context.Background(), id, &Counter{})
+			}
+			b.ResetTimer()
+			fn(b, store, ids)
+		})
+	}
+}
+
+func BenchmarkMemStore_Update(b *testing.B) {
+	ctx := context.Background()
+	benchmarkStores(b, func(b *testing.B, store *MemStore[string, Counter], ids []string) {
+		b.RunParallel(func(pb *testing.PB) {
+			r := rand.New(rand.NewSource(rand.Int63()))
+			for pb.Next() {
+				store.Update(ctx, ids[r.Intn(len(ids))], &Counter{Count: 1})
+			}
+		})
+	})
+}
+
+func BenchmarkMemStore_Mixed(b *testing.B) {
+	ctx := context.Background()
+	benchmarkStores(b, func(b *testing.B, store *MemStore[string, Counter], ids []string) {
+		b.RunParallel(func(pb *testing.PB) {
+			r := rand.New(rand.NewSource(rand.Int63()))
+			for i := 0; pb.Next(); i++ {
+				id := ids[r.Intn(len(ids))]
+				if i%4 == 0 {
+					store.Update(ctx, id, &Counter{Count: i})
+				} else {
+					store.GetByID(ctx, id)
+				}
+			}
+		})
+	})
+}
+
+func BenchmarkMemStore_GetAll(b *testing.B) {
+	ctx := context.Background()
+	benchmarkStores(b, func(b *testing.B, store *MemStore[string, Counter], ids []string) {
+		for i := 0; i < b.N; i++ {
+			store.GetAll(ctx)
+		}
+	})
+}
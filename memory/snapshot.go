@@ -0,0 +1,248 @@
+package memory
+
+import (
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	store "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/internal/fsync"
+)
+
+// Codec encodes the records of a snapshot.
+type Codec interface {
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+type Encoder interface {
+	Encode(v any) error
+}
+
+type Decoder interface {
+	Decode(v any) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) NewEncoder(w io.Writer) Encoder { return json.NewEncoder(w) }
+func (jsonCodec) NewDecoder(r io.Reader) Decoder { return json.NewDecoder(r) }
+
+type gobCodec struct{}
+
+func (gobCodec) NewEncoder(w io.Writer) Encoder { return gob.NewEncoder(w) }
+func (gobCodec) NewDecoder(r io.Reader) Decoder { return gob.NewDecoder(r) }
+
+var (
+	// JSONCodec writes one JSON document per record. It is the default.
+	JSONCodec Codec = jsonCodec{}
+	// GobCodec is smaller and faster, but entities with interface fields need
+	// their concrete types registered with gob.Register.
+	GobCodec Codec = gobCodec{}
+)
+
+// WithCodec sets the codec of Snapshot and Restore.
+func WithCodec(codec Codec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+const snapshotFormat = "go-store/memory"
+
+type snapshotHeader struct {
+	Format  string
+	Version int
+	Count   int
+}
+
+type snapshotEntry[K comparable, T any] struct {
+	ID      K
+	Version int64
+	Entity  T
+}
+
+// Snapshot writes every entity, with its id and version, to w. The store is
+// locked for reading while it is written, so w should not be slow.
+func (m *MemStore[K, T]) Snapshot(w io.Writer) error {
+	release := m.lockAll(false)
+	defer release()
+
+	return m.encodeSnapshot(w)
+}
+
+func (m *MemStore[K, T]) encodeSnapshot(w io.Writer) error {
+	now := m.now()
+	enc := m.codec.NewEncoder(w)
+	if err := enc.Encode(snapshotHeader{Format: snapshotFormat, Version: 1, Count: m.countAt(now)}); err != nil {
+		return store.NewError(backend, "Snapshot", nil, err)
+	}
+
+	for _, s := range m.shards {
+		for id, value := range s.data {
+			if s.expired(id, now) {
+				continue
+			}
+			if err := enc.Encode(snapshotEntry[K, T]{ID: id, Version: s.versions[id], Entity: value}); err != nil {
+				return store.NewError(backend, "Snapshot", id, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Restore replaces every entity with the ones of a snapshot. The snapshot
+// is read completely before touching the store, which does not change when
+// it is invalid.
+func (m *MemStore[K, T]) Restore(r io.Reader) error {
+	dec := m.codec.NewDecoder(r)
+
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return store.NewError(backend, "Restore", nil, fmt.Errorf("reading header: %w", err))
+	}
+	if header.Format != snapshotFormat || header.Version != 1 {
+		return store.NewError(backend, "Restore", nil, fmt.Errorf("unsupported snapshot %q version %d", header.Format, header.Version))
+	}
+
+	entries := make([]snapshotEntry[K, T], 0, header.Count)
+	data := make(map[K]T, header.Count)
+	for {
+		var entry snapshotEntry[K, T]
+		err := dec.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return store.NewError(backend, "Restore", nil, err)
+		}
+		entries = append(entries, entry)
+		data[entry.ID] = entry.Entity
+	}
+	if len(entries) != header.Count || len(data) != header.Count {
+		return store.NewError(backend, "Restore", nil, fmt.Errorf("snapshot has %d entities, expected %d", len(data), header.Count))
+	}
+	if err := m.checkUniqueAll(data); err != nil {
+		return store.NewError(backend, "Restore", nil, err)
+	}
+
+	release := m.lockAll(true)
+	defer release()
+
+	for _, s := range m.shards {
+		clear(s.data)
+		clear(s.versions)
+		clear(s.expires)
+		clear(s.access)
+	}
+	m.bytes = 0
+	for _, idx := range m.indexes {
+		clear(idx.entries)
+	}
+	for _, entry := range entries {
+		m.set(entry.ID, entry.Entity, entry.Version)
+	}
+	m.evict(nil)
+	m.changes.Add(1)
+
+	// The log of a durable store only makes sense on top of the old entities.
+	// The new snapshot already has the evictions, which are not logged
+	if m.wal != nil {
+		m.pending = nil
+		if m.wal.err != nil {
+			return store.NewError(backend, "Restore", nil, m.wal.err)
+		}
+		return m.compact()
+	}
+	return nil
+}
+
+// SnapshotFile writes a snapshot to path atomically: it is written to a
+// temporary file in the same directory, which then replaces path.
+func (m *MemStore[K, T]) SnapshotFile(path string) error {
+	release := m.lockAll(false)
+	defer release()
+
+	return m.writeSnapshotFile(path)
+}
+
+func (m *MemStore[K, T]) writeSnapshotFile(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return store.NewError(backend, "Snapshot", nil, err)
+	}
+	defer os.Remove(f.Name())
+
+	if err := m.encodeSnapshot(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return store.NewError(backend, "Snapshot", nil, err)
+	}
+	if err := f.Close(); err != nil {
+		return store.NewError(backend, "Snapshot", nil, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return store.NewError(backend, "Snapshot", nil, err)
+	}
+	// Without it the rename may not survive a crash
+	if err := fsync.Dir(filepath.Dir(path)); err != nil {
+		return store.NewError(backend, "Snapshot", nil, err)
+	}
+	return nil
+}
+
+// RestoreFile restores the snapshot in path. The error matches
+// fs.ErrNotExist when there is none yet.
+func (m *MemStore[K, T]) RestoreFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return store.NewError(backend, "Restore", nil, err)
+	}
+	defer f.Close()
+
+	return m.Restore(f)
+}
+
+// AutoSnapshot writes a snapshot to path every interval, skipping the
+// intervals in which the store did not change, until ctx is done. Then it
+// writes a last snapshot and returns. It stops at the first failed snapshot,
+// returning its error. It is meant to run in its own goroutine:
+//
+//	go store.AutoSnapshot(ctx, "cars.snapshot", time.Minute)
+func (m *MemStore[K, T]) AutoSnapshot(ctx context.Context, path string, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var saved uint64
+	written := false
+	save := func() error {
+		changes := m.changes.Load()
+		if written && changes == saved {
+			return nil
+		}
+		if err := m.SnapshotFile(path); err != nil {
+			return err
+		}
+		saved, written = changes, true
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return save()
+		case <-ticker.C:
+			if err := save(); err != nil {
+				return err
+			}
+		}
+	}
+}
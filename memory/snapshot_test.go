@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	for name, codec := range map[string]Codec{"JSON": JSONCodec, "Gob": GobCodec} {
+		t.Run(name, func(t *testing.T) {
+			source := NewKeyedMemStore[int, Car](WithCodec(codec), WithShards(4))
+			for i := 1; i <= 10; i++ {
+				_, err := source.Insert(ctx, i, &Car{Model: "Corolla", Price: i, Extras: map[string]string{"n": "1"}})
+				require.NoError(t, err)
+			}
+			require.NoError(t, source.Update(ctx, 3, &Car{Model: "Yaris"}))
+
+			var buf bytes.Buffer
+			require.NoError(t, source.Snapshot(&buf))
+
+			target := NewKeyedMemStore[int, Car](
+				WithCodec(codec),
+				WithIndex("model", func(c Car) string { return c.Model }),
+			)
+			target.Insert(ctx, 99, &Car{Model: "Gone"})
+			require.NoError(t, target.Restore(&buf))
+
+			n, err := target.Count(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, int64(10), n)
+
+			car, version, err := target.GetWithVersion(ctx, 3)
+			require.NoError(t, err)
+			assert.Equal(t, &Car{Model: "Yaris"}, car)
+			assert.Equal(t, int64(2), version)
+
+			car, err = target.GetByID(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, &Car{Model: "Corolla", Price: 1, Extras: map[string]string{"n": "1"}}, car)
+
+			found, err := target.FindByIndex(ctx, "model", "Corolla")
+			require.NoError(t, err)
+			assert.Len(t, found, 9)
+			found, err = target.FindByIndex(ctx, "model", "Gone")
+			require.NoError(t, err)
+			assert.Empty(t, found)
+		})
+	}
+
+	t.Run("Invalid snapshots change nothing", func(t *testing.T) {
+		source := NewMemStore[Car]()
+		source.Insert(ctx, "1", &Car{Model: "Corolla"})
+		source.Insert(ctx, "2", &Car{Model: "Corolla"})
+		var buf bytes.Buffer
+		require.NoError(t, source.Snapshot(&buf))
+		lines := strings.SplitAfter(buf.String(), "\n")
+
+		target := NewMemStore[Car](WithUniqueIndex("model", func(c Car) string { return c.Model }))
+		target.Insert(ctx, "3", &Car{Model: "Yaris"})
+
+		for name, snapshot := range map[string]string{
+			"Empty":         "",
+			"Unknown":       `{"Format":"other","Version":1}`,
+			"Truncated":     lines[0] + lines[1],
+			"Corrupted":     lines[0] + lines[1] + "{",
+			"Unique values": buf.String(),
+		} {
+			err := target.Restore(strings.NewReader(snapshot))
+			assert.Error(t, err, name)
+		}
+
+		all, err := target.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*Car{{Model: "Yaris"}}, all)
+	})
+}
+
+func TestSnapshotFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cars.snapshot")
+
+	store := NewMemStore[Car]()
+	err := store.RestoreFile(path)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	store.Insert(ctx, "1", &Car{Model: "Corolla"})
+	require.NoError(t, store.SnapshotFile(path))
+	store.Insert(ctx, "2", &Car{Model: "Yaris"})
+	require.NoError(t, store.SnapshotFile(path))
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are removed")
+
+	restored := NewMemStore[Car]()
+	require.NoError(t, restored.RestoreFile(path))
+	n, err := restored.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+func TestAutoSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cars.snapshot")
+	store := NewMemStore[Car]()
+	store.Insert(context.Background(), "1", &Car{Model: "Corolla"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- store.AutoSnapshot(ctx, path, 10*time.Millisecond) }()
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	// Nothing changed, so the file stays the same
+	time.Sleep(50 * time.Millisecond)
+	unchanged, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, info.ModTime(), unchanged.ModTime())
+
+	// The last snapshot is written when ctx is done
+	store.Insert(context.Background(), "2", &Car{Model: "Yaris"})
+	cancel()
+	require.NoError(t, <-done)
+
+	restored := NewMemStore[Car]()
+	require.NoError(t, restored.RestoreFile(path))
+	_, err = restored.GetByID(context.Background(), "2")
+	assert.NoError(t, err)
+}
+
+func TestAutoSnapshot_Error(t *testing.T) {
+	store := NewMemStore[Car]()
+	err := store.AutoSnapshot(context.Background(), filepath.Join(t.TempDir(), "missing", "cars.snapshot"), time.Millisecond)
+	assert.Error(t, err)
+
+	var storeErr *gostore.Error
+	require.ErrorAs(t, err, &storeErr)
+	assert.Equal(t, "Snapshot", storeErr.Op)
+}
@@ -0,0 +1,453 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	store "github.com/Silencevoice/go-store"
+)
+
+// ErrTxDone is returned when a store is used with the context of a
+// transaction that already finished.
+var ErrTxDone = errors.New("transaction already finished")
+
+// storeIDs numbers the stores, so transactions lock them in a fixed order.
+var storeIDs atomic.Uint64
+
+type txKey struct{}
+
+// tx stages the writes of every MemStore used with its context in one
+// overlay per store. Nothing reaches the stores until commit.
+type tx struct {
+	sync.Mutex
+	overlays map[uint64]txOverlay
+	done     bool
+}
+
+type txOverlay interface {
+	storeID() uint64
+	lock()
+	unlock()
+	validate() error
+	apply() error
+}
+
+// WithTx runs fn in a transaction spanning every MemStore called with the
+// context it receives. The writes are staged and only applied when fn
+// returns nil; an error (or a panic) discards them all.
+//
+// Reads inside the transaction see its own writes. On commit every store is
+// locked, and the transaction fails with store.ErrVersionConflict, applying
+// nothing, when an entity it read or wrote was changed by someone else since
+// the transaction first read it, ids it did not find included. Calls to
+// WithTx inside fn join the outer transaction.
+//
+// Durable stores write the changes of the transaction as a single log
+// record. When that fails the changes to that store are rolled back, but
+// the other stores of the transaction may already have applied theirs.
+func WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*tx); ok {
+		return fn(ctx)
+	}
+
+	t := &tx{overlays: map[uint64]txOverlay{}}
+	defer func() {
+		t.Lock()
+		t.done = true
+		t.Unlock()
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, t)); err != nil {
+		return err
+	}
+	return t.commit()
+}
+
+func (m *MemStore[K, T]) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return WithTx(ctx, fn)
+}
+
+func (t *tx) commit() error {
+	t.Lock()
+	defer t.Unlock()
+	t.done = true
+
+	overlays := make([]txOverlay, 0, len(t.overlays))
+	for _, o := range t.overlays {
+		overlays = append(overlays, o)
+	}
+	sort.Slice(overlays, func(i, j int) bool { return overlays[i].storeID() < overlays[j].storeID() })
+
+	for _, o := range overlays {
+		o.lock()
+		defer o.unlock()
+	}
+	for _, o := range overlays {
+		if err := o.validate(); err != nil {
+			return err
+		}
+	}
+	for _, o := range overlays {
+		if err := o.apply(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// view is the state an operation works on: the store itself, or the
+// overlay of a transaction on top of it.
+type view[K comparable, T any] interface {
+	get(id K) (T, int64, bool)
+	put(id K, value T, version int64)
+	remove(id K)
+	each(fn func(id K, value T) bool)
+	count() int
+	// byIndex calls fn for the entities with key in idx.
+	byIndex(idx *index[K, T], key any, fn func(id K, value T) bool)
+}
+
+// view returns the state for ctx, with the whole store locked for reading or
+// writing until release is called.
+func (m *MemStore[K, T]) view(ctx context.Context, write bool) (v view[K, T], release func(), err error) {
+	return m.open(ctx, write, m.lockAll)
+}
+
+// viewKey is view for the operations on a single id, which only lock its
+// shard.
+func (m *MemStore[K, T]) viewKey(ctx context.Context, id K, write bool) (v view[K, T], release func(), err error) {
+	return m.open(ctx, write, func(write bool) func() { return m.lockKey(id, write) })
+}
+
+func (m *MemStore[K, T]) open(ctx context.Context, write bool, lock func(write bool) func()) (view[K, T], func(), error) {
+	t, ok := ctx.Value(txKey{}).(*tx)
+	if !ok {
+		return m, lock(write), nil
+	}
+
+	t.Lock()
+	if t.done {
+		t.Unlock()
+		return nil, nil, ErrTxDone
+	}
+	o, ok := t.overlays[m.id].(*overlay[K, T])
+	if !ok {
+		o = &overlay[K, T]{store: m, writes: map[K]staged[T]{}, read: map[K]int64{}}
+		t.overlays[m.id] = o
+	}
+
+	// Writes only go to the overlay, so the store is only read
+	release := lock(false)
+	return o, func() {
+		release()
+		t.Unlock()
+	}, nil
+}
+
+// get does not find the expired entities, which are removed later by a
+// write.
+func (m *MemStore[K, T]) get(id K) (T, int64, bool) {
+	s := m.shard(id)
+	value, ok := s.data[id]
+	if !ok || (len(s.expires) > 0 && s.expired(id, m.now())) {
+		var zero T
+		return zero, 0, false
+	}
+	m.touch(s, id)
+	return value, s.versions[id], true
+}
+
+// put also keeps the bounds of the store, removing some expired entities of
+// the shard of id and evicting others when the store is full.
+func (m *MemStore[K, T]) put(id K, value T, version int64) {
+	m.record(id, walChange[K, T]{ID: id, Version: version, Entity: value})
+	m.set(id, value, version)
+
+	if s := m.shard(id); len(s.expires) > 0 {
+		m.dropExpired(s, m.now(), lazyExpirySamples)
+	}
+	m.evict(&id)
+}
+
+func (m *MemStore[K, T]) remove(id K) {
+	m.record(id, walChange[K, T]{ID: id, Deleted: true})
+	m.unset(id)
+}
+
+// record keeps the change for the log of durable stores.
+func (m *MemStore[K, T]) record(id K, change walChange[K, T]) {
+	if m.wal == nil {
+		return
+	}
+	// The shard is read directly, so expired entities are restored too and
+	// the read does not count as a use
+	s := m.shard(id)
+	prev, existed := s.data[id]
+	m.pending = append(m.pending, pendingChange[K, T]{
+		walChange:    change,
+		existed:      existed,
+		prev:         prev,
+		prevVersion:  s.versions[id],
+		prevDeadline: s.expires[id],
+	})
+}
+
+func (m *MemStore[K, T]) set(id K, value T, version int64) {
+	s := m.shard(id)
+	if old, ok := s.data[id]; ok {
+		for _, idx := range m.indexes {
+			idx.remove(id, old)
+		}
+		if len(s.expires) > 0 && s.expired(id, m.now()) {
+			m.evicted(id, old, Expired)
+		}
+	}
+	s.data[id] = value
+	s.versions[id] = version
+	m.changes.Add(1)
+	for _, idx := range m.indexes {
+		idx.add(id, value)
+	}
+	m.setDeadline(s, id, m.limits.ttl)
+	m.track(s, id, value)
+}
+
+func (m *MemStore[K, T]) unset(id K) {
+	s := m.shard(id)
+	if old, ok := s.data[id]; ok {
+		for _, idx := range m.indexes {
+			idx.remove(id, old)
+		}
+	}
+	delete(s.data, id)
+	delete(s.versions, id)
+	delete(s.expires, id)
+	m.untrack(s, id)
+	m.changes.Add(1)
+}
+
+func (m *MemStore[K, T]) each(fn func(id K, value T) bool) {
+	now := m.now()
+	for _, s := range m.shards {
+		for id, value := range s.data {
+			if len(s.expires) > 0 && s.expired(id, now) {
+				continue
+			}
+			if !fn(id, value) {
+				return
+			}
+		}
+	}
+}
+
+func (m *MemStore[K, T]) count() int {
+	return m.countAt(m.now())
+}
+
+// countAt counts the entities not expired at now.
+func (m *MemStore[K, T]) countAt(now time.Time) int {
+	n := 0
+	for _, s := range m.shards {
+		n += len(s.data)
+		for _, deadline := range s.expires {
+			if !now.Before(deadline) {
+				n--
+			}
+		}
+	}
+	return n
+}
+
+func (m *MemStore[K, T]) byIndex(idx *index[K, T], key any, fn func(id K, value T) bool) {
+	for id := range idx.entries[key] {
+		value, _, ok := m.get(id)
+		if ok && !fn(id, value) {
+			return
+		}
+	}
+}
+
+type staged[T any] struct {
+	value   T
+	version int64
+	deleted bool
+}
+
+type overlay[K comparable, T any] struct {
+	store   *MemStore[K, T]
+	release func()
+	writes  map[K]staged[T]
+	// read keeps the version each entity read or written had in the store (0
+	// when missing) when the transaction first touched it.
+	read map[K]int64
+}
+
+func (o *overlay[K, T]) get(id K) (T, int64, bool) {
+	if w, ok := o.writes[id]; ok {
+		return w.value, w.version, !w.deleted
+	}
+	value, version, ok := o.store.get(id)
+	o.saw(id, version)
+	return value, version, ok
+}
+
+func (o *overlay[K, T]) touch(id K) {
+	if _, ok := o.read[id]; !ok {
+		_, version, _ := o.store.get(id)
+		o.read[id] = version
+	}
+}
+
+// saw keeps the version of an entity read from the store, unless the
+// transaction already had it.
+func (o *overlay[K, T]) saw(id K, version int64) {
+	if _, ok := o.read[id]; !ok {
+		o.read[id] = version
+	}
+}
+
+func (o *overlay[K, T]) put(id K, value T, version int64) {
+	o.touch(id)
+	o.writes[id] = staged[T]{value: value, version: version}
+}
+
+func (o *overlay[K, T]) remove(id K) {
+	o.touch(id)
+	o.writes[id] = staged[T]{deleted: true}
+}
+
+func (o *overlay[K, T]) each(fn func(id K, value T) bool) {
+	stop := false
+	o.store.each(func(id K, value T) bool {
+		if _, ok := o.writes[id]; ok {
+			return true
+		}
+		o.saw(id, o.store.shard(id).versions[id])
+		stop = !fn(id, value)
+		return !stop
+	})
+	if stop {
+		return
+	}
+	for id, w := range o.writes {
+		if !w.deleted && !fn(id, w.value) {
+			return
+		}
+	}
+}
+
+func (o *overlay[K, T]) count() int {
+	n := o.store.count()
+	for id, w := range o.writes {
+		_, _, stored := o.store.get(id)
+		switch {
+		case w.deleted && stored:
+			n--
+		case !w.deleted && !stored:
+			n++
+		}
+	}
+	return n
+}
+
+func (o *overlay[K, T]) byIndex(idx *index[K, T], key any, fn func(id K, value T) bool) {
+	for id := range idx.entries[key] {
+		if _, ok := o.writes[id]; ok {
+			continue
+		}
+		value, version, ok := o.store.get(id)
+		if !ok {
+			continue
+		}
+		o.saw(id, version)
+		if !fn(id, value) {
+			return
+		}
+	}
+	for id, w := range o.writes {
+		if !w.deleted && idx.key(w.value) == key && !fn(id, w.value) {
+			return
+		}
+	}
+}
+
+func (o *overlay[K, T]) storeID() uint64 {
+	return o.store.id
+}
+
+func (o *overlay[K, T]) lock() {
+	o.release = o.store.lockAll(true)
+}
+
+func (o *overlay[K, T]) unlock() {
+	o.release()
+}
+
+// validate runs with every store locked. Besides the versions, it checks the
+// unique indexes again, because other writes may have been committed since
+// the transaction staged its own.
+func (o *overlay[K, T]) validate() error {
+	for id, version := range o.read {
+		if _, current, _ := o.store.get(id); current != version {
+			return store.NewError(backend, "Commit", id, store.ErrVersionConflict)
+		}
+	}
+	for id, w := range o.writes {
+		if w.deleted {
+			continue
+		}
+		if err := o.store.checkUnique(o, id, w.value); err != nil {
+			return store.NewError(backend, "Commit", id, err)
+		}
+	}
+	return nil
+}
+
+// apply writes the staged changes into the store, and for durable stores
+// into the log, in a single record.
+func (o *overlay[K, T]) apply() error {
+	for id, w := range o.writes {
+		if w.deleted {
+			o.store.remove(id)
+		} else {
+			o.store.put(id, w.value, w.version)
+		}
+	}
+	if err := o.store.flush(o.store); err != nil {
+		return store.NewError(backend, "Commit", nil, err)
+	}
+	return nil
+}
+
+// snapshot copies the entities seen by v, for the functions that expect a
+// plain map.
+func snapshot[K comparable, T any](v view[K, T]) map[K]T {
+	data := map[K]T{}
+	v.each(func(id K, value T) bool {
+		data[id] = value
+		return true
+	})
+	return data
+}
+
+// stageChanges writes into v the differences between two snapshots.
+func stageChanges[K comparable, T any](v view[K, T], before, after map[K]T) {
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			v.remove(id)
+		}
+	}
+	for id, value := range after {
+		old, ok := before[id]
+		if ok && reflect.DeepEqual(old, value) {
+			continue
+		}
+		_, version, _ := v.get(id)
+		v.put(id, value, version+1)
+	}
+}
@@ -0,0 +1,232 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ gostore.Transactor = (*MemStore[string, TestEntity])(nil)
+
+type Counter struct {
+	Count int
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func() (*MemStore[string, TestEntity], *MemStore[string, Counter]) {
+		cars := NewMemStore[TestEntity]()
+		counters := NewMemStore[Counter]()
+		counters.Insert(ctx, "cars", &Counter{Count: 0})
+		return cars, counters
+	}
+
+	t.Run("Commit writes to every store", func(t *testing.T) {
+		cars, counters := setup()
+
+		err := WithTx(ctx, func(ctx context.Context) error {
+			if _, err := cars.Insert(ctx, "1", &TestEntity{ID: "1", Value: "car"}); err != nil {
+				return err
+			}
+			return counters.Patch(ctx, "cars", gostore.Fields(gostore.Inc("count", 1)))
+		})
+		require.NoError(t, err)
+
+		car, err := cars.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "car", car.Value)
+		counter, _ := counters.GetByID(ctx, "cars")
+		assert.Equal(t, 1, counter.Count)
+	})
+
+	t.Run("Error rolls back partial work", func(t *testing.T) {
+		cars, counters := setup()
+		cars.Insert(ctx, "1", &TestEntity{ID: "1", Value: "car"})
+
+		err := cars.WithTx(ctx, func(ctx context.Context) error {
+			if err := counters.Patch(ctx, "cars", gostore.Fields(gostore.Inc("count", 1))); err != nil {
+				return err
+			}
+			if err := cars.Delete(ctx, "1"); err != nil {
+				return err
+			}
+			_, err := cars.Insert(ctx, "1", &TestEntity{ID: "1", Value: "new"})
+			if err != nil {
+				return err
+			}
+			_, err = cars.Insert(ctx, "1", &TestEntity{ID: "1"})
+			return err
+		})
+		assert.ErrorIs(t, err, gostore.ErrAlreadyExists)
+
+		car, err := cars.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "car", car.Value)
+		counter, _ := counters.GetByID(ctx, "cars")
+		assert.Equal(t, 0, counter.Count)
+	})
+
+	t.Run("Panic rolls back", func(t *testing.T) {
+		cars, _ := setup()
+
+		assert.Panics(t, func() {
+			WithTx(ctx, func(ctx context.Context) error {
+				cars.Insert(ctx, "1", &TestEntity{ID: "1"})
+				panic("boom")
+			})
+		})
+
+		_, err := cars.GetByID(ctx, "1")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+	})
+
+	t.Run("Reads see the staged writes", func(t *testing.T) {
+		cars, _ := setup()
+		cars.Insert(ctx, "1", &TestEntity{ID: "1", Value: "value-1"})
+		cars.Insert(ctx, "2", &TestEntity{ID: "2", Value: "value-2"})
+
+		err := WithTx(ctx, func(txCtx context.Context) error {
+			cars.Delete(txCtx, "1")
+			cars.Insert(txCtx, "3", &TestEntity{ID: "3", Value: "value-3"})
+
+			found, err := cars.Find(txCtx, query.Prefix("value", "value"))
+			require.NoError(t, err)
+			assert.Len(t, found, 2)
+
+			page, err := cars.List(txCtx, nil, gostore.FindOptions{Sort: []gostore.SortField{gostore.Asc("value")}})
+			require.NoError(t, err)
+			require.Len(t, page.Items, 2)
+			assert.Equal(t, "value-2", page.Items[0].Value)
+			assert.Equal(t, "value-3", page.Items[1].Value)
+
+			// Not visible outside the transaction yet
+			_, err = cars.GetByID(ctx, "3")
+			assert.ErrorIs(t, err, gostore.ErrNotFound)
+			_, err = cars.GetByID(ctx, "1")
+			assert.NoError(t, err)
+			return nil
+		})
+		require.NoError(t, err)
+
+		all, _ := cars.GetAll(ctx)
+		assert.Len(t, all, 2)
+	})
+
+	t.Run("Conflicting writes abort the commit", func(t *testing.T) {
+		cars, counters := setup()
+		cars.Insert(ctx, "1", &TestEntity{ID: "1", Value: "car"})
+
+		err := WithTx(ctx, func(txCtx context.Context) error {
+			counters.Patch(txCtx, "cars", gostore.Fields(gostore.Inc("count", 1)))
+			cars.Update(txCtx, "1", &TestEntity{ID: "1", Value: "from tx"})
+
+			// Someone else writes the same car before the commit
+			return cars.Update(ctx, "1", &TestEntity{ID: "1", Value: "concurrent"})
+		})
+		assert.ErrorIs(t, err, gostore.ErrVersionConflict)
+
+		car, _ := cars.GetByID(ctx, "1")
+		assert.Equal(t, "concurrent", car.Value)
+		counter, _ := counters.GetByID(ctx, "cars")
+		assert.Equal(t, 0, counter.Count)
+	})
+
+	t.Run("Writes based on stale reads abort the commit", func(t *testing.T) {
+		cars, counters := setup()
+		cars.Insert(ctx, "1", &TestEntity{ID: "1", Value: "car"})
+
+		err := WithTx(ctx, func(txCtx context.Context) error {
+			car, err := cars.GetByID(txCtx, "1")
+			if err != nil {
+				return err
+			}
+
+			// Someone else commits the car between the read and the write
+			require.NoError(t, cars.Update(ctx, "1", &TestEntity{ID: "1", Value: "concurrent"}))
+
+			car.Value += " from tx"
+			return cars.Update(txCtx, "1", car)
+		})
+		assert.ErrorIs(t, err, gostore.ErrVersionConflict)
+		car, _ := cars.GetByID(ctx, "1")
+		assert.Equal(t, "concurrent", car.Value)
+
+		// The same for the entities read by a scan, and the ids not found
+		for _, concurrent := range []func() error{
+			func() error { return cars.Update(ctx, "1", &TestEntity{ID: "1", Value: "again"}) },
+			func() error { _, err := cars.Insert(ctx, "2", &TestEntity{ID: "2"}); return err },
+		} {
+			err = WithTx(ctx, func(txCtx context.Context) error {
+				if _, err := cars.GetAll(txCtx); err != nil {
+					return err
+				}
+				if _, err := cars.GetByID(txCtx, "2"); !errors.Is(err, gostore.ErrNotFound) {
+					return err
+				}
+				require.NoError(t, concurrent())
+				return counters.Patch(txCtx, "cars", gostore.Fields(gostore.Inc("count", 1)))
+			})
+			assert.ErrorIs(t, err, gostore.ErrVersionConflict)
+		}
+		counter, _ := counters.GetByID(ctx, "cars")
+		assert.Equal(t, 0, counter.Count)
+	})
+
+	t.Run("Nested transactions join the outer one", func(t *testing.T) {
+		cars, _ := setup()
+
+		err := WithTx(ctx, func(ctx context.Context) error {
+			cars.Insert(ctx, "1", &TestEntity{ID: "1"})
+			WithTx(ctx, func(ctx context.Context) error {
+				_, err := cars.Insert(ctx, "2", &TestEntity{ID: "2"})
+				return err
+			})
+			return errors.New("abort")
+		})
+		assert.Error(t, err)
+
+		all, _ := cars.GetAll(ctx)
+		assert.Empty(t, all)
+	})
+
+	t.Run("Finished transaction", func(t *testing.T) {
+		cars, _ := setup()
+
+		var leaked context.Context
+		WithTx(ctx, func(ctx context.Context) error {
+			leaked = ctx
+			return nil
+		})
+
+		_, err := cars.Insert(leaked, "1", &TestEntity{ID: "1"})
+		assert.ErrorIs(t, err, ErrTxDone)
+	})
+
+	t.Run("ExecuteUpdate stages the changed entities", func(t *testing.T) {
+		cars, _ := setup()
+		cars.Insert(ctx, "1", &TestEntity{ID: "1", Value: "value-1"})
+		cars.Insert(ctx, "2", &TestEntity{ID: "2", Value: "value-2"})
+
+		err := WithTx(ctx, func(ctx context.Context) error {
+			_, err := cars.ExecuteUpdate(ctx, func(ctx context.Context, data map[string]TestEntity) (int, error) {
+				data["1"] = TestEntity{ID: "1", Value: "updated"}
+				delete(data, "2")
+				return 2, nil
+			})
+			return err
+		})
+		require.NoError(t, err)
+
+		car, version, _ := cars.GetWithVersion(ctx, "1")
+		assert.Equal(t, "updated", car.Value)
+		assert.Equal(t, int64(2), version)
+		_, err = cars.GetByID(ctx, "2")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+	})
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+
+	store "github.com/Silencevoice/go-store"
+)
+
+func (m *MemStore[K, T]) GetWithVersion(ctx context.Context, id K) (*T, int64, error) {
+	v, release, err := m.viewKey(ctx, id, false)
+	if err != nil {
+		return nil, 0, store.NewError(backend, "GetWithVersion", id, err)
+	}
+	defer release()
+
+	ent, version, ok := v.get(id)
+	if !ok {
+		return nil, 0, store.NewError(backend, "GetWithVersion", id, store.ErrNotFound)
+	}
+
+	ent = m.copy(ent)
+	return &ent, version, nil
+}
+
+func (m *MemStore[K, T]) UpdateIfVersion(ctx context.Context, id K, version int64, entity *T) error {
+	v, release, err := m.viewKey(ctx, id, true)
+	if err != nil {
+		return store.NewError(backend, "UpdateIfVersion", id, err)
+	}
+	defer release()
+
+	_, current, ok := v.get(id)
+	if !ok {
+		return store.NewError(backend, "UpdateIfVersion", id, store.ErrNotFound)
+	}
+	if current != version {
+		return store.NewError(backend, "UpdateIfVersion", id, store.ErrVersionConflict)
+	}
+	if err := m.checkUnique(v, id, *entity); err != nil {
+		return store.NewError(backend, "UpdateIfVersion", id, err)
+	}
+
+	v.put(id, m.copy(*entity), version+1)
+	if err := m.flush(v); err != nil {
+		return store.NewError(backend, "UpdateIfVersion", id, err)
+	}
+	return nil
+}
+
+// bumpVersions increments the version of every entity and drops the versions
+// of the deleted ones. It must be called with the write lock held.
+func (m *MemStore[K, T]) bumpVersions() {
+	m.changes.Add(1)
+	for _, s := range m.shards {
+		for id := range s.versions {
+			if _, ok := s.data[id]; !ok {
+				delete(s.versions, id)
+			}
+		}
+		for id := range s.data {
+			s.versions[id]++
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ gostore.Versioned[string, TestEntity] = (*MemStore[string, TestEntity])(nil)
+
+func TestVersions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Writes increment the version", func(t *testing.T) {
+		store := NewMemStore[TestEntity]()
+		_, err := store.Insert(ctx, "1", &TestEntity{ID: "1", Value: "value-1"})
+		require.NoError(t, err)
+
+		_, version, err := store.GetWithVersion(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), version)
+
+		require.NoError(t, store.Update(ctx, "1", &TestEntity{ID: "1", Value: "value-2"}))
+		require.NoError(t, store.UpdateIfVersion(ctx, "1", 2, &TestEntity{ID: "1", Value: "value-3"}))
+
+		entity, version, err := store.GetWithVersion(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), version)
+		assert.Equal(t, "value-3", entity.Value)
+	})
+
+	t.Run("Stale version conflicts", func(t *testing.T) {
+		store := NewMemStore[TestEntity]()
+		store.Insert(ctx, "1", &TestEntity{ID: "1", Value: "value-1"})
+		_, version, _ := store.GetWithVersion(ctx, "1")
+
+		require.NoError(t, store.UpdateIfVersion(ctx, "1", version, &TestEntity{ID: "1", Value: "first"}))
+		err := store.UpdateIfVersion(ctx, "1", version, &TestEntity{ID: "1", Value: "second"})
+		assert.ErrorIs(t, err, gostore.ErrVersionConflict)
+
+		entity, _ := store.GetByID(ctx, "1")
+		assert.Equal(t, "first", entity.Value)
+	})
+
+	t.Run("Missing entity", func(t *testing.T) {
+		store := NewMemStore[TestEntity]()
+
+		_, _, err := store.GetWithVersion(ctx, "1")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+		err = store.UpdateIfVersion(ctx, "1", 1, &TestEntity{})
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+	})
+
+	t.Run("Deleted entities start over", func(t *testing.T) {
+		store := NewMemStore[TestEntity]()
+		store.Insert(ctx, "1", &TestEntity{ID: "1"})
+		store.Update(ctx, "1", &TestEntity{ID: "1"})
+		store.Delete(ctx, "1")
+		store.Insert(ctx, "1", &TestEntity{ID: "1"})
+
+		_, version, err := store.GetWithVersion(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), version)
+	})
+
+	t.Run("ExecuteUpdate bumps every version", func(t *testing.T) {
+		store := NewMemStore[TestEntity]()
+		store.Insert(ctx, "1", &TestEntity{ID: "1"})
+		store.Insert(ctx, "2", &TestEntity{ID: "2"})
+
+		_, err := store.ExecuteUpdate(ctx, func(ctx context.Context, data map[string]TestEntity) (int, error) {
+			delete(data, "2")
+			data["3"] = TestEntity{ID: "3"}
+			return 2, nil
+		})
+		require.NoError(t, err)
+
+		_, version, _ := store.GetWithVersion(ctx, "1")
+		assert.Equal(t, int64(2), version)
+		_, version, _ = store.GetWithVersion(ctx, "3")
+		assert.Equal(t, int64(1), version)
+		assert.NotContains(t, store.shards[0].versions, "2")
+	})
+
+	t.Run("Concurrent writers", func(t *testing.T) {
+		store := NewMemStore[TestEntity]()
+		store.Insert(ctx, "1", &TestEntity{ID: "1"})
+
+		results := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			go func() {
+				results <- store.UpdateIfVersion(ctx, "1", 1, &TestEntity{ID: "1"})
+			}()
+		}
+
+		succeeded := 0
+		for i := 0; i < 10; i++ {
+			if err := <-results; err == nil {
+				succeeded++
+			} else {
+				assert.ErrorIs(t, err, gostore.ErrVersionConflict)
+			}
+		}
+		assert.Equal(t, 1, succeeded)
+	})
+}
@@ -0,0 +1,301 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	store "github.com/Silencevoice/go-store"
+)
+
+// ErrClosed is returned by the writes to a durable store after Close.
+var ErrClosed = errors.New("store closed")
+
+const (
+	snapshotName = "snapshot"
+	walName      = "wal"
+	// frameHeader is the length and the CRC-32C of the record that follows.
+	frameHeader = 8
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// WithCompactionSize sets the size the log of a durable store may reach
+// before it is compacted into a snapshot. The default is 64 MiB, and 0 only
+// compacts when Compact is called.
+func WithCompactionSize(size int64) Option {
+	return func(o *options) {
+		o.compactAt = size
+	}
+}
+
+// walChange is the new state of an entity, so replaying it twice does no
+// harm.
+type walChange[K comparable, T any] struct {
+	ID      K
+	Version int64
+	Deleted bool
+	Entity  T
+}
+
+// walRecord holds the changes of one write, which are replayed all or none.
+type walRecord[K comparable, T any] struct {
+	Changes []walChange[K, T]
+}
+
+// pendingChange is a change not written to the log yet, with what it
+// replaced in case the write fails.
+type pendingChange[K comparable, T any] struct {
+	walChange[K, T]
+	existed     bool
+	prev        T
+	prevVersion int64
+	// prevDeadline is zero when the entity did not expire
+	prevDeadline time.Time
+}
+
+type wal struct {
+	dir       string
+	f         *os.File
+	size      int64
+	compactAt int64
+	// err is set when the log cannot be written anymore
+	err error
+}
+
+// OpenDurable returns a string keyed durable MemStore.
+func OpenDurable[T any](dir string, opts ...Option) (*MemStore[string, T], error) {
+	return OpenKeyedDurable[string, T](dir, opts...)
+}
+
+// OpenKeyedDurable returns a MemStore that keeps its entities in dir. Every
+// write is appended to a write-ahead log, and synced to disk, before it
+// returns. On open the last snapshot is restored and the log replayed on top
+// of it, dropping the record a crash may have left half written.
+//
+// The log is compacted into a new snapshot when it reaches the size set by
+// WithCompactionSize. Close the store to release the log.
+func OpenKeyedDurable[K comparable, T any](dir string, opts ...Option) (*MemStore[K, T], error) {
+	o := newOptions(opts)
+	m := newMemStore[K, T](o)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, store.NewError(backend, "Open", nil, err)
+	}
+	if err := m.RestoreFile(filepath.Join(dir, snapshotName)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, walName), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, store.NewError(backend, "Open", nil, err)
+	}
+	size, err := m.replay(f)
+	if err != nil {
+		f.Close()
+		return nil, store.NewError(backend, "Open", nil, err)
+	}
+
+	// The evictions of a full store are not logged, so they happen again on
+	// every open until the next compaction
+	m.evict(nil)
+	m.notify()
+
+	m.wal = &wal{dir: dir, f: f, size: size, compactAt: o.compactAt}
+	return m, nil
+}
+
+// replay applies the records of the log and truncates it after the last
+// complete one.
+func (m *MemStore[K, T]) replay(f *os.File) (int64, error) {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return 0, err
+	}
+
+	var offset int64
+	for {
+		payload, ok := nextFrame(data[offset:])
+		if !ok {
+			break
+		}
+
+		var record walRecord[K, T]
+		if err := m.codec.NewDecoder(bytes.NewReader(payload)).Decode(&record); err != nil {
+			return 0, fmt.Errorf("log record at %d: %w", offset, err)
+		}
+		for _, c := range record.Changes {
+			if c.Deleted {
+				m.unset(c.ID)
+			} else {
+				m.set(c.ID, c.Entity, c.Version)
+			}
+		}
+		offset += int64(frameHeader + len(payload))
+	}
+
+	if offset < int64(len(data)) {
+		if err := f.Truncate(offset); err != nil {
+			return 0, err
+		}
+		if err := f.Sync(); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
+
+// nextFrame returns the payload of the frame at the start of data, unless it
+// is incomplete or corrupted.
+func nextFrame(data []byte) ([]byte, bool) {
+	if len(data) < frameHeader {
+		return nil, false
+	}
+	n := binary.LittleEndian.Uint32(data)
+	if uint64(len(data)-frameHeader) < uint64(n) {
+		return nil, false
+	}
+	payload := data[frameHeader : frameHeader+int(n)]
+	if crc32.Checksum(payload, castagnoli) != binary.LittleEndian.Uint32(data[4:]) {
+		return nil, false
+	}
+	return payload, true
+}
+
+// flush appends the pending changes to the log when v is the store itself.
+// When that fails, the changes are rolled back. Callers must hold the write
+// lock.
+func (m *MemStore[K, T]) flush(v view[K, T]) error {
+	if _, ok := v.(*MemStore[K, T]); !ok || m.wal == nil || len(m.pending) == 0 {
+		return nil
+	}
+
+	pending := m.pending
+	m.pending = nil
+	if err := m.appendRecord(pending); err != nil {
+		rolledBack := make(map[K]bool, len(pending))
+		for i := len(pending) - 1; i >= 0; i-- {
+			c := pending[i]
+			rolledBack[c.ID] = true
+			if !c.existed {
+				m.unset(c.ID)
+				continue
+			}
+			// set starts the TTL again, so the deadline is put back after it
+			m.set(c.ID, c.prev, c.prevVersion)
+			m.restoreDeadline(m.shard(c.ID), c.ID, c.prevDeadline)
+		}
+		// The entities the write evicted are back, so the callback must
+		// not hear about them
+		m.forgetEvictions(rolledBack)
+		return err
+	}
+
+	if m.wal.compactAt > 0 && m.wal.size >= m.wal.compactAt {
+		// The changes are already safe in the log, and compaction is tried
+		// again after the next write
+		_ = m.compact()
+	}
+	return nil
+}
+
+func (m *MemStore[K, T]) appendRecord(pending []pendingChange[K, T]) error {
+	w := m.wal
+	if w.err != nil {
+		return w.err
+	}
+
+	record := walRecord[K, T]{Changes: make([]walChange[K, T], len(pending))}
+	for i, c := range pending {
+		record.Changes[i] = c.walChange
+	}
+
+	var buf bytes.Buffer
+	buf.Write(make([]byte, frameHeader))
+	if err := m.codec.NewEncoder(&buf).Encode(record); err != nil {
+		return err
+	}
+	frame := buf.Bytes()
+	binary.LittleEndian.PutUint32(frame, uint32(len(frame)-frameHeader))
+	binary.LittleEndian.PutUint32(frame[4:], crc32.Checksum(frame[frameHeader:], castagnoli))
+
+	_, err := w.f.Write(frame)
+	if err == nil {
+		err = w.f.Sync()
+	}
+	if err != nil {
+		// Drop whatever part of the frame was written, or stop writing when
+		// that is not possible, so no record is ever appended after garbage
+		if terr := w.f.Truncate(w.size); terr != nil {
+			w.err = fmt.Errorf("log unusable after failed write: %w", err)
+		} else if _, serr := w.f.Seek(w.size, io.SeekStart); serr != nil {
+			w.err = fmt.Errorf("log unusable after failed write: %w", err)
+		}
+		return err
+	}
+
+	w.size += int64(len(frame))
+	return nil
+}
+
+// Compact writes a snapshot of a durable store and empties its log.
+func (m *MemStore[K, T]) Compact() error {
+	release := m.lockAll(true)
+	defer release()
+
+	if m.wal == nil {
+		return store.NewError(backend, "Compact", nil, errors.New("store is not durable"))
+	}
+	if m.wal.err != nil {
+		return store.NewError(backend, "Compact", nil, m.wal.err)
+	}
+	return m.compact()
+}
+
+// compact must be called with the write lock held. writeSnapshotFile syncs
+// the directory, so the new snapshot reaches the disk before the log is
+// truncated, and a crash between both only replays changes the snapshot has.
+func (m *MemStore[K, T]) compact() error {
+	if err := m.writeSnapshotFile(filepath.Join(m.wal.dir, snapshotName)); err != nil {
+		return err
+	}
+
+	w := m.wal
+	if err := w.f.Truncate(0); err != nil {
+		return store.NewError(backend, "Compact", nil, err)
+	}
+	if _, err := w.f.Seek(0, io.SeekStart); err != nil {
+		return store.NewError(backend, "Compact", nil, err)
+	}
+	if err := w.f.Sync(); err != nil {
+		return store.NewError(backend, "Compact", nil, err)
+	}
+	w.size = 0
+	return nil
+}
+
+// Close releases the log of a durable store, whose writes fail with
+// ErrClosed from then on. It does nothing for other stores.
+func (m *MemStore[K, T]) Close() error {
+	release := m.lockAll(true)
+	defer release()
+
+	if m.wal == nil || errors.Is(m.wal.err, ErrClosed) {
+		return nil
+	}
+	m.wal.err = ErrClosed
+	if err := m.wal.f.Close(); err != nil {
+		return store.NewError(backend, "Close", nil, err)
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDurable(t *testing.T) {
+	ctx := context.Background()
+
+	for name, codec := range map[string]Codec{"JSON": JSONCodec, "Gob": GobCodec} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := OpenKeyedDurable[int, Car](dir, WithCodec(codec))
+			require.NoError(t, err)
+
+			for i := 1; i <= 5; i++ {
+				_, err := store.Insert(ctx, i, &Car{Model: "Corolla", Price: i})
+				require.NoError(t, err)
+			}
+			require.NoError(t, store.Update(ctx, 1, &Car{Model: "Yaris"}))
+			require.NoError(t, store.Patch(ctx, 2, gostore.Fields(gostore.Set("price", 20))))
+			require.NoError(t, store.Delete(ctx, 3))
+			_, err = store.ExecuteUpdate(ctx, func(ctx context.Context, data map[int]Car) (int, error) {
+				car := data[4]
+				car.Color = "red"
+				data[4] = car
+				delete(data, 5)
+				return 2, nil
+			})
+			require.NoError(t, err)
+			require.NoError(t, store.WithTx(ctx, func(ctx context.Context) error {
+				_, err := store.Insert(ctx, 6, &Car{Model: "Prius"})
+				return err
+			}))
+			require.NoError(t, store.Close())
+
+			reopened, err := OpenKeyedDurable[int, Car](dir, WithCodec(codec))
+			require.NoError(t, err)
+			defer reopened.Close()
+
+			all, err := reopened.ExecuteQuery(ctx, func(ctx context.Context, data map[int]Car) ([]*Car, error) {
+				assert.Equal(t, map[int]Car{
+					1: {Model: "Yaris"},
+					2: {Model: "Corolla", Price: 20},
+					4: {Model: "Corolla", Price: 4, Color: "red"},
+					6: {Model: "Prius"},
+				}, data)
+				return nil, nil
+			})
+			require.NoError(t, err)
+			assert.Empty(t, all)
+
+			_, version, err := reopened.GetWithVersion(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), version)
+		})
+	}
+
+	t.Run("Crash in the middle of a record", func(t *testing.T) {
+		dir := t.TempDir()
+		store, err := OpenDurable[Car](dir)
+		require.NoError(t, err)
+		store.Insert(ctx, "1", &Car{Model: "Corolla"})
+		store.Insert(ctx, "2", &Car{Model: "Yaris"})
+		require.NoError(t, store.Close())
+
+		path := filepath.Join(dir, walName)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		complete := info.Size()
+
+		store, err = OpenDurable[Car](dir)
+		require.NoError(t, err)
+		store.Insert(ctx, "3", &Car{Model: "Prius"})
+		require.NoError(t, store.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		for _, size := range []int64{complete + 3, complete + frameHeader, int64(len(data)) - 1} {
+			require.NoError(t, os.WriteFile(path, data[:size], 0o644))
+
+			store, err = OpenDurable[Car](dir)
+			require.NoError(t, err)
+			n, err := store.Count(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), n)
+			_, err = store.GetByID(ctx, "3")
+			assert.ErrorIs(t, err, gostore.ErrNotFound)
+
+			truncated, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, complete, truncated.Size())
+
+			// New records go after the last complete one
+			store.Insert(ctx, "4", &Car{Model: "Aygo"})
+			require.NoError(t, store.Close())
+			store, err = OpenDurable[Car](dir)
+			require.NoError(t, err)
+			_, err = store.GetByID(ctx, "4")
+			assert.NoError(t, err)
+			require.NoError(t, store.Close())
+		}
+	})
+
+	t.Run("Corrupted record", func(t *testing.T) {
+		dir := t.TempDir()
+		store, err := OpenDurable[Car](dir)
+		require.NoError(t, err)
+		store.Insert(ctx, "1", &Car{Model: "Corolla"})
+		store.Insert(ctx, "2", &Car{Model: "Yaris"})
+		require.NoError(t, store.Close())
+
+		path := filepath.Join(dir, walName)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		data[len(data)-2] ^= 0xff
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+
+		store, err = OpenDurable[Car](dir)
+		require.NoError(t, err)
+		defer store.Close()
+		n, err := store.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+
+	t.Run("Compaction", func(t *testing.T) {
+		dir := t.TempDir()
+		store, err := OpenDurable[Car](dir, WithCompactionSize(0))
+		require.NoError(t, err)
+		store.Insert(ctx, "1", &Car{Model: "Corolla"})
+		store.Insert(ctx, "2", &Car{Model: "Yaris"})
+
+		require.NoError(t, store.Compact())
+		info, err := os.Stat(filepath.Join(dir, walName))
+		require.NoError(t, err)
+		assert.Zero(t, info.Size())
+
+		store.Delete(ctx, "1")
+		require.NoError(t, store.Close())
+
+		store, err = OpenDurable[Car](dir)
+		require.NoError(t, err)
+		defer store.Close()
+		n, err := store.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+		car, err := store.GetByID(ctx, "2")
+		require.NoError(t, err)
+		assert.Equal(t, &Car{Model: "Yaris"}, car)
+	})
+
+	t.Run("Automatic compaction", func(t *testing.T) {
+		dir := t.TempDir()
+		store, err := OpenDurable[Car](dir, WithCompactionSize(200))
+		require.NoError(t, err)
+		for i := 0; i < 20; i++ {
+			_, err := store.Upsert(ctx, "1", &Car{Model: "Corolla", Price: i})
+			require.NoError(t, err)
+		}
+		require.NoError(t, store.Close())
+
+		info, err := os.Stat(filepath.Join(dir, walName))
+		require.NoError(t, err)
+		assert.Less(t, info.Size(), int64(200))
+		_, err = os.Stat(filepath.Join(dir, snapshotName))
+		require.NoError(t, err)
+
+		store, err = OpenDurable[Car](dir)
+		require.NoError(t, err)
+		defer store.Close()
+		car, version, err := store.GetWithVersion(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, &Car{Model: "Corolla", Price: 19}, car)
+		assert.Equal(t, int64(20), version)
+	})
+
+	t.Run("Restore replaces the log", func(t *testing.T) {
+		source := NewMemStore[Car]()
+		source.Insert(ctx, "9", &Car{Model: "Aygo"})
+		dir := t.TempDir()
+		path := filepath.Join(dir, "backup")
+		require.NoError(t, source.SnapshotFile(path))
+
+		store, err := OpenDurable[Car](filepath.Join(dir, "data"))
+		require.NoError(t, err)
+		store.Insert(ctx, "1", &Car{Model: "Corolla"})
+		require.NoError(t, store.RestoreFile(path))
+		require.NoError(t, store.Close())
+
+		store, err = OpenDurable[Car](filepath.Join(dir, "data"))
+		require.NoError(t, err)
+		defer store.Close()
+		_, err = store.GetByID(ctx, "1")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+		_, err = store.GetByID(ctx, "9")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Writes after Close", func(t *testing.T) {
+		store, err := OpenKeyedDurable[int, Car](t.TempDir(), WithIndex("model", func(c Car) string { return c.Model }))
+		require.NoError(t, err)
+		store.Insert(ctx, 1, &Car{Model: "Corolla"})
+		require.NoError(t, store.Close())
+		require.NoError(t, store.Close())
+
+		_, err = store.Insert(ctx, 2, &Car{Model: "Corolla"})
+		assert.ErrorIs(t, err, ErrClosed)
+		err = store.Update(ctx, 1, &Car{Model: "Yaris"})
+		assert.ErrorIs(t, err, ErrClosed)
+		result, err := store.DeleteMany(ctx, []int{1}, gostore.BulkOptions{})
+		assert.ErrorIs(t, err, ErrClosed)
+		assert.Nil(t, result)
+		err = store.WithTx(ctx, func(ctx context.Context) error {
+			return store.Delete(ctx, 1)
+		})
+		assert.ErrorIs(t, err, ErrClosed)
+
+		// The failed writes are rolled back, and reads still work
+		car, version, err := store.GetWithVersion(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, &Car{Model: "Corolla"}, car)
+		assert.Equal(t, int64(1), version)
+		_, err = store.GetByID(ctx, 2)
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+		found, err := store.FindByIndex(ctx, "model", "Corolla")
+		require.NoError(t, err)
+		assert.Len(t, found, 1)
+	})
+
+	t.Run("Failed writes restore what they evicted", func(t *testing.T) {
+		clock := &clock{now: time.Now()}
+		evictions := []evicted{}
+		store, err := OpenDurable[TestEntity](t.TempDir(), WithMaxEntries(1), recordEvictions(&evictions))
+		require.NoError(t, err)
+		defer store.Close()
+		store.now = clock.Now
+		store.Insert(ctx, "1", &TestEntity{ID: "1"})
+		require.NoError(t, store.Expire(ctx, "1", time.Hour))
+
+		store.wal.err = errors.New("disk full")
+		_, err = store.Insert(ctx, "2", &TestEntity{ID: "2"})
+		assert.Error(t, err)
+		assert.Empty(t, evictions)
+		_, err = store.GetByID(ctx, "1")
+		require.NoError(t, err)
+
+		// The entity keeps its deadline
+		clock.Add(time.Hour)
+		_, err = store.GetByID(ctx, "1")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+	})
+
+	t.Run("Not durable", func(t *testing.T) {
+		store := NewMemStore[Car]()
+		assert.NoError(t, store.Close())
+		assert.Error(t, store.Compact())
+	})
+
+	t.Run("Unreadable directory", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(file, nil, 0o644))
+		_, err := OpenDurable[Car](file)
+		var storeErr *gostore.Error
+		assert.True(t, errors.As(err, &storeErr))
+	})
+}
@@ -0,0 +1,198 @@
+package mongo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	store "github.com/Silencevoice/go-store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type bulkItem[K comparable] struct {
+	index int
+	id    K
+	model mongo.WriteModel
+}
+
+func (m *MongoStore[K, T]) InsertMany(ctx context.Context, entries []store.Entry[K, T], opts store.BulkOptions) (*store.BulkResult[K], error) {
+	items := []bulkItem[K]{}
+	failures := []store.BulkFailure[K]{}
+	now := time.Now()
+	for i, entry := range entries {
+		key, err := m.encodeKey("InsertMany", entry.ID)
+		if err != nil {
+			failures = append(failures, store.BulkFailure[K]{Index: i, ID: entry.ID, Err: err})
+			continue
+		}
+
+		doc, err := m.mapping.document(key, entry.Entity, now)
+		if err != nil {
+			failures = append(failures, store.BulkFailure[K]{Index: i, ID: entry.ID, Err: store.NewError(backend, "InsertMany", entry.ID, err)})
+			continue
+		}
+		if m.version != "" {
+			doc = append(withoutField(doc, m.version), bson.E{Key: m.version, Value: int64(1)})
+		}
+		items = append(items, bulkItem[K]{index: i, id: entry.ID, model: mongo.NewInsertOneModel().SetDocument(doc)})
+	}
+
+	return m.bulkWrite(ctx, "InsertMany", items, failures, opts)
+}
+
+// UpdateMany and DeleteMany look up the existing ids first, because the bulk
+// write result only counts the matched documents without telling which ones.
+// Documents deleted between both steps are not reported as failures.
+func (m *MongoStore[K, T]) UpdateMany(ctx context.Context, entries []store.Entry[K, T], opts store.BulkOptions) (*store.BulkResult[K], error) {
+	ids := make([]K, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+
+	keys, failures, err := m.existingKeys(ctx, "UpdateMany", ids)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []bulkItem[K]{}
+	for i, entry := range entries {
+		if keys[i] == nil {
+			continue
+		}
+
+		update, err := m.updateDoc(entry.Entity)
+		if err != nil {
+			failures = append(failures, store.BulkFailure[K]{Index: i, ID: entry.ID, Err: store.NewError(backend, "UpdateMany", entry.ID, err)})
+			continue
+		}
+		model := mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": keys[i]}).SetUpdate(update)
+		items = append(items, bulkItem[K]{index: i, id: entry.ID, model: model})
+	}
+
+	return m.bulkWrite(ctx, "UpdateMany", items, failures, opts)
+}
+
+func (m *MongoStore[K, T]) DeleteMany(ctx context.Context, ids []K, opts store.BulkOptions) (*store.BulkResult[K], error) {
+	keys, failures, err := m.existingKeys(ctx, "DeleteMany", ids)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []bulkItem[K]{}
+	for i, id := range ids {
+		if keys[i] != nil {
+			items = append(items, bulkItem[K]{index: i, id: id, model: mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": keys[i]})})
+		}
+	}
+
+	return m.bulkWrite(ctx, "DeleteMany", items, failures, opts)
+}
+
+// existingKeys encodes ids, leaving a nil key and a failure for the invalid
+// and missing ones.
+func (m *MongoStore[K, T]) existingKeys(ctx context.Context, op string, ids []K) ([]any, []store.BulkFailure[K], error) {
+	keys := make([]any, len(ids))
+	failures := []store.BulkFailure[K]{}
+	valid := []any{}
+	for i, id := range ids {
+		key, err := m.encodeKey(op, id)
+		if err != nil {
+			failures = append(failures, store.BulkFailure[K]{Index: i, ID: id, Err: err})
+			continue
+		}
+		keys[i] = key
+		valid = append(valid, key)
+	}
+	if len(valid) == 0 {
+		return keys, failures, nil
+	}
+
+	cursor, err := m.collection.Find(ctx, bson.M{"_id": bson.M{"$in": valid}}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, nil, store.NewError(backend, op, nil, err)
+	}
+	defer cursor.Close(ctx)
+
+	existing := [][]byte{}
+	for cursor.Next(ctx) {
+		existing = append(existing, append([]byte{}, cursor.Current.Lookup("_id").Value...))
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, nil, store.NewError(backend, op, nil, err)
+	}
+
+	for i, key := range keys {
+		if key == nil {
+			continue
+		}
+		_, raw, err := bson.MarshalValue(key)
+		if err != nil {
+			return nil, nil, store.NewError(backend, op, ids[i], err)
+		}
+		found := false
+		for _, e := range existing {
+			if bytes.Equal(e, raw) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			keys[i] = nil
+			failures = append(failures, store.BulkFailure[K]{Index: i, ID: ids[i], Err: store.NewError(backend, op, ids[i], store.ErrNotFound)})
+		}
+	}
+
+	return keys, failures, nil
+}
+
+// bulkWrite sends the models in a single BulkWrite and merges its write
+// errors with the failures found before. Ordered writes only send the models
+// placed before the first known failure.
+func (m *MongoStore[K, T]) bulkWrite(ctx context.Context, op string, items []bulkItem[K], failures []store.BulkFailure[K], opts store.BulkOptions) (*store.BulkResult[K], error) {
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Index < failures[j].Index })
+	if opts.Ordered && len(failures) > 0 {
+		failures = failures[:1]
+		n := sort.Search(len(items), func(i int) bool { return items[i].index > failures[0].Index })
+		items = items[:n]
+	}
+
+	result := &store.BulkResult[K]{Succeeded: len(items)}
+	if len(items) > 0 {
+		models := make([]mongo.WriteModel, len(items))
+		for i, item := range items {
+			models[i] = item.model
+		}
+
+		_, err := m.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(opts.Ordered))
+		var bulkErr mongo.BulkWriteException
+		if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+			for _, writeErr := range bulkErr.WriteErrors {
+				item := items[writeErr.Index]
+				failures = append(failures, store.BulkFailure[K]{Index: item.index, ID: item.id, Err: store.NewError(backend, op, item.id, bulkWriteError(writeErr))})
+			}
+			result.Succeeded -= len(bulkErr.WriteErrors)
+		} else if err != nil {
+			return nil, store.NewError(backend, op, nil, err)
+		}
+	}
+
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Index < failures[j].Index })
+	if opts.Ordered && len(failures) > 0 {
+		failures = failures[:1]
+		result.Succeeded = sort.Search(len(items), func(i int) bool { return items[i].index >= failures[0].Index })
+	}
+	result.Failures = failures
+
+	return result, result.Err()
+}
+
+func bulkWriteError(err mongo.BulkWriteError) error {
+	if err.Code == 11000 {
+		return duplicateKeyError(err.Message, err)
+	}
+	return err
+}
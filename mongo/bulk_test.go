@@ -0,0 +1,158 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+var _ gostore.BulkWriter[string, TestEntity] = (*MongoStore[string, TestEntity])(nil)
+
+func entries(ids ...string) []gostore.Entry[string, TestEntity] {
+	result := []gostore.Entry[string, TestEntity]{}
+	for _, id := range ids {
+		result = append(result, gostore.Entry[string, TestEntity]{ID: id, Entity: &TestEntity{Value: "value"}})
+	}
+	return result
+}
+
+func idsResponse(ids ...string) bson.D {
+	docs := []bson.D{}
+	for _, id := range ids {
+		oid, _ := primitive.ObjectIDFromHex(id)
+		docs = append(docs, bson.D{{Key: "_id", Value: oid}})
+	}
+	return mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, docs...)
+}
+
+func TestMongoStore_InsertMany(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ids := []string{primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()}
+
+	mt.Run("Insert every entity in one command", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 3}))
+		result, err := store.InsertMany(context.Background(), entries(ids...), gostore.BulkOptions{})
+		require.NoError(mt, err)
+		assert.Equal(mt, 3, result.Succeeded)
+		assert.Empty(mt, result.Failures)
+
+		events := mt.GetAllStartedEvents()
+		require.Len(mt, events, 1)
+		docs, _ := events[0].Command.Lookup("documents").Array().Values()
+		assert.Len(mt, docs, 3)
+		assert.False(mt, events[0].Command.Lookup("ordered").Boolean())
+	})
+
+	mt.Run("Reports duplicate and invalid keys", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 1, Code: 11000, Message: "duplicate key"}))
+		result, err := store.InsertMany(context.Background(), entries(ids[0], "invalid", ids[1], ids[2]), gostore.BulkOptions{})
+		assert.Error(mt, err)
+		assert.Equal(mt, 2, result.Succeeded)
+		require.Len(mt, result.Failures, 2)
+		assert.Equal(mt, 1, result.Failures[0].Index)
+		assert.ErrorIs(mt, result.Failures[0].Err, gostore.ErrInvalidID)
+		assert.Equal(mt, 2, result.Failures[1].Index)
+		assert.Equal(mt, ids[1], result.Failures[1].ID)
+		assert.ErrorIs(mt, result.Failures[1].Err, gostore.ErrAlreadyExists)
+	})
+
+	mt.Run("Ordered stops at the first failure", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		result, err := store.InsertMany(context.Background(), entries(ids[0], "invalid", ids[1]), gostore.BulkOptions{Ordered: true})
+		assert.ErrorIs(mt, err, gostore.ErrInvalidID)
+		assert.Equal(mt, 1, result.Succeeded)
+		assert.Len(mt, result.Failures, 1)
+
+		docs, _ := mt.GetStartedEvent().Command.Lookup("documents").Array().Values()
+		assert.Len(mt, docs, 1)
+	})
+
+	mt.Run("Ordered write error", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 1, Code: 11000, Message: "duplicate key"}))
+		result, err := store.InsertMany(context.Background(), entries(ids...), gostore.BulkOptions{Ordered: true})
+		assert.ErrorIs(mt, err, gostore.ErrAlreadyExists)
+		assert.Equal(mt, 1, result.Succeeded)
+		require.Len(mt, result.Failures, 1)
+		assert.Equal(mt, ids[1], result.Failures[0].ID)
+	})
+
+	mt.Run("Command error", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}, {Key: "errmsg", Value: "failure"}})
+		result, err := store.InsertMany(context.Background(), entries(ids...), gostore.BulkOptions{})
+		assert.Error(mt, err)
+		assert.Nil(mt, result)
+	})
+}
+
+func TestMongoStore_UpdateMany(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ids := []string{primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()}
+
+	mt.Run("Reports missing entities", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(idsResponse(ids[0], ids[2]), updateResponse(2))
+		result, err := store.UpdateMany(context.Background(), entries(ids...), gostore.BulkOptions{})
+		assert.ErrorIs(mt, err, gostore.ErrNotFound)
+		assert.Equal(mt, 2, result.Succeeded)
+		require.Len(mt, result.Failures, 1)
+		assert.Equal(mt, ids[1], result.Failures[0].ID)
+
+		events := mt.GetAllStartedEvents()
+		require.Len(mt, events, 2)
+		updates, _ := events[1].Command.Lookup("updates").Array().Values()
+		assert.Len(mt, updates, 2)
+	})
+
+	mt.Run("Nothing to update", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(idsResponse())
+		result, err := store.UpdateMany(context.Background(), entries(ids[0]), gostore.BulkOptions{})
+		assert.ErrorIs(mt, err, gostore.ErrNotFound)
+		assert.Equal(mt, 0, result.Succeeded)
+	})
+}
+
+func TestMongoStore_DeleteMany(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ids := []string{primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()}
+
+	mt.Run("Ordered delete stops at missing entities", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(idsResponse(ids[0], ids[2]), mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		result, err := store.DeleteMany(context.Background(), ids, gostore.BulkOptions{Ordered: true})
+		assert.ErrorIs(mt, err, gostore.ErrNotFound)
+		assert.Equal(mt, 1, result.Succeeded)
+
+		events := mt.GetAllStartedEvents()
+		require.Len(mt, events, 2)
+		deletes, _ := events[1].Command.Lookup("deletes").Array().Values()
+		assert.Len(mt, deletes, 1)
+	})
+
+	mt.Run("Lookup error", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}, {Key: "errmsg", Value: "failure"}})
+		_, err := store.DeleteMany(context.Background(), ids, gostore.BulkOptions{})
+		assert.Error(mt, err)
+	})
+}
@@ -0,0 +1,54 @@
+package mongo
+
+import (
+	"context"
+
+	store "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (m *MongoStore[K, T]) Exists(ctx context.Context, id K) (bool, error) {
+	key, err := m.encodeKey("Exists", id)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := m.collection.CountDocuments(ctx, bson.M{"_id": key}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, store.NewError(backend, "Exists", id, err)
+	}
+	return n > 0, nil
+}
+
+// Count uses the collection metadata, which is fast but may be off after an
+// unclean shutdown. Inside a transaction, where the metadata cannot be read,
+// it counts the documents instead.
+func (m *MongoStore[K, T]) Count(ctx context.Context) (int64, error) {
+	var n int64
+	var err error
+	if session := mongo.SessionFromContext(ctx); session != nil {
+		n, err = m.collection.CountDocuments(ctx, bson.M{})
+	} else {
+		n, err = m.collection.EstimatedDocumentCount(ctx)
+	}
+	if err != nil {
+		return 0, store.NewError(backend, "Count", nil, err)
+	}
+	return n, nil
+}
+
+func (m *MongoStore[K, T]) CountWhere(ctx context.Context, filter query.Expr) (int64, error) {
+	compiled, err := compileFilter(filter, m.mapping)
+	if err != nil {
+		return 0, store.NewError(backend, "CountWhere", nil, err)
+	}
+
+	n, err := m.collection.CountDocuments(ctx, compiled)
+	if err != nil {
+		return 0, store.NewError(backend, "CountWhere", nil, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,85 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+var _ gostore.Counter[string] = (*MongoStore[string, TestEntity])(nil)
+
+func TestMongoStore_Count(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Exists", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+		id := primitive.NewObjectID()
+
+		mt.AddMockResponses(countResponse(1), countResponse(0))
+		ok, err := store.Exists(context.Background(), id.Hex())
+		require.NoError(mt, err)
+		assert.True(mt, ok)
+		ok, err = store.Exists(context.Background(), id.Hex())
+		require.NoError(mt, err)
+		assert.False(mt, ok)
+
+		pipeline := mt.GetStartedEvent().Command.Lookup("pipeline").Array()
+		assert.Equal(mt, id, pipeline.Index(0).Value().Document().Lookup("$match", "_id").ObjectID())
+	})
+
+	mt.Run("Exists with invalid ID", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+		_, err := store.Exists(context.Background(), "1")
+		assert.ErrorIs(mt, err, gostore.ErrInvalidID)
+	})
+
+	mt.Run("Count uses the estimate", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: int32(42)}))
+		n, err := store.Count(context.Background())
+		require.NoError(mt, err)
+		assert.Equal(mt, int64(42), n)
+		assert.Equal(mt, "count", mt.GetStartedEvent().CommandName)
+	})
+
+	mt.Run("Count inside a transaction", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(countResponse(7), mtest.CreateSuccessResponse())
+		err := store.WithTx(context.Background(), func(ctx context.Context) error {
+			n, err := store.Count(ctx)
+			assert.Equal(mt, int64(7), n)
+			return err
+		})
+		require.NoError(mt, err)
+		assert.Equal(mt, "aggregate", mt.GetStartedEvent().CommandName)
+	})
+
+	mt.Run("Count where", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithMapping(EnvelopeDocument))
+
+		mt.AddMockResponses(countResponse(2))
+		n, err := store.CountWhere(context.Background(), query.Eq("value", "x"))
+		require.NoError(mt, err)
+		assert.Equal(mt, int64(2), n)
+
+		pipeline := mt.GetStartedEvent().Command.Lookup("pipeline").Array()
+		assert.Equal(mt, "x", pipeline.Index(0).Value().Document().Lookup("$match", "data.value", "$eq").StringValue())
+	})
+
+	mt.Run("Count error", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}, {Key: "errmsg", Value: "failure"}})
+		_, err := store.Count(context.Background())
+		assert.Error(mt, err)
+	})
+}
@@ -0,0 +1,316 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	store "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Index describes an index of the collection. Keys and Partial use entity
+// field paths, which are mapped like in every other method, so the same
+// specification works for both document mappings.
+type Index struct {
+	// Name defaults to the one MongoDB generates from the keys, like
+	// "make_1_year_-1".
+	Name string
+	Keys []store.SortField
+	// Unique rejects writes repeating the keys of another document with a
+	// *DuplicateKeyError.
+	Unique bool
+	// TTL removes documents once the date in their single key field is older
+	// than TTL.
+	TTL time.Duration
+	// Partial only indexes the documents matching the filter.
+	Partial query.Expr
+}
+
+// WithIndexes sets the indexes EnsureIndexes keeps in the collection.
+func WithIndexes(indexes ...Index) Option {
+	return func(c *config) {
+		c.indexes = append(c.indexes, indexes...)
+	}
+}
+
+// IndexReport tells what EnsureIndexes did. Drifted lists the indexes that
+// exist with other options than the ones specified, and Unknown the ones
+// that exist without being specified. Neither is changed: dropping or
+// rebuilding an index is left to whoever runs the migration.
+type IndexReport struct {
+	Created []string
+	Drifted []IndexDrift
+	Unknown []string
+}
+
+type IndexDrift struct {
+	Name   string
+	Reason string
+}
+
+// EnsureIndexes reconciles the indexes given to WithIndexes with the ones in
+// the collection, creating the missing ones. It is meant to be called once
+// at startup.
+func (m *MongoStore[K, T]) EnsureIndexes(ctx context.Context) (*IndexReport, error) {
+	specs := make([]bson.D, len(m.indexes))
+	for i, index := range m.indexes {
+		spec, err := index.spec(m.mapping)
+		if err != nil {
+			return nil, store.NewError(backend, "EnsureIndexes", nil, err)
+		}
+		specs[i] = spec
+	}
+
+	cursor, err := m.collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, store.NewError(backend, "EnsureIndexes", nil, err)
+	}
+	existing := []bson.D{}
+	if err := cursor.All(ctx, &existing); err != nil {
+		return nil, store.NewError(backend, "EnsureIndexes", nil, err)
+	}
+
+	report := &IndexReport{}
+	models := []mongo.IndexModel{}
+	matched := map[string]bool{}
+	for _, spec := range specs {
+		name := lookup(spec, "name").(string)
+		current, ok := findIndex(existing, spec)
+		if !ok {
+			models = append(models, indexModel(spec))
+			report.Created = append(report.Created, name)
+			continue
+		}
+
+		currentName, _ := lookup(current, "name").(string)
+		matched[currentName] = true
+		if reason := indexDrift(spec, current); reason != "" {
+			report.Drifted = append(report.Drifted, IndexDrift{Name: name, Reason: reason})
+		}
+	}
+	for _, current := range existing {
+		name, _ := lookup(current, "name").(string)
+		if name != "_id_" && !matched[name] {
+			report.Unknown = append(report.Unknown, name)
+		}
+	}
+
+	if len(models) > 0 {
+		if _, err := m.collection.Indexes().CreateMany(ctx, models); err != nil {
+			return nil, store.NewError(backend, "EnsureIndexes", nil, err)
+		}
+	}
+
+	return report, nil
+}
+
+// spec returns the index as listed by listIndexes.
+func (index Index) spec(mapping Mapping) (bson.D, error) {
+	if len(index.Keys) == 0 {
+		return nil, fmt.Errorf("index %q has no keys", index.Name)
+	}
+
+	keys := bson.D{}
+	names := []string{}
+	for _, key := range index.Keys {
+		direction := int32(1)
+		if key.Desc {
+			direction = -1
+		}
+		path := mapping.path(key.Field)
+		keys = append(keys, bson.E{Key: path, Value: direction})
+		names = append(names, fmt.Sprintf("%s_%d", path, direction))
+	}
+
+	name := index.Name
+	if name == "" {
+		name = strings.Join(names, "_")
+	}
+
+	spec := bson.D{{Key: "key", Value: keys}, {Key: "name", Value: name}}
+	if index.Unique {
+		spec = append(spec, bson.E{Key: "unique", Value: true})
+	}
+	if index.TTL > 0 {
+		if len(index.Keys) != 1 {
+			return nil, fmt.Errorf("TTL index %q must have a single key", name)
+		}
+		spec = append(spec, bson.E{Key: "expireAfterSeconds", Value: int32(index.TTL / time.Second)})
+	}
+	if index.Partial != nil {
+		filter, err := compileFilter(index.Partial, mapping)
+		if err != nil {
+			return nil, fmt.Errorf("index %q: %w", name, err)
+		}
+		spec = append(spec, bson.E{Key: "partialFilterExpression", Value: filter})
+	}
+	return spec, nil
+}
+
+func indexModel(spec bson.D) mongo.IndexModel {
+	opts := options.Index().SetName(lookup(spec, "name").(string))
+	if unique, ok := lookup(spec, "unique").(bool); ok {
+		opts.SetUnique(unique)
+	}
+	if ttl, ok := lookup(spec, "expireAfterSeconds").(int32); ok {
+		opts.SetExpireAfterSeconds(ttl)
+	}
+	if partial := lookup(spec, "partialFilterExpression"); partial != nil {
+		opts.SetPartialFilterExpression(partial)
+	}
+	return mongo.IndexModel{Keys: lookup(spec, "key"), Options: opts}
+}
+
+// findIndex finds the existing index with the name of spec, or else with its
+// keys.
+func findIndex(existing []bson.D, spec bson.D) (bson.D, bool) {
+	for _, current := range existing {
+		if lookup(current, "name") == lookup(spec, "name") {
+			return current, true
+		}
+	}
+	for _, current := range existing {
+		if sameKeys(lookup(current, "key"), lookup(spec, "key")) {
+			return current, true
+		}
+	}
+	return nil, false
+}
+
+// indexDrift describes how current differs from spec, or returns "".
+func indexDrift(spec, current bson.D) string {
+	diffs := []string{}
+	if !sameKeys(lookup(current, "key"), lookup(spec, "key")) {
+		diffs = append(diffs, "keys")
+	}
+	if lookup(current, "name") != lookup(spec, "name") {
+		diffs = append(diffs, "name")
+	}
+	currentUnique, _ := lookup(current, "unique").(bool)
+	specUnique, _ := lookup(spec, "unique").(bool)
+	if currentUnique != specUnique {
+		diffs = append(diffs, "unique")
+	}
+	if toInt64(lookup(current, "expireAfterSeconds")) != toInt64(lookup(spec, "expireAfterSeconds")) {
+		diffs = append(diffs, "TTL")
+	}
+	if !sameDocument(lookup(current, "partialFilterExpression"), lookup(spec, "partialFilterExpression")) {
+		diffs = append(diffs, "partial filter")
+	}
+
+	if len(diffs) == 0 {
+		return ""
+	}
+	return "different " + strings.Join(diffs, ", ")
+}
+
+func sameKeys(a, b any) bool {
+	x, _ := a.(bson.D)
+	y, _ := b.(bson.D)
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i].Key != y[i].Key || toInt64(x[i].Value) != toInt64(y[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameDocument compares two documents after a round trip through BSON, so
+// the numbers have the same types.
+func sameDocument(a, b any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	var x, y bson.M
+	for _, pair := range []struct {
+		doc any
+		out *bson.M
+	}{{a, &x}, {b, &y}} {
+		raw, err := bson.Marshal(pair.doc)
+		if err != nil {
+			return false
+		}
+		if err := bson.Unmarshal(raw, pair.out); err != nil {
+			return false
+		}
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+func lookup(doc bson.D, key string) any {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	return nil
+}
+
+func toInt64(v any) int64 {
+	switch n := v.(type) {
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
+
+// DuplicateKeyError is returned when a write repeats the keys of a unique
+// index. It matches store.ErrAlreadyExists for the _id index and
+// store.ErrUniqueViolation for the rest.
+type DuplicateKeyError struct {
+	// Index is the name of the violated index, empty when the server did
+	// not tell it.
+	Index string
+	Err   error
+}
+
+func (e *DuplicateKeyError) Error() string {
+	if e.Index == "" {
+		return fmt.Sprintf("%s: %s", e.sentinel(), e.Err)
+	}
+	return fmt.Sprintf("%s: index %q: %s", e.sentinel(), e.Index, e.Err)
+}
+
+func (e *DuplicateKeyError) Unwrap() []error {
+	return []error{e.sentinel(), e.Err}
+}
+
+func (e *DuplicateKeyError) sentinel() error {
+	if e.Index == "" || e.Index == "_id_" {
+		return store.ErrAlreadyExists
+	}
+	return store.ErrUniqueViolation
+}
+
+var duplicateIndex = regexp.MustCompile(`index: (\S+) dup key`)
+
+// writeError turns duplicate key errors into a *DuplicateKeyError.
+func writeError(err error) error {
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return duplicateKeyError(err.Error(), err)
+}
+
+func duplicateKeyError(message string, err error) error {
+	index := ""
+	if match := duplicateIndex.FindStringSubmatch(message); match != nil {
+		index = match[1]
+	}
+	return &DuplicateKeyError{Index: index, Err: err}
+}
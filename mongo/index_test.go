@@ -0,0 +1,208 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func indexesResponse(indexes ...bson.D) bson.D {
+	return mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, append([]bson.D{{
+		{Key: "v", Value: int32(2)},
+		{Key: "key", Value: bson.D{{Key: "_id", Value: int32(1)}}},
+		{Key: "name", Value: "_id_"},
+	}}, indexes...)...)
+}
+
+func carIndexes() Option {
+	return WithIndexes(
+		Index{Keys: []gostore.SortField{gostore.Asc("vin")}, Unique: true},
+		Index{Keys: []gostore.SortField{gostore.Asc("make"), gostore.Desc("year")}},
+		Index{Name: "expiry", Keys: []gostore.SortField{gostore.Asc("sold_at")}, TTL: 24 * time.Hour},
+		Index{Keys: []gostore.SortField{gostore.Asc("color")}, Partial: query.Eq("used", true)},
+	)
+}
+
+func TestMongoStore_EnsureIndexes(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Create missing indexes", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", carIndexes())
+
+		mt.AddMockResponses(
+			indexesResponse(bson.D{
+				{Key: "v", Value: int32(2)},
+				{Key: "key", Value: bson.D{{Key: "vin", Value: int32(1)}}},
+				{Key: "name", Value: "vin_1"},
+				{Key: "unique", Value: true},
+			}),
+			mtest.CreateSuccessResponse(),
+		)
+		report, err := store.EnsureIndexes(context.Background())
+		require.NoError(mt, err)
+		assert.Equal(mt, []string{"make_1_year_-1", "expiry", "color_1"}, report.Created)
+		assert.Empty(mt, report.Drifted)
+		assert.Empty(mt, report.Unknown)
+
+		events := mt.GetAllStartedEvents()
+		require.Len(mt, events, 2)
+		assert.Equal(mt, "createIndexes", events[1].CommandName)
+
+		var cmd struct {
+			Indexes []bson.M `bson:"indexes"`
+		}
+		require.NoError(mt, bson.Unmarshal(events[1].Command, &cmd))
+		require.Len(mt, cmd.Indexes, 3)
+		assert.Equal(mt, bson.M{"make": int32(1), "year": int32(-1)}, cmd.Indexes[0]["key"])
+		assert.Equal(mt, "expiry", cmd.Indexes[1]["name"])
+		assert.Equal(mt, int32(86400), cmd.Indexes[1]["expireAfterSeconds"])
+		assert.Equal(mt, bson.M{"used": bson.M{"$eq": true}}, cmd.Indexes[2]["partialFilterExpression"])
+	})
+
+	mt.Run("Report drift without changing anything", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithIndexes(
+			Index{Keys: []gostore.SortField{gostore.Asc("vin")}, Unique: true},
+			Index{Name: "by_make", Keys: []gostore.SortField{gostore.Asc("make")}},
+			Index{Keys: []gostore.SortField{gostore.Asc("color")}, Partial: query.Eq("used", true)},
+		))
+
+		mt.AddMockResponses(indexesResponse(
+			bson.D{
+				{Key: "key", Value: bson.D{{Key: "vin", Value: int32(1)}}},
+				{Key: "name", Value: "vin_1"},
+			},
+			bson.D{
+				{Key: "key", Value: bson.D{{Key: "make", Value: float64(1)}}},
+				{Key: "name", Value: "make_1"},
+			},
+			bson.D{
+				{Key: "key", Value: bson.D{{Key: "color", Value: int32(1)}}},
+				{Key: "name", Value: "color_1"},
+				{Key: "partialFilterExpression", Value: bson.D{{Key: "used", Value: false}}},
+			},
+			bson.D{
+				{Key: "key", Value: bson.D{{Key: "price", Value: int32(1)}}},
+				{Key: "name", Value: "price_1"},
+			},
+		))
+		report, err := store.EnsureIndexes(context.Background())
+		require.NoError(mt, err)
+		assert.Empty(mt, report.Created)
+		assert.Equal(mt, []IndexDrift{
+			{Name: "vin_1", Reason: "different unique"},
+			{Name: "by_make", Reason: "different name"},
+			{Name: "color_1", Reason: "different partial filter"},
+		}, report.Drifted)
+		assert.Equal(mt, []string{"price_1"}, report.Unknown)
+		assert.Len(mt, mt.GetAllStartedEvents(), 1)
+	})
+
+	mt.Run("Envelope paths", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithMapping(EnvelopeDocument), WithIndexes(
+			Index{Keys: []gostore.SortField{gostore.Asc("vin")}, Unique: true},
+		))
+
+		mt.AddMockResponses(indexesResponse(), mtest.CreateSuccessResponse())
+		report, err := store.EnsureIndexes(context.Background())
+		require.NoError(mt, err)
+		assert.Equal(mt, []string{"data.vin_1"}, report.Created)
+
+		events := mt.GetAllStartedEvents()
+		require.Len(mt, events, 2)
+		index := events[1].Command.Lookup("indexes").Array().Index(0).Value().Document()
+		assert.Equal(mt, "data.vin", index.Lookup("key").Document().Index(0).Key())
+	})
+
+	mt.Run("Invalid specifications", func(mt *mtest.T) {
+		for _, index := range []Index{
+			{Name: "empty"},
+			{Keys: []gostore.SortField{gostore.Asc("a"), gostore.Asc("b")}, TTL: time.Hour},
+			{Keys: []gostore.SortField{gostore.Asc("a")}, Partial: query.CompareExpr{Field: "a", Op: "like"}},
+		} {
+			store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithIndexes(index))
+			_, err := store.EnsureIndexes(context.Background())
+			assert.Error(mt, err)
+		}
+		assert.Empty(mt, mt.GetAllStartedEvents())
+	})
+
+	mt.Run("List error", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", carIndexes())
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 13, Message: "unauthorized"}))
+		_, err := store.EnsureIndexes(context.Background())
+		assert.ErrorContains(mt, err, "unauthorized")
+	})
+}
+
+func duplicateKeyResponse(index string) bson.D {
+	return mtest.CreateWriteErrorsResponse(mtest.WriteError{
+		Code:    11000,
+		Message: "E11000 duplicate key error collection: db.foo.bar index: " + index + ` dup key: { vin: "X" }`,
+	})
+}
+
+func TestMongoStore_DuplicateKeyError(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID().Hex()
+
+	mt.Run("Unique index", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(duplicateKeyResponse("vin_1"))
+		_, err := store.Insert(context.Background(), id, &TestEntity{})
+		assert.ErrorIs(mt, err, gostore.ErrUniqueViolation)
+		assert.NotErrorIs(mt, err, gostore.ErrAlreadyExists)
+		assert.ErrorContains(mt, err, `index "vin_1"`)
+
+		var dupErr *DuplicateKeyError
+		require.ErrorAs(mt, err, &dupErr)
+		assert.Equal(mt, "vin_1", dupErr.Index)
+	})
+
+	mt.Run("Id index", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(duplicateKeyResponse("_id_"))
+		_, err := store.Insert(context.Background(), id, &TestEntity{})
+		assert.ErrorIs(mt, err, gostore.ErrAlreadyExists)
+		assert.NotErrorIs(mt, err, gostore.ErrUniqueViolation)
+	})
+
+	mt.Run("Every write", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+		ctx := context.Background()
+
+		writes := map[string]func() error{
+			"Update":  func() error { return store.Update(ctx, id, &TestEntity{}) },
+			"Replace": func() error { return store.Replace(ctx, id, &TestEntity{}) },
+			"Upsert":  func() error { _, err := store.Upsert(ctx, id, &TestEntity{}); return err },
+			"Patch":   func() error { return store.Patch(ctx, id, gostore.Fields(gostore.Set("value", "X"))) },
+		}
+		for name, write := range writes {
+			mt.AddMockResponses(duplicateKeyResponse("vin_1"))
+			err := write()
+			assert.ErrorIs(mt, err, gostore.ErrUniqueViolation, name)
+		}
+	})
+
+	mt.Run("Bulk writes", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(duplicateKeyResponse("vin_1"))
+		result, err := store.InsertMany(context.Background(), entries(id), gostore.BulkOptions{})
+		assert.ErrorIs(mt, err, gostore.ErrUniqueViolation)
+
+		var dupErr *DuplicateKeyError
+		require.ErrorAs(mt, result.Failures[0].Err, &dupErr)
+		assert.Equal(mt, "vin_1", dupErr.Index)
+	})
+}
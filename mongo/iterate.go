@@ -0,0 +1,90 @@
+package mongo
+
+import (
+	"context"
+
+	store "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// cursorIterator decodes documents straight from the driver cursor, so only
+// the current batch is held in memory.
+type cursorIterator[T any] struct {
+	ctx    context.Context
+	op     string
+	cursor *mongo.Cursor
+	decode func(bson.Raw) (*T, error)
+	value  *T
+	err    error
+}
+
+func (it *cursorIterator[T]) Next() bool {
+	it.value = nil
+	if it.err != nil {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	if !it.cursor.Next(it.ctx) {
+		if err := it.cursor.Err(); err != nil {
+			it.err = store.NewError(backend, it.op, nil, err)
+		}
+		return false
+	}
+
+	entity, err := it.decode(it.cursor.Current)
+	if err != nil {
+		it.err = store.NewError(backend, it.op, nil, err)
+		return false
+	}
+	it.value = entity
+	return true
+}
+
+func (it *cursorIterator[T]) Value() *T {
+	return it.value
+}
+
+func (it *cursorIterator[T]) Err() error {
+	return it.err
+}
+
+func (it *cursorIterator[T]) Close() error {
+	return it.cursor.Close(context.WithoutCancel(it.ctx))
+}
+
+func (m *MongoStore[K, T]) Iterate(ctx context.Context, filter query.Expr) (store.Iterator[T], error) {
+	compiled, err := compileFilter(filter, m.mapping)
+	if err != nil {
+		return nil, store.NewError(backend, "Iterate", nil, err)
+	}
+	return m.iterate(ctx, "Iterate", compiled)
+}
+
+func (m *MongoStore[K, T]) IterateByID(ctx context.Context, ids []K) (store.Iterator[T], error) {
+	keys := []any{}
+	for _, id := range ids {
+		key, err := m.encodeKey("IterateByID", id)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return m.iterate(ctx, "IterateByID", bson.M{"_id": bson.M{"$in": keys}})
+}
+
+func (m *MongoStore[K, T]) IterateQuery(ctx context.Context, filter bson.M) (store.Iterator[T], error) {
+	return m.iterate(ctx, "IterateQuery", filter)
+}
+
+func (m *MongoStore[K, T]) iterate(ctx context.Context, op string, filter bson.M) (store.Iterator[T], error) {
+	cursor, err := m.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, store.NewError(backend, op, nil, err)
+	}
+	return &cursorIterator[T]{ctx: ctx, op: op, cursor: cursor, decode: m.decode}, nil
+}
@@ -0,0 +1,109 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestMongoStore_Iterate(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Stream several batches", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "foo.bar", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: "1"}, {Key: "value", Value: "value-1"}},
+			),
+			mtest.CreateCursorResponse(0, "foo.bar", mtest.NextBatch,
+				bson.D{{Key: "_id", Value: "2"}, {Key: "value", Value: "value-2"}},
+			),
+		)
+
+		it, err := store.Iterate(context.Background(), query.Prefix("value", "value"))
+		require.NoError(mt, err)
+		defer it.Close()
+
+		require.True(mt, it.Next())
+		assert.Equal(mt, "value-1", it.Value().Value)
+		assert.Len(mt, mt.GetAllStartedEvents(), 1)
+
+		require.True(mt, it.Next())
+		assert.Equal(mt, "value-2", it.Value().Value)
+		events := mt.GetAllStartedEvents()
+		require.Len(mt, events, 2)
+		assert.Equal(mt, "getMore", events[1].CommandName)
+
+		assert.False(mt, it.Next())
+		assert.Nil(mt, it.Value())
+		assert.NoError(mt, it.Err())
+	})
+
+	mt.Run("Stop when the context is cancelled", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "foo.bar", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: "1"}, {Key: "value", Value: "value-1"}},
+			),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		it, err := store.Iterate(ctx, nil)
+		require.NoError(mt, err)
+		defer it.Close()
+
+		require.True(mt, it.Next())
+		cancel()
+		assert.False(mt, it.Next())
+		assert.ErrorIs(mt, it.Err(), context.Canceled)
+	})
+
+	mt.Run("Decode error", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: "1"}, {Key: "value", Value: bson.A{"not", "a", "string"}}},
+			),
+		)
+
+		it, err := store.IterateQuery(context.Background(), bson.M{})
+		require.NoError(mt, err)
+
+		entities, err := gostore.Collect(it)
+		assert.Empty(mt, entities)
+		assert.Error(mt, err)
+	})
+
+	mt.Run("Iterate by ID", func(mt *mtest.T) {
+		store := NewKeyedMongoStore[string, TestEntity](mt.DB, "foo.bar", StringCodec())
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: "1"}, {Key: "value", Value: "value-1"}},
+			),
+		)
+
+		it, err := store.IterateByID(context.Background(), []string{"1"})
+		require.NoError(mt, err)
+
+		entities, err := gostore.Collect(it)
+		require.NoError(mt, err)
+		assert.Len(mt, entities, 1)
+	})
+
+	mt.Run("Iterate by invalid ID", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		_, err := store.IterateByID(context.Background(), []string{"1"})
+		assert.ErrorIs(mt, err, gostore.ErrInvalidID)
+	})
+}
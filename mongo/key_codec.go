@@ -0,0 +1,73 @@
+package mongo
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KeyCodec converts a store key into the value saved as the document _id.
+type KeyCodec[K comparable] interface {
+	EncodeKey(id K) (any, error)
+}
+
+type KeyCodecFunc[K comparable] func(id K) (any, error)
+
+func (f KeyCodecFunc[K]) EncodeKey(id K) (any, error) {
+	return f(id)
+}
+
+// ObjectIDCodec stores hex encoded string keys as ObjectIDs.
+func ObjectIDCodec() KeyCodec[string] {
+	return KeyCodecFunc[string](func(id string) (any, error) {
+		return primitive.ObjectIDFromHex(id)
+	})
+}
+
+// StringCodec stores string keys as plain strings.
+func StringCodec() KeyCodec[string] {
+	return KeyCodecFunc[string](func(id string) (any, error) {
+		return id, nil
+	})
+}
+
+// UUIDCodec stores canonical UUID strings as BSON binary subtype 4.
+func UUIDCodec() KeyCodec[string] {
+	return KeyCodecFunc[string](func(id string) (any, error) {
+		data, err := parseUUID(id)
+		if err != nil {
+			return nil, err
+		}
+		return primitive.Binary{Subtype: 0x04, Data: data}, nil
+	})
+}
+
+// IntCodec stores integer keys as 64-bit integers.
+func IntCodec[K ~int | ~int8 | ~int16 | ~int32 | ~int64]() KeyCodec[K] {
+	return KeyCodecFunc[K](func(id K) (any, error) {
+		return int64(id), nil
+	})
+}
+
+// RawCodec hands the key to the driver unchanged, which is what composite
+// keys (structs with bson tags) need.
+func RawCodec[K comparable]() KeyCodec[K] {
+	return KeyCodecFunc[K](func(id K) (any, error) {
+		return id, nil
+	})
+}
+
+func parseUUID(id string) ([]byte, error) {
+	if len(id) != 36 || id[8] != '-' || id[13] != '-' || id[18] != '-' || id[23] != '-' {
+		return nil, errors.New("the provided string is not a valid UUID")
+	}
+
+	data, err := hex.DecodeString(strings.ReplaceAll(id, "-", ""))
+	if err != nil {
+		return nil, errors.New("the provided string is not a valid UUID")
+	}
+
+	return data, nil
+}
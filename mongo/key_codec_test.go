@@ -0,0 +1,58 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestKeyCodecs(t *testing.T) {
+	t.Run("ObjectID codec", func(t *testing.T) {
+		objectID := primitive.NewObjectID()
+		key, err := ObjectIDCodec().EncodeKey(objectID.Hex())
+		require.NoError(t, err)
+		assert.Equal(t, objectID, key)
+
+		_, err = ObjectIDCodec().EncodeKey("1")
+		assert.Error(t, err)
+	})
+
+	t.Run("String codec", func(t *testing.T) {
+		key, err := StringCodec().EncodeKey("plain-key")
+		require.NoError(t, err)
+		assert.Equal(t, "plain-key", key)
+	})
+
+	t.Run("UUID codec", func(t *testing.T) {
+		key, err := UUIDCodec().EncodeKey("123e4567-e89b-12d3-a456-426614174000")
+		require.NoError(t, err)
+		assert.Equal(t, primitive.Binary{
+			Subtype: 0x04,
+			Data:    []byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00},
+		}, key)
+
+		for _, invalid := range []string{"", "123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-42661417400z", "123e4567-e89b-12d3-a456_426614174000"} {
+			_, err := UUIDCodec().EncodeKey(invalid)
+			assert.Error(t, err, invalid)
+		}
+	})
+
+	t.Run("Int codec", func(t *testing.T) {
+		type carID int32
+		key, err := IntCodec[carID]().EncodeKey(7)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), key)
+	})
+
+	t.Run("Raw codec", func(t *testing.T) {
+		type compositeKey struct {
+			Make string `bson:"make"`
+			Year int    `bson:"year"`
+		}
+		key, err := RawCodec[compositeKey]().EncodeKey(compositeKey{"Toyota", 2020})
+		require.NoError(t, err)
+		assert.Equal(t, compositeKey{"Toyota", 2020}, key)
+	})
+}
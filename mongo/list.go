@@ -0,0 +1,160 @@
+package mongo
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	store "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type listCursor struct {
+	Values []bson.RawValue `bson:"v"`
+	Key    bson.RawValue   `bson:"k"`
+}
+
+func (m *MongoStore[K, T]) List(ctx context.Context, filter query.Expr, opts store.FindOptions) (*store.Page[T], error) {
+	compiled, err := compileFilter(filter, m.mapping)
+	if err != nil {
+		return nil, store.NewError(backend, "List", nil, err)
+	}
+
+	total, err := m.collection.CountDocuments(ctx, compiled)
+	if err != nil {
+		return nil, store.NewError(backend, "List", nil, err)
+	}
+
+	findFilter := compiled
+	if opts.Cursor != "" {
+		after, err := keysetFilter(opts.Cursor, opts.Sort, m.mapping)
+		if err != nil {
+			return nil, store.NewError(backend, "List", nil, err)
+		}
+		findFilter = bson.M{"$and": bson.A{compiled, after}}
+	}
+
+	sortDoc := bson.D{}
+	for _, field := range opts.Sort {
+		direction := 1
+		if field.Desc {
+			direction = -1
+		}
+		sortDoc = append(sortDoc, bson.E{Key: m.mapping.path(field.Field), Value: direction})
+	}
+	sortDoc = append(sortDoc, bson.E{Key: "_id", Value: 1})
+
+	findOpts := options.Find().SetSort(sortDoc)
+	if opts.Offset > 0 {
+		findOpts.SetSkip(int64(opts.Offset))
+	}
+	if opts.Limit > 0 {
+		findOpts.SetLimit(int64(opts.Limit) + 1)
+	}
+
+	cursor, err := m.collection.Find(ctx, findFilter, findOpts)
+	if err != nil {
+		return nil, store.NewError(backend, "List", nil, err)
+	}
+	defer cursor.Close(ctx)
+
+	page := &store.Page[T]{Items: []*T{}, Total: total}
+	var last bson.Raw
+	for cursor.Next(ctx) {
+		if opts.Limit > 0 && len(page.Items) == opts.Limit {
+			next, err := encodeCursor(last, opts.Sort, m.mapping)
+			if err != nil {
+				return nil, store.NewError(backend, "List", nil, err)
+			}
+			page.NextCursor = next
+			break
+		}
+
+		entity, err := m.decode(cursor.Current)
+		if err != nil {
+			return nil, store.NewError(backend, "List", nil, err)
+		}
+		page.Items = append(page.Items, entity)
+		last = append(bson.Raw{}, cursor.Current...)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, store.NewError(backend, "List", nil, err)
+	}
+
+	return page, nil
+}
+
+func encodeCursor(doc bson.Raw, sortFields []store.SortField, mapping Mapping) (string, error) {
+	cursor := listCursor{Values: make([]bson.RawValue, len(sortFields))}
+	for i, field := range sortFields {
+		cursor.Values[i] = lookupValue(doc, mapping.path(field.Field))
+	}
+	cursor.Key = lookupValue(doc, "_id")
+
+	raw, err := bson.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func lookupValue(doc bson.Raw, path string) bson.RawValue {
+	value, err := doc.LookupErr(strings.Split(path, ".")...)
+	if err != nil {
+		return bson.RawValue{Type: bsontype.Null}
+	}
+	return value
+}
+
+// keysetFilter matches the documents placed after the cursor position in the
+// (sort fields..., _id) order.
+func keysetFilter(token string, sortFields []store.SortField, mapping Mapping) (bson.M, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", store.ErrInvalidCursor, err)
+	}
+
+	var cursor listCursor
+	if err := bson.Unmarshal(raw, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: %w", store.ErrInvalidCursor, err)
+	}
+	if len(cursor.Values) != len(sortFields) {
+		return nil, fmt.Errorf("%w: sort fields do not match", store.ErrInvalidCursor)
+	}
+
+	branches := bson.A{}
+	equal := bson.A{}
+	for i, field := range sortFields {
+		path, value := mapping.path(field.Field), cursor.Values[i]
+		branches = append(branches, bson.M{"$and": append(append(bson.A{}, equal...), after(path, value, field.Desc))})
+		equal = append(equal, sameValue(path, value))
+	}
+	branches = append(branches, bson.M{"$and": append(equal, bson.M{"_id": bson.M{"$gt": cursor.Key}})})
+
+	return bson.M{"$or": branches}, nil
+}
+
+func after(field string, value bson.RawValue, desc bool) bson.M {
+	if value.Type == bsontype.Null {
+		if desc {
+			return bson.M{"$expr": false}
+		}
+		return bson.M{field: bson.M{"$ne": nil}}
+	}
+	if desc {
+		// null and missing fields come last, and $lt never matches them
+		return bson.M{"$or": bson.A{bson.M{field: bson.M{"$lt": value}}, bson.M{field: nil}}}
+	}
+	return bson.M{field: bson.M{"$gt": value}}
+}
+
+func sameValue(field string, value bson.RawValue) bson.M {
+	if value.Type == bsontype.Null {
+		return bson.M{field: nil}
+	}
+	return bson.M{field: value}
+}
@@ -0,0 +1,146 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func countResponse(n int32) bson.D {
+	return mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, bson.D{{Key: "n", Value: n}})
+}
+
+func TestMongoStore_List(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("First page", func(mt *mtest.T) {
+		store := NewKeyedMongoStore[string, TestEntity](mt.DB, "foo.bar", StringCodec())
+
+		mt.AddMockResponses(
+			countResponse(3),
+			mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: "1"}, {Key: "value", Value: "a"}},
+				bson.D{{Key: "_id", Value: "2"}, {Key: "value", Value: "b"}},
+				bson.D{{Key: "_id", Value: "3"}, {Key: "value", Value: "c"}},
+			),
+		)
+
+		page, err := store.List(context.Background(), query.Exists("value"), gostore.FindOptions{
+			Limit:  2,
+			Offset: 1,
+			Sort:   []gostore.SortField{gostore.Desc("value")},
+		})
+		require.NoError(mt, err)
+		assert.Equal(mt, int64(3), page.Total)
+		assert.Len(mt, page.Items, 2)
+		assert.Equal(mt, "b", page.Items[1].Value)
+		assert.NotEmpty(mt, page.NextCursor)
+
+		events := mt.GetAllStartedEvents()
+		require.Len(mt, events, 2)
+		find := events[1].Command
+		assert.Equal(mt, mustMarshal(mt, bson.D{{Key: "value", Value: -1}, {Key: "_id", Value: 1}}), find.Lookup("sort").Document())
+		assert.Equal(mt, int64(3), find.Lookup("limit").Int64())
+		assert.Equal(mt, int64(1), find.Lookup("skip").Int64())
+	})
+
+	mt.Run("Next page from cursor", func(mt *mtest.T) {
+		store := NewKeyedMongoStore[string, TestEntity](mt.DB, "foo.bar", StringCodec())
+		sort := []gostore.SortField{gostore.Desc("value")}
+
+		doc := mustMarshal(mt, bson.D{{Key: "_id", Value: "2"}, {Key: "value", Value: "b"}})
+		cursor, err := encodeCursor(doc, sort, FlatDocument)
+		require.NoError(mt, err)
+
+		mt.AddMockResponses(
+			countResponse(3),
+			mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: "3"}, {Key: "value", Value: "a"}},
+			),
+		)
+
+		page, err := store.List(context.Background(), nil, gostore.FindOptions{Limit: 2, Sort: sort, Cursor: cursor})
+		require.NoError(mt, err)
+		assert.Len(mt, page.Items, 1)
+		assert.Empty(mt, page.NextCursor)
+
+		filter := mt.GetAllStartedEvents()[1].Command.Lookup("filter").Document()
+		branches, err := filter.Lookup("$and").Array().Index(1).Value().Document().Lookup("$or").Array().Values()
+		require.NoError(mt, err)
+		require.Len(mt, branches, 2)
+		before := branches[0].Document().Lookup("$and").Array().Index(0).Value().Document().Lookup("$or").Array()
+		assert.Equal(mt, "b", before.Index(0).Value().Document().Lookup("value", "$lt").StringValue())
+		last := branches[1].Document().Lookup("$and").Array()
+		assert.Equal(mt, "b", last.Index(0).Value().Document().Lookup("value").StringValue())
+		assert.Equal(mt, "2", last.Index(1).Value().Document().Lookup("_id", "$gt").StringValue())
+	})
+
+	mt.Run("Descending pages reach null and missing fields", func(mt *mtest.T) {
+		store := NewKeyedMongoStore[string, TestEntity](mt.DB, "foo.bar", StringCodec())
+		sort := []gostore.SortField{gostore.Desc("value")}
+
+		mt.AddMockResponses(
+			countResponse(4),
+			mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: "1"}, {Key: "value", Value: "b"}},
+				bson.D{{Key: "_id", Value: "2"}, {Key: "value", Value: "a"}},
+				bson.D{{Key: "_id", Value: "3"}, {Key: "value", Value: nil}},
+			),
+		)
+		first, err := store.List(context.Background(), nil, gostore.FindOptions{Limit: 2, Sort: sort})
+		require.NoError(mt, err)
+		require.NotEmpty(mt, first.NextCursor)
+
+		mt.AddMockResponses(
+			countResponse(4),
+			mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: "3"}, {Key: "value", Value: nil}},
+				bson.D{{Key: "_id", Value: "4"}},
+			),
+		)
+		second, err := store.List(context.Background(), nil, gostore.FindOptions{Limit: 2, Sort: sort, Cursor: first.NextCursor})
+		require.NoError(mt, err)
+		assert.Equal(mt, []*TestEntity{{ID: "3"}, {ID: "4"}}, second.Items)
+
+		// After "a", the documents with a smaller value come first, then the
+		// ones whose value is null or missing
+		events := mt.GetAllStartedEvents()
+		filter := events[len(events)-1].Command.Lookup("filter").Document()
+		branches, err := filter.Lookup("$and").Array().Index(1).Value().Document().Lookup("$or").Array().Values()
+		require.NoError(mt, err)
+		before := branches[0].Document().Lookup("$and").Array().Index(0).Value().Document().Lookup("$or").Array()
+		assert.Equal(mt, "a", before.Index(0).Value().Document().Lookup("value", "$lt").StringValue())
+		assert.Equal(mt, bsontype.Null, before.Index(1).Value().Document().Lookup("value").Type)
+	})
+
+	mt.Run("Invalid cursor", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(countResponse(3))
+
+		_, err := store.List(context.Background(), nil, gostore.FindOptions{Cursor: "not a cursor"})
+		assert.ErrorIs(mt, err, gostore.ErrInvalidCursor)
+	})
+
+	mt.Run("Count error", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "count error"}))
+
+		_, err := store.List(context.Background(), nil, gostore.FindOptions{})
+		assert.ErrorContains(mt, err, "count error")
+	})
+}
+
+func mustMarshal(mt *mtest.T, doc bson.D) bson.Raw {
+	raw, err := bson.Marshal(doc)
+	require.NoError(mt, err)
+	return raw
+}
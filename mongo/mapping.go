@@ -0,0 +1,152 @@
+package mongo
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Mapping decides how entities are laid out in the stored documents. Every
+// MongoStore method reads, writes and filters through the same mapping.
+type Mapping int
+
+const (
+	// FlatDocument stores the entity fields at the top level of the
+	// document, with the _id taken from the key.
+	FlatDocument Mapping = iota
+	// EnvelopeDocument stores the entity under "data", next to the _id and
+	// the created_at and updated_at metadata fields.
+	EnvelopeDocument
+)
+
+const (
+	dataField      = "data"
+	createdAtField = "created_at"
+	updatedAtField = "updated_at"
+)
+
+type config struct {
+	mapping Mapping
+	version string
+	indexes []Index
+}
+
+type Option func(*config)
+
+func WithMapping(mapping Mapping) Option {
+	return func(c *config) {
+		c.mapping = mapping
+	}
+}
+
+// WithVersionField enables optimistic concurrency: every document keeps its
+// version in the given top level field, which GetWithVersion reads and
+// UpdateIfVersion checks.
+func WithVersionField(field string) Option {
+	return func(c *config) {
+		c.version = field
+	}
+}
+
+// path returns the document path of an entity field.
+func (mp Mapping) path(field string) string {
+	if mp == EnvelopeDocument && field != "_id" {
+		return dataField + "." + field
+	}
+	return field
+}
+
+// document builds the document inserted for a new entity.
+func (mp Mapping) document(key any, entity any, now time.Time) (bson.D, error) {
+	fields, err := entityFields(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	if mp == EnvelopeDocument {
+		return bson.D{
+			{Key: "_id", Value: key},
+			{Key: dataField, Value: fields},
+			{Key: createdAtField, Value: now},
+			{Key: updatedAtField, Value: now},
+		}, nil
+	}
+	return append(bson.D{{Key: "_id", Value: key}}, fields...), nil
+}
+
+// update builds the $set document that overwrites an entity, keeping the
+// fields not known by the entity type.
+func (mp Mapping) update(entity any, now time.Time) (bson.D, error) {
+	fields, err := entityFields(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	if mp == EnvelopeDocument {
+		return bson.D{
+			{Key: dataField, Value: fields},
+			{Key: updatedAtField, Value: now},
+		}, nil
+	}
+	return fields, nil
+}
+
+// decode reads an entity from a stored document. The _id always comes from
+// the document key, so an entity field tagged bson:"_id" gets the key back.
+func (mp Mapping) decode(raw bson.Raw, entity any) error {
+	if mp != EnvelopeDocument {
+		return bson.Unmarshal(raw, entity)
+	}
+
+	data, err := raw.LookupErr(dataField)
+	if err != nil {
+		return fmt.Errorf("missing %q field: %w", dataField, err)
+	}
+	doc, ok := data.DocumentOK()
+	if !ok {
+		return fmt.Errorf("%q field is a %s, not a document", dataField, data.Type)
+	}
+
+	elems, err := doc.Elements()
+	if err != nil {
+		return err
+	}
+	merged := bson.D{}
+	if id, err := raw.LookupErr("_id"); err == nil {
+		merged = append(merged, bson.E{Key: "_id", Value: id})
+	}
+	for _, elem := range elems {
+		if elem.Key() != "_id" {
+			merged = append(merged, bson.E{Key: elem.Key(), Value: elem.Value()})
+		}
+	}
+
+	encoded, err := bson.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(encoded, entity)
+}
+
+// entityFields marshals an entity into its top level fields, leaving out the
+// _id because it always comes from the key.
+func entityFields(entity any) (bson.D, error) {
+	raw, err := bson.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	elems, err := bson.Raw(raw).Elements()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := bson.D{}
+	for _, elem := range elems {
+		if elem.Key() != "_id" {
+			fields = append(fields, bson.E{Key: elem.Key(), Value: elem.Value()})
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,140 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Silencevoice/go-store/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestMapping_RoundTrip(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	for _, tt := range []struct {
+		name    string
+		mapping Mapping
+	}{
+		{"Flat document", FlatDocument},
+		{"Envelope document", EnvelopeDocument},
+	} {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			ctx := context.Background()
+			store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithMapping(tt.mapping))
+			id := primitive.NewObjectID().Hex()
+
+			// Insert and keep the document sent to the server
+			mt.AddMockResponses(mtest.CreateSuccessResponse())
+			_, err := store.Insert(ctx, id, &TestEntity{Value: "inserted"})
+			require.NoError(mt, err)
+			stored := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+
+			// Read it back
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, toD(mt, stored)))
+			result, err := store.GetByID(ctx, id)
+			require.NoError(mt, err)
+			assert.Equal(mt, &TestEntity{ID: id, Value: "inserted"}, result)
+			mt.GetStartedEvent()
+
+			// Update and apply the $set to the stored document
+			mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+			err = store.Update(ctx, id, &TestEntity{Value: "updated"})
+			require.NoError(mt, err)
+			set := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document().
+				Lookup("u").Document().Lookup("$set").Document()
+			updated := applySet(mt, stored, set)
+
+			// Re-read the updated document
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, updated))
+			result, err = store.GetByID(ctx, id)
+			require.NoError(mt, err)
+			assert.Equal(mt, &TestEntity{ID: id, Value: "updated"}, result)
+		})
+	}
+}
+
+func TestMapping_Documents(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Flat document has the entity fields at the top level", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+		id := primitive.NewObjectID()
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		_, err := store.Insert(context.Background(), id.Hex(), &TestEntity{ID: "ignored", Value: "test-value"})
+		require.NoError(mt, err)
+
+		doc := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(mt, id, doc.Lookup("_id").ObjectID())
+		assert.Equal(mt, "test-value", doc.Lookup("value").StringValue())
+		_, err = doc.LookupErr("data")
+		assert.Error(mt, err)
+	})
+
+	mt.Run("Envelope document has data and metadata", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithMapping(EnvelopeDocument))
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		_, err := store.Insert(context.Background(), primitive.NewObjectID().Hex(), &TestEntity{Value: "test-value"})
+		require.NoError(mt, err)
+
+		doc := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(mt, "test-value", doc.Lookup("data", "value").StringValue())
+		assert.Equal(mt, doc.Lookup("created_at").Time(), doc.Lookup("updated_at").Time())
+	})
+
+	mt.Run("Envelope queries filter on data fields", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithMapping(EnvelopeDocument))
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "1"},
+			{Key: "data", Value: bson.D{{Key: "value", Value: "test-value"}}},
+		}))
+
+		result, err := store.Find(context.Background(), query.Eq("value", "test-value"))
+		require.NoError(mt, err)
+		assert.Equal(mt, []*TestEntity{{ID: "1", Value: "test-value"}}, result)
+
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(mt, "test-value", filter.Lookup("data.value", "$eq").StringValue())
+	})
+
+	mt.Run("Envelope document without data", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithMapping(EnvelopeDocument))
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "value", Value: "test-value"},
+		}))
+
+		_, err := store.GetByID(context.Background(), primitive.NewObjectID().Hex())
+		assert.Error(mt, err)
+	})
+}
+
+func toD(t *mtest.T, raw bson.Raw) bson.D {
+	var doc bson.D
+	require.NoError(t, bson.Unmarshal(raw, &doc))
+	return doc
+}
+
+// applySet mimics the top level $set of the server on a stored document.
+func applySet(t *mtest.T, raw bson.Raw, set bson.Raw) bson.D {
+	doc := toD(t, raw)
+	for _, elem := range toD(t, set) {
+		replaced := false
+		for i := range doc {
+			if doc[i].Key == elem.Key {
+				doc[i].Value, replaced = elem.Value, true
+			}
+		}
+		if !replaced {
+			doc = append(doc, elem)
+		}
+	}
+	return doc
+}
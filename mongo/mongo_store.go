@@ -3,158 +3,259 @@ package mongo
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
+	store "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type MongoStore[T any] struct {
+const backend = "mongo"
+
+type MongoStore[K comparable, T any] struct {
 	collection *mongo.Collection
+	keys       KeyCodec[K]
+	mapping    Mapping
+	version    string
+	indexes    []Index
+}
+
+// NewMongoStore returns a string keyed MongoStore whose keys are hex encoded
+// ObjectIDs.
+func NewMongoStore[T any](db *mongo.Database, collectionName string, opts ...Option) *MongoStore[string, T] {
+	return NewKeyedMongoStore[string, T](db, collectionName, ObjectIDCodec(), opts...)
 }
 
-func NewMongoStore[T any](db *mongo.Database, collectionName string) *MongoStore[T] {
-	return &MongoStore[T]{
+func NewKeyedMongoStore[K comparable, T any](db *mongo.Database, collectionName string, keys KeyCodec[K], opts ...Option) *MongoStore[K, T] {
+	cfg := config{mapping: FlatDocument}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &MongoStore[K, T]{
 		collection: db.Collection(collectionName),
+		keys:       keys,
+		mapping:    cfg.mapping,
+		version:    cfg.version,
+		indexes:    cfg.indexes,
 	}
 }
 
-func (m *MongoStore[T]) GetByID(ctx context.Context, id string) (*T, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
+func (m *MongoStore[K, T]) GetByID(ctx context.Context, id K) (*T, error) {
+	key, err := m.encodeKey("GetByID", id)
 	if err != nil {
-		return nil, errors.New("invalid ID format")
+		return nil, err
 	}
 
-	var result T
-	err = m.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&result)
+	raw, err := m.collection.FindOne(ctx, bson.M{"_id": key}).Raw()
 	if errors.Is(err, mongo.ErrNoDocuments) {
-		return nil, errors.New("entity not found")
+		return nil, store.NewError(backend, "GetByID", id, store.ErrNotFound)
 	} else if err != nil {
-		return nil, err
+		return nil, store.NewError(backend, "GetByID", id, err)
 	}
 
-	return &result, nil
+	result, err := m.decode(raw)
+	if err != nil {
+		return nil, store.NewError(backend, "GetByID", id, err)
+	}
+
+	return result, nil
 }
 
-func (m *MongoStore[T]) GetMultipleByID(ctx context.Context, ids []string) ([]*T, error) {
-	objectIDs := []primitive.ObjectID{}
+func (m *MongoStore[K, T]) GetMultipleByID(ctx context.Context, ids []K) ([]*T, error) {
+	keys := []any{}
 	for _, id := range ids {
-		objectID, err := primitive.ObjectIDFromHex(id)
+		key, err := m.encodeKey("GetMultipleByID", id)
 		if err != nil {
-			return nil, errors.New("invalid ID format")
+			return nil, err
 		}
-		objectIDs = append(objectIDs, objectID)
+		keys = append(keys, key)
 	}
 
-	cursor, err := m.collection.Find(ctx, bson.M{"_id": bson.M{"$in": objectIDs}})
+	return m.find(ctx, "GetMultipleByID", bson.M{"_id": bson.M{"$in": keys}})
+}
+
+func (m *MongoStore[K, T]) GetAll(ctx context.Context) ([]*T, error) {
+	return m.find(ctx, "GetAll", bson.M{})
+}
+
+func (m *MongoStore[K, T]) Insert(ctx context.Context, id K, entity *T) (*T, error) {
+	key, err := m.encodeKey("Insert", id)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
 
-	var results []*T
-	for cursor.Next(ctx) {
-		var entity T
-		if err := cursor.Decode(&entity); err != nil {
-			return nil, err
-		}
-		results = append(results, &entity)
+	doc, err := m.mapping.document(key, entity, time.Now())
+	if err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
+	if m.version != "" {
+		doc = append(withoutField(doc, m.version), bson.E{Key: m.version, Value: int64(1)})
 	}
 
-	return results, nil
+	_, err = m.collection.InsertOne(ctx, doc)
+	if err != nil {
+		return nil, store.NewError(backend, "Insert", id, writeError(err))
+	}
+
+	return entity, nil
 }
 
-func (m *MongoStore[T]) GetAll(ctx context.Context) ([]*T, error) {
-	cursor, err := m.collection.Find(ctx, bson.M{})
+func (m *MongoStore[K, T]) Delete(ctx context.Context, id K) error {
+	key, err := m.encodeKey("Delete", id)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer cursor.Close(ctx)
 
-	var results []*T
-	for cursor.Next(ctx) {
-		var entity T
-		if err := cursor.Decode(&entity); err != nil {
-			return nil, err
-		}
-		results = append(results, &entity)
+	res, err := m.collection.DeleteOne(ctx, bson.M{"_id": key})
+	if err != nil {
+		return store.NewError(backend, "Delete", id, err)
+	}
+	if res.DeletedCount == 0 {
+		return store.NewError(backend, "Delete", id, store.ErrNotFound)
 	}
 
-	return results, nil
+	return nil
 }
 
-func (m *MongoStore[T]) Insert(ctx context.Context, id string, entity *T) (*T, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
+func (m *MongoStore[K, T]) Update(ctx context.Context, id K, entity *T) error {
+	key, err := m.encodeKey("Update", id)
 	if err != nil {
-		return nil, errors.New("invalid ID format")
+		return err
 	}
 
-	_, err = m.collection.InsertOne(ctx, bson.M{
-		"_id":  objectID,
-		"data": entity,
-	})
-	if mongo.IsDuplicateKeyError(err) {
-		return nil, errors.New("already existing key")
-	} else if err != nil {
-		return nil, err
+	update, err := m.updateDoc(entity)
+	if err != nil {
+		return store.NewError(backend, "Update", id, err)
 	}
 
-	return entity, nil
-}
-
-func (m *MongoStore[T]) Delete(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	res, err := m.collection.UpdateOne(ctx, bson.M{"_id": key}, update)
 	if err != nil {
-		return errors.New("invalid ID format")
+		return store.NewError(backend, "Update", id, writeError(err))
+	}
+	if res.MatchedCount == 0 {
+		return store.NewError(backend, "Update", id, store.ErrNotFound)
 	}
 
-	res, err := m.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	return nil
+}
+
+func (m *MongoStore[K, T]) Replace(ctx context.Context, id K, entity *T) error {
+	res, err := m.replace(ctx, "Replace", id, entity, false)
 	if err != nil {
 		return err
 	}
-	if res.DeletedCount == 0 {
-		return errors.New("entity not found")
+	if res.MatchedCount == 0 {
+		return store.NewError(backend, "Replace", id, store.ErrNotFound)
 	}
 
 	return nil
 }
 
-func (m *MongoStore[T]) Update(ctx context.Context, id string, entity *T) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+func (m *MongoStore[K, T]) Upsert(ctx context.Context, id K, entity *T) (bool, error) {
+	res, err := m.replace(ctx, "Upsert", id, entity, true)
 	if err != nil {
-		return errors.New("invalid ID format")
+		return false, err
 	}
 
-	res, err := m.collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": entity})
+	return res.UpsertedCount > 0, nil
+}
+
+// replace swaps the whole stored entity in a single command. A plain
+// ReplaceOne is enough for flat documents, but envelopes must keep their
+// created_at and versions must be incremented, which needs an update.
+func (m *MongoStore[K, T]) replace(ctx context.Context, op string, id K, entity *T, upsert bool) (*mongo.UpdateResult, error) {
+	key, err := m.encodeKey(op, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if res.MatchedCount == 0 {
-		return errors.New("entity not found")
+
+	now := time.Now()
+	filter := bson.M{"_id": key}
+
+	var res *mongo.UpdateResult
+	switch {
+	case m.mapping == EnvelopeDocument:
+		var update bson.D
+		update, err = m.updateDoc(entity)
+		if err != nil {
+			return nil, store.NewError(backend, op, id, err)
+		}
+		update = append(update, bson.E{Key: "$setOnInsert", Value: bson.D{{Key: createdAtField, Value: now}}})
+		res, err = m.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(upsert))
+	case m.version != "":
+		var doc bson.D
+		doc, err = m.mapping.document(key, entity, now)
+		if err != nil {
+			return nil, store.NewError(backend, op, id, err)
+		}
+		// $literal keeps entity strings starting with $ from being read as
+		// field paths, while $version still refers to the stored document.
+		pipeline := mongo.Pipeline{{{Key: "$replaceWith", Value: bson.M{"$mergeObjects": bson.A{
+			bson.M{"$literal": withoutField(doc, m.version)},
+			bson.M{m.version: bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$" + m.version, 0}}, 1}}},
+		}}}}}
+		res, err = m.collection.UpdateOne(ctx, filter, pipeline, options.Update().SetUpsert(upsert))
+	default:
+		var doc bson.D
+		doc, err = m.mapping.document(key, entity, now)
+		if err != nil {
+			return nil, store.NewError(backend, op, id, err)
+		}
+		res, err = m.collection.ReplaceOne(ctx, filter, doc, options.Replace().SetUpsert(upsert))
+	}
+	if err != nil {
+		return nil, store.NewError(backend, op, id, writeError(err))
 	}
 
-	return nil
+	return res, nil
 }
 
-func (m *MongoStore[T]) ExecuteQuery(ctx context.Context, filter bson.M) ([]*T, error) {
-	cursor, err := m.collection.Find(ctx, filter)
+func (m *MongoStore[K, T]) ExecuteQuery(ctx context.Context, filter bson.M) ([]*T, error) {
+	return m.find(ctx, "ExecuteQuery", filter)
+}
+
+func (m *MongoStore[K, T]) ExecuteUpdate(ctx context.Context, f func(ctx context.Context, collection *mongo.Collection) (int, error)) (int, error) {
+	return f(ctx, m.collection)
+}
+
+func (m *MongoStore[K, T]) Find(ctx context.Context, filter query.Expr) ([]*T, error) {
+	compiled, err := compileFilter(filter, m.mapping)
+	if err != nil {
+		return nil, store.NewError(backend, "Find", nil, err)
+	}
+	return m.find(ctx, "Find", compiled)
+}
+
+func (m *MongoStore[K, T]) find(ctx context.Context, op string, filter bson.M) ([]*T, error) {
+	it, err := m.iterate(ctx, op, filter)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
 
-	var results []*T
-	for cursor.Next(ctx) {
-		var entity T
-		if err := cursor.Decode(&entity); err != nil {
-			return nil, err
-		}
-		results = append(results, &entity)
+	results, err := store.Collect(it)
+	if err != nil {
+		return nil, err
 	}
 
 	return results, nil
 }
 
-func (m *MongoStore[T]) ExecuteUpdate(ctx context.Context, f func(ctx context.Context, collection *mongo.Collection) (int, error)) (int, error) {
-	return f(ctx, m.collection)
+func (m *MongoStore[K, T]) decode(raw bson.Raw) (*T, error) {
+	var entity T
+	if err := m.mapping.decode(raw, &entity); err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+func (m *MongoStore[K, T]) encodeKey(op string, id K) (any, error) {
+	key, err := m.keys.EncodeKey(id)
+	if err != nil {
+		return nil, store.NewError(backend, op, id, fmt.Errorf("%w: %w", store.ErrInvalidID, err))
+	}
+	return key, nil
 }
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"testing"
 
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/query"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -84,6 +86,13 @@ func TestMongoStore_GetByID(t *testing.T) {
 		assert.Nil(mt, result)
 		assert.Error(mt, err)
 		assert.ErrorContains(t, err, "invalid ID format")
+		assert.ErrorIs(mt, err, gostore.ErrInvalidID)
+
+		var storeErr *gostore.Error
+		assert.ErrorAs(mt, err, &storeErr)
+		assert.Equal(mt, "GetByID", storeErr.Op)
+		assert.Equal(mt, "1", storeErr.Key)
+		assert.Equal(mt, "mongo", storeErr.Backend)
 	})
 }
 
@@ -96,7 +105,7 @@ func TestMongoStore_GetMultipleByID(t *testing.T) {
 		stringObjectID2 := primitive.NewObjectID().Hex()
 
 		// Simular respuesta para Find
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, "foo.bar", mtest.FirstBatch,
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
 			bson.D{{Key: "_id", Value: stringObjectID1}, {Key: "value", Value: "value-1"}},
 			bson.D{{Key: "_id", Value: stringObjectID2}, {Key: "value", Value: "value-2"}},
 		))
@@ -122,7 +131,7 @@ func TestMongoStore_GetMultipleByID(t *testing.T) {
 		// Validar resultados
 		assert.Nil(mt, result)
 		assert.Error(mt, err)
-		assert.Equal(mt, "invalid ID format", err.Error())
+		assert.ErrorIs(mt, err, gostore.ErrInvalidID)
 	})
 
 	mt.Run("No documents found", func(mt *mtest.T) {
@@ -130,7 +139,7 @@ func TestMongoStore_GetMultipleByID(t *testing.T) {
 		stringObjectID1 := primitive.NewObjectID().Hex()
 
 		// Simular respuesta vacía para Find
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, "foo.bar", mtest.FirstBatch))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch))
 
 		// Ejecutar el método
 		ids := []string{stringObjectID1}
@@ -173,6 +182,11 @@ func TestMongoStore_Insert(t *testing.T) {
 		assert.Nil(mt, result)
 		assert.Error(mt, err)
 		assert.Contains(mt, err.Error(), "already existing key")
+		assert.ErrorIs(mt, err, gostore.ErrAlreadyExists)
+
+		var serverErr mongo.ServerError
+		assert.ErrorAs(mt, err, &serverErr)
+		assert.True(mt, serverErr.HasErrorCode(11000))
 	})
 
 	mt.Run("Invalid ID format", func(mt *mtest.T) {
@@ -269,13 +283,111 @@ func TestMongoStore_Update(t *testing.T) {
 	})
 }
 
+func TestMongoStore_Replace(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Replace existing entity", func(mt *mtest.T) {
+		id := primitive.NewObjectID()
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(updateResponse(1))
+		err := store.Replace(context.Background(), id.Hex(), &TestEntity{Value: "replaced"})
+		assert.NoError(mt, err)
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(mt, id, update.Lookup("u", "_id").ObjectID())
+		assert.Equal(mt, "replaced", update.Lookup("u", "value").StringValue())
+		assert.False(mt, update.Lookup("upsert").Boolean())
+	})
+
+	mt.Run("Replace non-existent entity", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(updateResponse(0))
+		err := store.Replace(context.Background(), primitive.NewObjectID().Hex(), &TestEntity{})
+		assert.ErrorIs(mt, err, gostore.ErrNotFound)
+	})
+
+	mt.Run("Invalid ID format", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+		err := store.Replace(context.Background(), "1", &TestEntity{})
+		assert.ErrorIs(mt, err, gostore.ErrInvalidID)
+	})
+}
+
+func TestMongoStore_Upsert(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Upsert creates the entity", func(mt *mtest.T) {
+		id := primitive.NewObjectID()
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+			bson.E{Key: "nModified", Value: 0},
+			bson.E{Key: "upserted", Value: bson.A{bson.D{{Key: "index", Value: 0}, {Key: "_id", Value: id}}}},
+		))
+		created, err := store.Upsert(context.Background(), id.Hex(), &TestEntity{Value: "created"})
+		assert.NoError(mt, err)
+		assert.True(mt, created)
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(mt, id, update.Lookup("q", "_id").ObjectID())
+		assert.True(mt, update.Lookup("upsert").Boolean())
+	})
+
+	mt.Run("Upsert overwrites the entity", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(updateResponse(1))
+		created, err := store.Upsert(context.Background(), primitive.NewObjectID().Hex(), &TestEntity{Value: "overwritten"})
+		assert.NoError(mt, err)
+		assert.False(mt, created)
+	})
+
+	mt.Run("Envelope keeps created_at", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithMapping(EnvelopeDocument))
+
+		mt.AddMockResponses(updateResponse(1))
+		_, err := store.Upsert(context.Background(), primitive.NewObjectID().Hex(), &TestEntity{Value: "overwritten"})
+		assert.NoError(mt, err)
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u").Document()
+		assert.Equal(mt, "overwritten", update.Lookup("$set", "data", "value").StringValue())
+		_, err = update.LookupErr("$setOnInsert", "created_at")
+		assert.NoError(mt, err)
+	})
+
+	mt.Run("Versioned upsert increments the version", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithVersionField("version"))
+
+		mt.AddMockResponses(updateResponse(1))
+		_, err := store.Upsert(context.Background(), primitive.NewObjectID().Hex(), &TestEntity{Value: "$value"})
+		assert.NoError(mt, err)
+
+		pipeline := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u").Array()
+		merge := pipeline.Index(0).Value().Document().Lookup("$replaceWith", "$mergeObjects").Array()
+		assert.Equal(mt, "$value", merge.Index(0).Value().Document().Lookup("$literal", "value").StringValue())
+		_, err = merge.Index(1).Value().Document().LookupErr("version", "$add")
+		assert.NoError(mt, err)
+	})
+
+	mt.Run("Upsert error", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}, {Key: "errmsg", Value: "failure"}})
+		_, err := store.Upsert(context.Background(), primitive.NewObjectID().Hex(), &TestEntity{})
+		assert.Error(mt, err)
+	})
+}
+
 func TestMongoStore_GetAll(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 
 	mt.Run("Get all entities", func(mt *mtest.T) {
 		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
 
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, "foo.bar", mtest.FirstBatch,
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
 			bson.D{{Key: "_id", Value: "1"}, {Key: "value", Value: "value-1"}},
 			bson.D{{Key: "_id", Value: "2"}, {Key: "value", Value: "value-2"}},
 		))
@@ -295,7 +407,7 @@ func TestMongoStore_ExecuteQuery(t *testing.T) {
 		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
 
 		// Simular respuesta para Find con resultados coincidentes
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, "foo.bar", mtest.FirstBatch,
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
 			bson.D{{Key: "_id", Value: "1"}, {Key: "value", Value: "value-1"}},
 			bson.D{{Key: "_id", Value: "2"}, {Key: "value", Value: "value-2"}},
 		))
@@ -315,7 +427,7 @@ func TestMongoStore_ExecuteQuery(t *testing.T) {
 		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
 
 		// Simular respuesta vacía para Find
-		mt.AddMockResponses(mtest.CreateCursorResponse(1, "foo.bar", mtest.FirstBatch))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch))
 
 		// Ejecutar el método
 		filter := bson.M{"value": "non-existent"}
@@ -372,3 +484,70 @@ func TestExecuteUpdate(t *testing.T) {
 		assert.ErrorContains(t, err, "update error")
 	})
 }
+
+func TestKeyedMongoStore(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Integer keys are stored as _id", func(mt *mtest.T) {
+		store := NewKeyedMongoStore[int64, TestEntity](mt.DB, "foo.bar", IntCodec[int64]())
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		_, err := store.Insert(context.Background(), 42, &TestEntity{Value: "test-value"})
+		assert.NoError(mt, err)
+
+		doc := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(mt, int64(42), doc.Lookup("_id").Int64())
+	})
+
+	mt.Run("Plain string keys", func(mt *mtest.T) {
+		store := NewKeyedMongoStore[string, TestEntity](mt.DB, "foo.bar", StringCodec())
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "foo.bar", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "car-1"},
+			{Key: "value", Value: "test-value"},
+		}))
+
+		result, err := store.GetByID(context.Background(), "car-1")
+		assert.NoError(mt, err)
+		assert.Equal(mt, &TestEntity{ID: "car-1", Value: "test-value"}, result)
+
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(mt, "car-1", filter.Lookup("_id").StringValue())
+	})
+
+	mt.Run("Invalid UUID key", func(mt *mtest.T) {
+		store := NewKeyedMongoStore[string, TestEntity](mt.DB, "foo.bar", UUIDCodec())
+
+		err := store.Delete(context.Background(), "not-a-uuid")
+		assert.ErrorIs(mt, err, gostore.ErrInvalidID)
+	})
+}
+
+func TestMongoStore_Find(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Find with matching results", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "1"}, {Key: "value", Value: "value-1"}},
+		))
+
+		result, err := store.Find(context.Background(), query.Prefix("value", "value"))
+		assert.NoError(mt, err)
+		assert.Len(mt, result, 1)
+		assert.Equal(mt, "value-1", result[0].Value)
+
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(mt, "^value", filter.Lookup("value", "$regex").StringValue())
+	})
+
+	mt.Run("Find with invalid expression", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		result, err := store.Find(context.Background(), query.CompareExpr{Field: "value", Op: "like"})
+		assert.Nil(mt, result)
+		assert.Error(mt, err)
+	})
+}
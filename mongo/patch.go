@@ -0,0 +1,138 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	store "github.com/Silencevoice/go-store"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Patch translates the field operations into a single $set, $unset and $inc
+// update, so concurrent patches on different fields do not overwrite each
+// other. Patches making a field an object need to look at its current value,
+// so they are applied as an update pipeline instead.
+func (m *MongoStore[K, T]) Patch(ctx context.Context, id K, patch store.Patch) error {
+	key, err := m.encodeKey("Patch", id)
+	if err != nil {
+		return err
+	}
+
+	ops, err := patch.Ops()
+	if err == nil {
+		err = m.checkPatch(ops)
+	}
+	if err != nil {
+		return store.NewError(backend, "Patch", id, fmt.Errorf("%w: %w", store.ErrInvalidPatch, err))
+	}
+
+	if len(ops) == 0 {
+		count, err := m.collection.CountDocuments(ctx, bson.M{"_id": key})
+		if err != nil {
+			return store.NewError(backend, "Patch", id, err)
+		}
+		if count == 0 {
+			return store.NewError(backend, "Patch", id, store.ErrNotFound)
+		}
+		return nil
+	}
+
+	res, err := m.collection.UpdateOne(ctx, bson.M{"_id": key}, m.patchUpdate(ops))
+	if err != nil {
+		return store.NewError(backend, "Patch", id, writeError(err))
+	}
+	if res.MatchedCount == 0 {
+		return store.NewError(backend, "Patch", id, store.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (m *MongoStore[K, T]) checkPatch(ops []store.FieldOp) error {
+	for _, op := range ops {
+		if op.Field == "_id" || op.Field == m.version {
+			return fmt.Errorf("field %q cannot be patched", op.Field)
+		}
+		switch op.Op {
+		case store.PatchSet, store.PatchUnset, store.PatchInc, store.PatchEnsureObject:
+		default:
+			return fmt.Errorf("unsupported patch operation %q", op.Op)
+		}
+	}
+	return store.CheckOps(ops)
+}
+
+func (m *MongoStore[K, T]) patchUpdate(ops []store.FieldOp) any {
+	for _, op := range ops {
+		if op.Op == store.PatchEnsureObject {
+			return m.patchPipeline(ops)
+		}
+	}
+
+	set, unset, inc := bson.D{}, bson.D{}, bson.D{}
+	for _, op := range ops {
+		path := m.mapping.path(op.Field)
+		switch op.Op {
+		case store.PatchSet:
+			set = append(set, bson.E{Key: path, Value: op.Value})
+		case store.PatchUnset:
+			unset = append(unset, bson.E{Key: path, Value: ""})
+		case store.PatchInc:
+			inc = append(inc, bson.E{Key: path, Value: op.Value})
+		}
+	}
+	if m.mapping == EnvelopeDocument {
+		set = append(set, bson.E{Key: updatedAtField, Value: time.Now()})
+	}
+	if m.version != "" {
+		inc = append(inc, bson.E{Key: m.version, Value: int64(1)})
+	}
+
+	update := bson.D{}
+	for _, op := range []bson.E{{Key: "$set", Value: set}, {Key: "$unset", Value: unset}, {Key: "$inc", Value: inc}} {
+		if len(op.Value.(bson.D)) > 0 {
+			update = append(update, op)
+		}
+	}
+	return update
+}
+
+// patchPipeline is the update of patchUpdate as a pipeline, whose values are
+// expressions: the set values are wrapped in $literal, so strings starting
+// with $ are not taken for fields.
+func (m *MongoStore[K, T]) patchPipeline(ops []store.FieldOp) bson.A {
+	set, unset := bson.D{}, bson.A{}
+	for _, op := range ops {
+		path := m.mapping.path(op.Field)
+		switch op.Op {
+		case store.PatchSet:
+			set = append(set, bson.E{Key: path, Value: bson.M{"$literal": op.Value}})
+		case store.PatchUnset:
+			unset = append(unset, path)
+		case store.PatchInc:
+			set = append(set, bson.E{Key: path, Value: increment(path, op.Value)})
+		case store.PatchEnsureObject:
+			set = append(set, bson.E{Key: path, Value: bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{bson.M{"$type": "$" + path}, "object"}}, "$" + path, bson.M{"$literal": bson.M{}},
+			}}})
+		}
+	}
+	if m.mapping == EnvelopeDocument {
+		set = append(set, bson.E{Key: updatedAtField, Value: bson.M{"$literal": time.Now()}})
+	}
+	if m.version != "" {
+		set = append(set, bson.E{Key: m.version, Value: increment(m.version, int64(1))})
+	}
+
+	pipeline := bson.A{bson.D{{Key: "$set", Value: set}}}
+	if len(unset) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$unset", Value: unset}})
+	}
+	return pipeline
+}
+
+// increment is $inc as an expression, counting a missing field as 0.
+func increment(path string, by any) bson.M {
+	return bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$" + path, 0}}, by}}
+}
@@ -0,0 +1,107 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+var _ gostore.Patcher[string] = (*MongoStore[string, TestEntity])(nil)
+
+func TestMongoStore_Patch(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Field patch", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(updateResponse(1))
+		err := store.Patch(context.Background(), primitive.NewObjectID().Hex(),
+			gostore.Fields(gostore.Set("price", 100), gostore.Unset("color"), gostore.Inc("mileage", 10)))
+		require.NoError(mt, err)
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u").Document()
+		assert.Equal(mt, int32(100), update.Lookup("$set", "price").Int32())
+		assert.Equal(mt, "", update.Lookup("$unset", "color").StringValue())
+		assert.Equal(mt, int32(10), update.Lookup("$inc", "mileage").Int32())
+	})
+
+	mt.Run("Merge patch in an envelope", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithMapping(EnvelopeDocument), WithVersionField("version"))
+
+		mt.AddMockResponses(updateResponse(1))
+		err := store.Patch(context.Background(), primitive.NewObjectID().Hex(),
+			gostore.MergePatch(`{"value": "patched", "engine": {"size": 1.6, "fuel": null}}`))
+		require.NoError(mt, err)
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u").Document()
+		set := update.Lookup("$set").Document()
+		assert.Equal(mt, "patched", set.Lookup("data.value").StringValue())
+		assert.Equal(mt, 1.6, set.Lookup("data.engine.size").Double())
+		_, err = set.LookupErr("updated_at")
+		assert.NoError(mt, err)
+		assert.Equal(mt, "", update.Lookup("$unset", "data.engine.fuel").StringValue())
+		assert.Equal(mt, int64(1), update.Lookup("$inc", "version").Int64())
+	})
+
+	mt.Run("Empty objects use a pipeline", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithVersionField("version"))
+
+		mt.AddMockResponses(updateResponse(1))
+		err := store.Patch(context.Background(), primitive.NewObjectID().Hex(),
+			gostore.MergePatch(`{"engine": {}, "value": "$x", "color": null}`))
+		require.NoError(mt, err)
+
+		pipeline := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u").Array()
+		set := pipeline.Index(0).Value().Document().Lookup("$set").Document()
+		assert.Equal(mt, "$engine", set.Lookup("engine", "$cond").Array().Index(1).Value().StringValue())
+		assert.Equal(mt, "$x", set.Lookup("value", "$literal").StringValue())
+		assert.Equal(mt, "$version", set.Lookup("version", "$add").Array().Index(0).Value().Document().Lookup("$ifNull").Array().Index(0).Value().StringValue())
+		assert.Equal(mt, "color", pipeline.Index(1).Value().Document().Lookup("$unset").Array().Index(0).Value().StringValue())
+	})
+
+	mt.Run("Patch non-existent entity", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(updateResponse(0))
+		err := store.Patch(context.Background(), primitive.NewObjectID().Hex(), gostore.Fields(gostore.Set("value", "x")))
+		assert.ErrorIs(mt, err, gostore.ErrNotFound)
+	})
+
+	mt.Run("Empty patch checks the entity exists", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(countResponse(0))
+		err := store.Patch(context.Background(), primitive.NewObjectID().Hex(), gostore.MergePatch(`{}`))
+		assert.ErrorIs(mt, err, gostore.ErrNotFound)
+	})
+
+	mt.Run("Invalid patches", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+		id := primitive.NewObjectID().Hex()
+
+		err := store.Patch(context.Background(), id, gostore.MergePatch(`[]`))
+		assert.ErrorIs(mt, err, gostore.ErrInvalidPatch)
+		err = store.Patch(context.Background(), id, gostore.Fields(gostore.Set("_id", "x")))
+		assert.ErrorIs(mt, err, gostore.ErrInvalidPatch)
+		err = store.Patch(context.Background(), id, gostore.Fields(gostore.FieldOp{Op: "push", Field: "tags"}))
+		assert.ErrorIs(mt, err, gostore.ErrInvalidPatch)
+		err = store.Patch(context.Background(), id, gostore.Fields(gostore.Set("value", "x"), gostore.Unset("value")))
+		assert.ErrorIs(mt, err, gostore.ErrInvalidPatch)
+		err = store.Patch(context.Background(), id, gostore.Fields(gostore.Unset("engine"), gostore.Inc("engine.size", 1)))
+		assert.ErrorIs(mt, err, gostore.ErrInvalidPatch)
+	})
+
+	mt.Run("Update error", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}, {Key: "errmsg", Value: "failure"}})
+		err := store.Patch(context.Background(), primitive.NewObjectID().Hex(), gostore.Fields(gostore.Set("value", "x")))
+		assert.Error(mt, err)
+	})
+}
@@ -0,0 +1,81 @@
+package mongo
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/Silencevoice/go-store/query"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var compareOperators = map[query.Op]string{
+	query.OpEq:  "$eq",
+	query.OpNe:  "$ne",
+	query.OpGt:  "$gt",
+	query.OpGte: "$gte",
+	query.OpLt:  "$lt",
+	query.OpLte: "$lte",
+}
+
+// compileFilter translates a query expression into a MongoDB filter over the
+// documents laid out by mapping.
+func compileFilter(expr query.Expr, mapping Mapping) (bson.M, error) {
+	switch e := expr.(type) {
+	case nil:
+		return bson.M{}, nil
+	case query.CompareExpr:
+		operator, ok := compareOperators[e.Op]
+		if !ok {
+			return nil, fmt.Errorf("unsupported query operator %q", e.Op)
+		}
+		return bson.M{mapping.path(e.Field): bson.M{operator: e.Value}}, nil
+	case query.InExpr:
+		values := e.Values
+		if values == nil {
+			values = []any{}
+		}
+		return bson.M{mapping.path(e.Field): bson.M{"$in": values}}, nil
+	case query.AndExpr:
+		if len(e.Exprs) == 0 {
+			return bson.M{}, nil
+		}
+		filters, err := compileFilters(e.Exprs, mapping)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$and": filters}, nil
+	case query.OrExpr:
+		if len(e.Exprs) == 0 {
+			return bson.M{"$expr": false}, nil
+		}
+		filters, err := compileFilters(e.Exprs, mapping)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$or": filters}, nil
+	case query.NotExpr:
+		filter, err := compileFilter(e.Expr, mapping)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$nor": bson.A{filter}}, nil
+	case query.ExistsExpr:
+		return bson.M{mapping.path(e.Field): bson.M{"$exists": true}}, nil
+	case query.PrefixExpr:
+		return bson.M{mapping.path(e.Field): bson.M{"$regex": "^" + regexp.QuoteMeta(e.Prefix)}}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported query expression %T", expr)
+}
+
+func compileFilters(exprs []query.Expr, mapping Mapping) (bson.A, error) {
+	filters := bson.A{}
+	for _, expr := range exprs {
+		filter, err := compileFilter(expr, mapping)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
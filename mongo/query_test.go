@@ -0,0 +1,59 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/Silencevoice/go-store/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCompileFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     query.Expr
+		expected bson.M
+	}{
+		{"Nil", nil, bson.M{}},
+		{"Eq", query.Eq("model", "Corolla"), bson.M{"model": bson.M{"$eq": "Corolla"}}},
+		{"Ne", query.Ne("model", "Corolla"), bson.M{"model": bson.M{"$ne": "Corolla"}}},
+		{"Gt", query.Gt("year", 2019), bson.M{"year": bson.M{"$gt": 2019}}},
+		{"Gte", query.Gte("year", 2019), bson.M{"year": bson.M{"$gte": 2019}}},
+		{"Lt", query.Lt("year", 2019), bson.M{"year": bson.M{"$lt": 2019}}},
+		{"Lte", query.Lte("year", 2019), bson.M{"year": bson.M{"$lte": 2019}}},
+		{"In", query.In("model", "Corolla", "Yaris"), bson.M{"model": bson.M{"$in": []any{"Corolla", "Yaris"}}}},
+		{"Empty In", query.In("model"), bson.M{"model": bson.M{"$in": []any{}}}},
+		{"And", query.And(query.Eq("model", "Corolla"), query.Exists("vin")), bson.M{"$and": bson.A{
+			bson.M{"model": bson.M{"$eq": "Corolla"}},
+			bson.M{"vin": bson.M{"$exists": true}},
+		}}},
+		{"Empty And", query.And(), bson.M{}},
+		{"Or", query.Or(query.Eq("model", "Corolla")), bson.M{"$or": bson.A{bson.M{"model": bson.M{"$eq": "Corolla"}}}}},
+		{"Empty Or", query.Or(), bson.M{"$expr": false}},
+		{"Not", query.Not(query.Eq("model", "Corolla")), bson.M{"$nor": bson.A{bson.M{"model": bson.M{"$eq": "Corolla"}}}}},
+		{"Prefix", query.Prefix("vin", "WVW.1"), bson.M{"vin": bson.M{"$regex": `^WVW\.1`}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := compileFilter(tt.expr, FlatDocument)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, filter)
+		})
+	}
+
+	t.Run("Unsupported operator", func(t *testing.T) {
+		_, err := compileFilter(query.And(query.CompareExpr{Field: "model", Op: "like"}), FlatDocument)
+		assert.Error(t, err)
+	})
+}
+
+func TestCompileFilter_Envelope(t *testing.T) {
+	filter, err := compileFilter(query.And(query.Eq("model", "Corolla"), query.In("_id", "1", "2")), EnvelopeDocument)
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": bson.A{
+		bson.M{"data.model": bson.M{"$eq": "Corolla"}},
+		bson.M{"_id": bson.M{"$in": []any{"1", "2"}}},
+	}}, filter)
+}
@@ -0,0 +1,30 @@
+package mongo
+
+import (
+	"context"
+
+	store "github.com/Silencevoice/go-store"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WithTx runs fn in a driver session with WithTransaction, which commits
+// when fn returns nil and retries it on transient errors, so fn must be safe
+// to run more than once. Every MongoStore on the same client called with the
+// context fn receives takes part in the transaction, and calls to WithTx
+// inside fn join it.
+func (m *MongoStore[K, T]) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := m.collection.Database().Client().StartSession()
+	if err != nil {
+		return store.NewError(backend, "WithTx", nil, err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
@@ -0,0 +1,76 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+var _ gostore.Transactor = (*MongoStore[string, TestEntity])(nil)
+
+func TestMongoStore_WithTx(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Commit", func(mt *mtest.T) {
+		cars := NewMongoStore[TestEntity](mt.DB, "cars")
+		counters := NewMongoStore[TestEntity](mt.DB, "counters")
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), updateResponse(1), mtest.CreateSuccessResponse())
+		err := cars.WithTx(context.Background(), func(ctx context.Context) error {
+			if _, err := cars.Insert(ctx, primitive.NewObjectID().Hex(), &TestEntity{Value: "car"}); err != nil {
+				return err
+			}
+			return counters.Patch(ctx, primitive.NewObjectID().Hex(), gostore.Fields(gostore.Inc("count", 1)))
+		})
+		require.NoError(mt, err)
+
+		events := mt.GetAllStartedEvents()
+		require.Len(mt, events, 3)
+		assert.Equal(mt, "insert", events[0].CommandName)
+		assert.True(mt, events[0].Command.Lookup("startTransaction").Boolean())
+		assert.Equal(mt, "update", events[1].CommandName)
+		assert.Equal(mt, events[0].Command.Lookup("lsid"), events[1].Command.Lookup("lsid"))
+		assert.Equal(mt, "commitTransaction", events[2].CommandName)
+	})
+
+	mt.Run("Error rolls back", func(mt *mtest.T) {
+		cars := NewMongoStore[TestEntity](mt.DB, "cars")
+		abort := errors.New("abort")
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+		err := cars.WithTx(context.Background(), func(ctx context.Context) error {
+			if _, err := cars.Insert(ctx, primitive.NewObjectID().Hex(), &TestEntity{Value: "car"}); err != nil {
+				return err
+			}
+			return abort
+		})
+		assert.ErrorIs(mt, err, abort)
+
+		events := mt.GetAllStartedEvents()
+		require.Len(mt, events, 2)
+		assert.Equal(mt, "abortTransaction", events[1].CommandName)
+	})
+
+	mt.Run("Nested transactions join the outer one", func(mt *mtest.T) {
+		cars := NewMongoStore[TestEntity](mt.DB, "cars")
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+		err := cars.WithTx(context.Background(), func(ctx context.Context) error {
+			return cars.WithTx(ctx, func(ctx context.Context) error {
+				_, err := cars.Insert(ctx, primitive.NewObjectID().Hex(), &TestEntity{Value: "car"})
+				return err
+			})
+		})
+		require.NoError(mt, err)
+
+		events := mt.GetAllStartedEvents()
+		require.Len(mt, events, 2)
+		assert.Equal(mt, "commitTransaction", events[1].CommandName)
+	})
+}
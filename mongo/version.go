@@ -0,0 +1,116 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	store "github.com/Silencevoice/go-store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetWithVersion needs the WithVersionField option. Documents written before
+// versioning was enabled have version 0.
+func (m *MongoStore[K, T]) GetWithVersion(ctx context.Context, id K) (*T, int64, error) {
+	if m.version == "" {
+		return nil, 0, store.NewError(backend, "GetWithVersion", id, errors.ErrUnsupported)
+	}
+
+	key, err := m.encodeKey("GetWithVersion", id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	raw, err := m.collection.FindOne(ctx, bson.M{"_id": key}).Raw()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, 0, store.NewError(backend, "GetWithVersion", id, store.ErrNotFound)
+	} else if err != nil {
+		return nil, 0, store.NewError(backend, "GetWithVersion", id, err)
+	}
+
+	result, err := m.decode(raw)
+	if err != nil {
+		return nil, 0, store.NewError(backend, "GetWithVersion", id, err)
+	}
+
+	var version int64
+	if value, err := raw.LookupErr(m.version); err == nil {
+		var ok bool
+		if version, ok = value.AsInt64OK(); !ok {
+			return nil, 0, store.NewError(backend, "GetWithVersion", id, errors.New("version field is not a number"))
+		}
+	}
+
+	return result, version, nil
+}
+
+// UpdateIfVersion only updates the document when its version field still
+// holds version. When nothing matches, a second query tells a missing
+// document from a conflict.
+func (m *MongoStore[K, T]) UpdateIfVersion(ctx context.Context, id K, version int64, entity *T) error {
+	if m.version == "" {
+		return store.NewError(backend, "UpdateIfVersion", id, errors.ErrUnsupported)
+	}
+
+	key, err := m.encodeKey("UpdateIfVersion", id)
+	if err != nil {
+		return err
+	}
+
+	update, err := m.updateDoc(entity)
+	if err != nil {
+		return store.NewError(backend, "UpdateIfVersion", id, err)
+	}
+
+	filter := bson.M{"_id": key, m.version: version}
+	if version == 0 {
+		filter[m.version] = nil
+	}
+
+	res, err := m.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return store.NewError(backend, "UpdateIfVersion", id, writeError(err))
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+
+	count, err := m.collection.CountDocuments(ctx, bson.M{"_id": key})
+	if err != nil {
+		return store.NewError(backend, "UpdateIfVersion", id, err)
+	}
+	if count == 0 {
+		return store.NewError(backend, "UpdateIfVersion", id, store.ErrNotFound)
+	}
+	return store.NewError(backend, "UpdateIfVersion", id, store.ErrVersionConflict)
+}
+
+// updateDoc builds the update overwriting an entity, incrementing its version
+// when versioning is enabled.
+func (m *MongoStore[K, T]) updateDoc(entity *T) (bson.D, error) {
+	set, err := m.mapping.update(entity, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if m.version == "" {
+		return bson.D{{Key: "$set", Value: set}}, nil
+	}
+
+	return bson.D{
+		{Key: "$set", Value: withoutField(set, m.version)},
+		{Key: "$inc", Value: bson.D{{Key: m.version, Value: int64(1)}}},
+	}, nil
+}
+
+// withoutField removes field from doc, so an entity field sharing its name
+// with the version field cannot overwrite it.
+func withoutField(doc bson.D, field string) bson.D {
+	result := bson.D{}
+	for _, elem := range doc {
+		if elem.Key != field {
+			result = append(result, elem)
+		}
+	}
+	return result
+}
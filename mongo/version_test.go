@@ -0,0 +1,121 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+var _ gostore.Versioned[string, TestEntity] = (*MongoStore[string, TestEntity])(nil)
+
+func updateResponse(n int32) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}, bson.E{Key: "nModified", Value: n})
+}
+
+func TestMongoStore_Versions(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Versioning disabled", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar")
+		id := primitive.NewObjectID().Hex()
+
+		_, _, err := store.GetWithVersion(context.Background(), id)
+		assert.ErrorIs(mt, err, errors.ErrUnsupported)
+		err = store.UpdateIfVersion(context.Background(), id, 1, &TestEntity{})
+		assert.ErrorIs(mt, err, errors.ErrUnsupported)
+	})
+
+	mt.Run("Insert starts at version 1", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithMapping(EnvelopeDocument), WithVersionField("version"))
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		_, err := store.Insert(context.Background(), primitive.NewObjectID().Hex(), &TestEntity{Value: "test-value"})
+		require.NoError(mt, err)
+
+		doc := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(mt, int64(1), doc.Lookup("version").Int64())
+	})
+
+	mt.Run("Get with version", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithVersionField("version"))
+		id := primitive.NewObjectID()
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: id},
+			{Key: "value", Value: "test-value"},
+			{Key: "version", Value: int32(3)},
+		}))
+
+		result, version, err := store.GetWithVersion(context.Background(), id.Hex())
+		require.NoError(mt, err)
+		assert.Equal(mt, &TestEntity{ID: id.Hex(), Value: "test-value"}, result)
+		assert.Equal(mt, int64(3), version)
+	})
+
+	mt.Run("Documents without version", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithVersionField("version"))
+		id := primitive.NewObjectID()
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "foo.bar", mtest.FirstBatch, bson.D{{Key: "_id", Value: id}}))
+		_, version, err := store.GetWithVersion(context.Background(), id.Hex())
+		require.NoError(mt, err)
+		assert.Equal(mt, int64(0), version)
+		mt.GetStartedEvent()
+
+		mt.AddMockResponses(updateResponse(1))
+		err = store.UpdateIfVersion(context.Background(), id.Hex(), 0, &TestEntity{})
+		require.NoError(mt, err)
+
+		filter := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("q").Document()
+		assert.Equal(mt, bson.TypeNull, filter.Lookup("version").Type)
+	})
+
+	mt.Run("Update if version", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithVersionField("version"))
+		id := primitive.NewObjectID()
+
+		mt.AddMockResponses(updateResponse(1))
+		err := store.UpdateIfVersion(context.Background(), id.Hex(), 2, &TestEntity{Value: "updated"})
+		require.NoError(mt, err)
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(mt, id, update.Lookup("q", "_id").ObjectID())
+		assert.Equal(mt, int64(2), update.Lookup("q", "version").Int64())
+		assert.Equal(mt, "updated", update.Lookup("u", "$set", "value").StringValue())
+		assert.Equal(mt, int64(1), update.Lookup("u", "$inc", "version").Int64())
+	})
+
+	mt.Run("Stale version conflicts", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithVersionField("version"))
+
+		mt.AddMockResponses(updateResponse(0), countResponse(1))
+		err := store.UpdateIfVersion(context.Background(), primitive.NewObjectID().Hex(), 1, &TestEntity{})
+		assert.ErrorIs(mt, err, gostore.ErrVersionConflict)
+	})
+
+	mt.Run("Missing entity", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithVersionField("version"))
+
+		mt.AddMockResponses(updateResponse(0), countResponse(0))
+		err := store.UpdateIfVersion(context.Background(), primitive.NewObjectID().Hex(), 1, &TestEntity{})
+		assert.ErrorIs(mt, err, gostore.ErrNotFound)
+	})
+
+	mt.Run("Update increments the version", func(mt *mtest.T) {
+		store := NewMongoStore[TestEntity](mt.DB, "foo.bar", WithVersionField("version"))
+
+		mt.AddMockResponses(updateResponse(1))
+		err := store.Update(context.Background(), primitive.NewObjectID().Hex(), &TestEntity{Value: "updated"})
+		require.NoError(mt, err)
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(mt, int64(1), update.Lookup("u", "$inc", "version").Int64())
+	})
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+
+	"github.com/Silencevoice/go-store/query"
+)
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+func Asc(field string) SortField {
+	return SortField{Field: field}
+}
+
+func Desc(field string) SortField {
+	return SortField{Field: field, Desc: true}
+}
+
+// FindOptions controls the slice of results returned by List. Results are
+// ordered by the Sort fields and then by key, so every backend returns the
+// same order for the same options.
+//
+// Cursor is the opaque NextCursor of a previous page and resumes right after
+// its last item (keyset pagination). Cursors are only valid for the backend
+// and sort fields that produced them. Offset skips items after the cursor
+// position, and a zero Limit returns every remaining item.
+type FindOptions struct {
+	Limit  int
+	Offset int
+	Sort   []SortField
+	Cursor string
+}
+
+// Page holds one page of List results. Total counts every entity matching
+// the filter, and NextCursor is empty when there are no more items.
+type Page[T any] struct {
+	Items      []*T
+	Total      int64
+	NextCursor string
+}
+
+type Lister[T any] interface {
+	List(ctx context.Context, filter query.Expr, opts FindOptions) (*Page[T], error)
+}
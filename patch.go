@@ -0,0 +1,166 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type PatchOp string
+
+const (
+	PatchSet   PatchOp = "set"
+	PatchUnset PatchOp = "unset"
+	PatchInc   PatchOp = "inc"
+	// PatchEnsureObject makes the field an empty object unless it is one
+	// already, which is what a merge patch does with an empty object.
+	PatchEnsureObject PatchOp = "ensureObject"
+)
+
+// FieldOp changes a single field. Fields are named and nested with dots as
+// in the query expressions.
+type FieldOp struct {
+	Op    PatchOp
+	Field string
+	Value any
+}
+
+func Set(field string, value any) FieldOp {
+	return FieldOp{Op: PatchSet, Field: field, Value: value}
+}
+
+// Unset removes the field from the document, or sets it to its zero value
+// when the backend keeps the entity as a struct.
+func Unset(field string) FieldOp {
+	return FieldOp{Op: PatchUnset, Field: field}
+}
+
+func Inc(field string, by any) FieldOp {
+	return FieldOp{Op: PatchInc, Field: field, Value: by}
+}
+
+func EnsureObject(field string) FieldOp {
+	return FieldOp{Op: PatchEnsureObject, Field: field}
+}
+
+// CheckOps fails when two operations change the same field, or one changes
+// a field nested in the other. Mongo cannot apply them in a single update,
+// so every backend rejects them, instead of applying them in order where it
+// could.
+func CheckOps(ops []FieldOp) error {
+	for i, a := range ops {
+		for _, b := range ops[:i] {
+			if overlaps(a.Field, b.Field) {
+				return fmt.Errorf("operations on %q and %q conflict", b.Field, a.Field)
+			}
+		}
+	}
+	return nil
+}
+
+func overlaps(a, b string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	return a == b || strings.HasPrefix(b, a+".")
+}
+
+// Patch is a partial update applied by Patcher stores. Backends only
+// understand field operations, so every patch translates itself into them.
+type Patch interface {
+	Ops() ([]FieldOp, error)
+}
+
+// FieldPatch applies its operations, each on a different field:
+//
+//	repo.Patch(ctx, id, store.Fields(store.Set("price", 100), store.Unset("color"), store.Inc("mileage", 10)))
+type FieldPatch []FieldOp
+
+func Fields(ops ...FieldOp) FieldPatch {
+	return FieldPatch(ops)
+}
+
+func (p FieldPatch) Ops() ([]FieldOp, error) {
+	return p, nil
+}
+
+// MergePatch is a JSON Merge Patch document (RFC 7396): null members remove
+// the field, objects are merged recursively and any other value replaces the
+// field. An empty object turns the field into one, unless it is an object
+// already.
+type MergePatch []byte
+
+func (p MergePatch) Ops() ([]FieldOp, error) {
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.UseNumber()
+
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	object, ok := doc.(map[string]any)
+	if !ok {
+		return nil, errors.New("merge patch is not a JSON object")
+	}
+
+	return mergeOps(nil, "", object), nil
+}
+
+func mergeOps(ops []FieldOp, prefix string, object map[string]any) []FieldOp {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field := prefix + name
+		switch value := object[name].(type) {
+		case nil:
+			ops = append(ops, Unset(field))
+		case map[string]any:
+			if len(value) == 0 {
+				ops = append(ops, EnsureObject(field))
+			} else {
+				ops = mergeOps(ops, field+".", value)
+			}
+		default:
+			ops = append(ops, Set(field, jsonValue(value)))
+		}
+	}
+	return ops
+}
+
+// jsonValue turns the json.Number values decoded from a patch into int64 or
+// float64, so backends receive plain Go numbers.
+func jsonValue(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		values := make([]any, len(v))
+		for i, elem := range v {
+			values[i] = jsonValue(elem)
+		}
+		return values
+	case map[string]any:
+		values := make(map[string]any, len(v))
+		for name, elem := range v {
+			values[name] = jsonValue(elem)
+		}
+		return values
+	}
+	return value
+}
+
+type Patcher[K comparable] interface {
+	Patch(ctx context.Context, id K, patch Patch) error
+}
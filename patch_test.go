@@ -0,0 +1,55 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatch(t *testing.T) {
+	t.Run("Translates into field operations", func(t *testing.T) {
+		ops, err := MergePatch(`{"price": 100, "color": null, "engine": {"size": 1.6, "fuel": null}, "tags": ["a", 1]}`).Ops()
+		require.NoError(t, err)
+		assert.Equal(t, []FieldOp{
+			Unset("color"),
+			Unset("engine.fuel"),
+			Set("engine.size", 1.6),
+			Set("price", int64(100)),
+			Set("tags", []any{"a", int64(1)}),
+		}, ops)
+	})
+
+	t.Run("Empty objects", func(t *testing.T) {
+		ops, err := MergePatch(`{"engine": {}, "extra": {"a": {}}}`).Ops()
+		require.NoError(t, err)
+		assert.Equal(t, []FieldOp{EnsureObject("engine"), EnsureObject("extra.a")}, ops)
+	})
+
+	t.Run("Invalid JSON", func(t *testing.T) {
+		_, err := MergePatch(`{"price":`).Ops()
+		assert.Error(t, err)
+	})
+
+	t.Run("Not an object", func(t *testing.T) {
+		_, err := MergePatch(`[1, 2]`).Ops()
+		assert.Error(t, err)
+	})
+}
+
+func TestFieldPatch(t *testing.T) {
+	ops, err := Fields(Set("price", 100), Unset("color"), Inc("mileage", 10)).Ops()
+	require.NoError(t, err)
+	assert.Equal(t, []FieldOp{
+		{Op: PatchSet, Field: "price", Value: 100},
+		{Op: PatchUnset, Field: "color"},
+		{Op: PatchInc, Field: "mileage", Value: 10},
+	}, ops)
+}
+
+func TestCheckOps(t *testing.T) {
+	assert.NoError(t, CheckOps([]FieldOp{Set("engine.size", 1.6), Set("engine.power", 100), Set("engineer", "x")}))
+	assert.Error(t, CheckOps([]FieldOp{Set("price", 100), Unset("price")}))
+	assert.Error(t, CheckOps([]FieldOp{Unset("engine"), Set("engine.size", 1.6)}))
+	assert.Error(t, CheckOps([]FieldOp{Inc("engine.size", 1), EnsureObject("engine")}))
+}
@@ -0,0 +1,112 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Silencevoice/go-store/internal/fields"
+)
+
+// Match evaluates expr against an entity in memory, following the MongoDB
+// semantics for missing fields and list values. A nil expr matches
+// everything.
+func Match(expr Expr, entity any) (bool, error) {
+	switch e := expr.(type) {
+	case nil:
+		return true, nil
+	case CompareExpr:
+		value, found := fields.Lookup(entity, e.Field)
+		return matchCompare(e.Op, value, found, e.Value)
+	case InExpr:
+		value, found := fields.Lookup(entity, e.Field)
+		for _, candidate := range e.Values {
+			ok, err := matchCompare(OpEq, value, found, candidate)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case AndExpr:
+		for _, inner := range e.Exprs {
+			ok, err := Match(inner, entity)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case OrExpr:
+		for _, inner := range e.Exprs {
+			ok, err := Match(inner, entity)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case NotExpr:
+		ok, err := Match(e.Expr, entity)
+		return !ok, err
+	case ExistsExpr:
+		_, found := fields.Lookup(entity, e.Field)
+		return found, nil
+	case PrefixExpr:
+		value, found := fields.Lookup(entity, e.Field)
+		if !found {
+			return false, nil
+		}
+		return anyElement(value, func(v any) bool {
+			rv := reflect.ValueOf(v)
+			return rv.Kind() == reflect.String && strings.HasPrefix(rv.String(), e.Prefix)
+		}), nil
+	}
+
+	return false, fmt.Errorf("unsupported query expression %T", expr)
+}
+
+func matchCompare(op Op, value any, found bool, operand any) (bool, error) {
+	switch op {
+	case OpEq:
+		if !found {
+			return operand == nil, nil
+		}
+		return anyElement(value, func(v any) bool { return fields.Equal(v, operand) }) || fields.Equal(value, operand), nil
+	case OpNe:
+		ok, err := matchCompare(OpEq, value, found, operand)
+		return !ok, err
+	case OpGt, OpGte, OpLt, OpLte:
+		if !found {
+			return false, nil
+		}
+		return anyElement(value, func(v any) bool {
+			c, ok := fields.Compare(v, operand)
+			if !ok {
+				return false
+			}
+			switch op {
+			case OpGt:
+				return c > 0
+			case OpGte:
+				return c >= 0
+			case OpLt:
+				return c < 0
+			}
+			return c <= 0
+		}), nil
+	}
+
+	return false, fmt.Errorf("unsupported query operator %q", op)
+}
+
+// anyElement applies f to value or, when value is a list, to each of its
+// elements, as MongoDB does when filtering array fields.
+func anyElement(value any, f func(any) bool) bool {
+	if !fields.IsList(value) {
+		return f(value)
+	}
+	for _, elem := range fields.Elements(value) {
+		if f(elem) {
+			return true
+		}
+	}
+	return false
+}
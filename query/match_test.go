@@ -0,0 +1,108 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type engine struct {
+	Size float64 `bson:"size"`
+	Fuel string  `bson:"fuel"`
+}
+
+type car struct {
+	ID       string    `bson:"_id"`
+	Model    string    `bson:"model"`
+	Year     int       `bson:"year"`
+	Price    float64   `json:"price"`
+	Tags     []string  `bson:"tags"`
+	Engine   *engine   `bson:"engine"`
+	Color    string    `bson:"color,omitempty"`
+	SoldAt   time.Time `bson:"sold_at"`
+	internal string
+}
+
+func TestMatch(t *testing.T) {
+	soldAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	corolla := car{
+		ID:     "1",
+		Model:  "Corolla",
+		Year:   2020,
+		Price:  15000,
+		Tags:   []string{"hybrid", "family"},
+		Engine: &engine{Size: 1.8, Fuel: "hybrid"},
+		SoldAt: soldAt,
+	}
+
+	tests := []struct {
+		name     string
+		expr     Expr
+		expected bool
+	}{
+		{"Nil matches everything", nil, true},
+		{"Eq by bson name", Eq("model", "Corolla"), true},
+		{"Eq by json name", Eq("price", 15000), true},
+		{"Eq by Go name", Eq("Model", "Corolla"), true},
+		{"Eq mismatch", Eq("model", "Yaris"), false},
+		{"Eq across numeric types", Eq("year", int64(2020)), true},
+		{"Eq on list element", Eq("tags", "family"), true},
+		{"Eq on missing field", Eq("unknown", "value"), false},
+		{"Eq nil on missing field", Eq("unknown", nil), true},
+		{"Ne", Ne("model", "Yaris"), true},
+		{"Ne on missing field", Ne("unknown", "value"), true},
+		{"Gt", Gt("year", 2019), true},
+		{"Gte", Gte("year", 2020), true},
+		{"Lt", Lt("year", 2020), false},
+		{"Lte", Lte("engine.size", 1.8), true},
+		{"Gt with incomparable types", Gt("model", 10), false},
+		{"Gt with times", Gt("sold_at", soldAt.Add(-time.Hour)), true},
+		{"Nested field", Eq("engine.fuel", "hybrid"), true},
+		{"In", In("model", "Yaris", "Corolla"), true},
+		{"In without match", In("model", "Yaris", "Auris"), false},
+		{"And", And(Eq("model", "Corolla"), Gt("year", 2019)), true},
+		{"And with a failing branch", And(Eq("model", "Corolla"), Gt("year", 2020)), false},
+		{"Empty And", And(), true},
+		{"Or", Or(Eq("model", "Yaris"), Eq("year", 2020)), true},
+		{"Empty Or", Or(), false},
+		{"Not", Not(Eq("model", "Yaris")), true},
+		{"Exists", Exists("engine.size"), true},
+		{"Exists on omitted empty field", Exists("color"), false},
+		{"Exists on missing field", Exists("unknown"), false},
+		{"Unexported fields are ignored", Exists("internal"), false},
+		{"Prefix", Prefix("model", "Cor"), true},
+		{"Prefix on list", Prefix("tags", "fam"), true},
+		{"Prefix mismatch", Prefix("model", "cor"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := Match(tt.expr, corolla)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ok)
+
+			ok, err = Match(tt.expr, &corolla)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ok)
+		})
+	}
+
+	t.Run("Nil nested pointer", func(t *testing.T) {
+		ok, err := Match(Exists("engine.size"), car{})
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Maps", func(t *testing.T) {
+		ok, err := Match(Eq("model", "Corolla"), map[string]any{"model": "Corolla"})
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("Unsupported operator", func(t *testing.T) {
+		_, err := Match(CompareExpr{Field: "model", Op: "like", Value: "C%"}, corolla)
+		assert.Error(t, err)
+	})
+}
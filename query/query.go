@@ -0,0 +1,108 @@
+// Package query defines a small, backend agnostic expression language to
+// filter entities by their fields. Field paths use the document field names
+// (bson tags) and dots to reach nested fields, e.g. "engine.size".
+package query
+
+type Expr interface {
+	isExpr()
+}
+
+type Op string
+
+const (
+	OpEq  Op = "eq"
+	OpNe  Op = "ne"
+	OpGt  Op = "gt"
+	OpGte Op = "gte"
+	OpLt  Op = "lt"
+	OpLte Op = "lte"
+)
+
+type CompareExpr struct {
+	Field string
+	Op    Op
+	Value any
+}
+
+type InExpr struct {
+	Field  string
+	Values []any
+}
+
+type AndExpr struct {
+	Exprs []Expr
+}
+
+type OrExpr struct {
+	Exprs []Expr
+}
+
+type NotExpr struct {
+	Expr Expr
+}
+
+type ExistsExpr struct {
+	Field string
+}
+
+type PrefixExpr struct {
+	Field  string
+	Prefix string
+}
+
+func (CompareExpr) isExpr() {}
+func (InExpr) isExpr()      {}
+func (AndExpr) isExpr()     {}
+func (OrExpr) isExpr()      {}
+func (NotExpr) isExpr()     {}
+func (ExistsExpr) isExpr()  {}
+func (PrefixExpr) isExpr()  {}
+
+func Eq(field string, value any) Expr {
+	return CompareExpr{Field: field, Op: OpEq, Value: value}
+}
+
+func Ne(field string, value any) Expr {
+	return CompareExpr{Field: field, Op: OpNe, Value: value}
+}
+
+func Gt(field string, value any) Expr {
+	return CompareExpr{Field: field, Op: OpGt, Value: value}
+}
+
+func Gte(field string, value any) Expr {
+	return CompareExpr{Field: field, Op: OpGte, Value: value}
+}
+
+func Lt(field string, value any) Expr {
+	return CompareExpr{Field: field, Op: OpLt, Value: value}
+}
+
+func Lte(field string, value any) Expr {
+	return CompareExpr{Field: field, Op: OpLte, Value: value}
+}
+
+func In(field string, values ...any) Expr {
+	return InExpr{Field: field, Values: values}
+}
+
+func And(exprs ...Expr) Expr {
+	return AndExpr{Exprs: exprs}
+}
+
+func Or(exprs ...Expr) Expr {
+	return OrExpr{Exprs: exprs}
+}
+
+func Not(expr Expr) Expr {
+	return NotExpr{Expr: expr}
+}
+
+func Exists(field string) Expr {
+	return ExistsExpr{Field: field}
+}
+
+// Prefix matches string fields starting with prefix.
+func Prefix(field string, prefix string) Expr {
+	return PrefixExpr{Field: field, Prefix: prefix}
+}
@@ -0,0 +1,69 @@
+package redis
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec turns entities into the value of their string key.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+var (
+	// JSONCodec is the default.
+	JSONCodec Codec = jsonCodec{}
+	// GobCodec is smaller and faster, but only Go can read it.
+	GobCodec Codec = gobCodec{}
+)
+
+type config struct {
+	codec  Codec
+	hashes bool
+}
+
+type Option func(*config)
+
+// WithCodec sets the codec of the values of the string keys.
+func WithCodec(codec Codec) Option {
+	return func(c *config) {
+		c.codec = codec
+	}
+}
+
+// WithHashes stores every entity as a hash instead of a string, with a hash
+// field per field of its JSON object. String fields are kept as they are,
+// and the rest as JSON, so HGET prints what a person expects:
+//
+//	HGET cars:1 model  -> Corolla
+//	HGET cars:1 year   -> 2020
+//	HGET cars:1 extras -> {"gps":"yes"}
+//
+// Null fields are not stored, so an entity must have at least one field
+// that is not null.
+func WithHashes() Option {
+	return func(c *config) {
+		c.hashes = true
+	}
+}
@@ -0,0 +1,209 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// The hash writes need a script to check the key and write the fields at
+// once. They return whether the key existed.
+var (
+	insertHash = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then return 1 end
+redis.call('HSET', KEYS[1], unpack(ARGV))
+return 0`)
+	replaceHash = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then return 0 end
+redis.call('DEL', KEYS[1])
+redis.call('HSET', KEYS[1], unpack(ARGV))
+return 1`)
+	upsertHash = redis.NewScript(`
+local existed = redis.call('DEL', KEYS[1])
+redis.call('HSET', KEYS[1], unpack(ARGV))
+return existed`)
+)
+
+// hashLayout keeps every entity in a hash. See WithHashes.
+type hashLayout[T any] struct {
+	client redis.UniversalClient
+	// strings has the JSON names of the string fields of T, which are
+	// stored without quotes
+	strings map[string]bool
+}
+
+func newHashLayout[T any](client redis.UniversalClient) *hashLayout[T] {
+	l := &hashLayout[T]{client: client, strings: map[string]bool{}}
+	stringFields(reflect.TypeOf((*T)(nil)).Elem(), l.strings)
+	return l
+}
+
+// stringFields adds the JSON names of the string fields of t to names,
+// following the rules of encoding/json for tags and embedded structs.
+func stringFields(t reflect.Type, names map[string]bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			stringFields(ft, names)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		if ft.Kind() == reflect.String && opts != "string" {
+			names[name] = true
+		}
+	}
+}
+
+// fields returns the field and value pairs of the hash of entity.
+func (l *hashLayout[T]) fields(entity *T) ([]any, error) {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, errors.New("hashes need entities encoded as JSON objects")
+	}
+
+	args := []any{}
+	for name, raw := range object {
+		if string(raw) == "null" {
+			continue
+		}
+		value := string(raw)
+		if l.strings[name] {
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return nil, err
+			}
+		}
+		args = append(args, name, value)
+	}
+	if len(args) == 0 {
+		return nil, errors.New("entity has no fields to store in a hash")
+	}
+	return args, nil
+}
+
+// decode rebuilds the entity of the fields of a hash.
+func (l *hashLayout[T]) decode(fields map[string]string) (*T, error) {
+	object := make(map[string]json.RawMessage, len(fields))
+	for name, value := range fields {
+		if l.strings[name] {
+			quoted, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			object[name] = quoted
+		} else {
+			object[name] = json.RawMessage(value)
+		}
+	}
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	var entity T
+	if err := json.Unmarshal(data, &entity); err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+func (l *hashLayout[T]) get(ctx context.Context, key string) (*T, error) {
+	fields, err := l.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return l.decode(fields)
+}
+
+// getMany sends every HGETALL in a single pipeline.
+func (l *hashLayout[T]) getMany(ctx context.Context, keys []string) ([]*T, error) {
+	cmds := make([]*redis.MapStringStringCmd, len(keys))
+	_, err := l.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.HGetAll(ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ents := make([]*T, len(keys))
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			continue
+		}
+		if ents[i], err = l.decode(fields); err != nil {
+			return nil, fmt.Errorf("%s: %w", keys[i], err)
+		}
+	}
+	return ents, nil
+}
+
+// run runs script on the key and the fields of entity, which it returns.
+func (l *hashLayout[T]) run(ctx context.Context, script *redis.Script, key string, entity *T) (fields []any, existed bool, err error) {
+	fields, err = l.fields(entity)
+	if err != nil {
+		return nil, false, err
+	}
+	n, err := script.Run(ctx, l.client, []string{key}, fields...).Int()
+	return fields, n > 0, err
+}
+
+func (l *hashLayout[T]) insert(ctx context.Context, key string, entity *T) (*T, error) {
+	fields, existed, err := l.run(ctx, insertHash, key, entity)
+	if err != nil || existed {
+		return nil, err
+	}
+
+	written := make(map[string]string, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		written[fields[i].(string)] = fields[i+1].(string)
+	}
+	return l.decode(written)
+}
+
+func (l *hashLayout[T]) replace(ctx context.Context, key string, entity *T) (bool, error) {
+	_, existed, err := l.run(ctx, replaceHash, key, entity)
+	return existed, err
+}
+
+func (l *hashLayout[T]) upsert(ctx context.Context, key string, entity *T) (bool, error) {
+	_, existed, err := l.run(ctx, upsertHash, key, entity)
+	return !existed, err
+}
@@ -0,0 +1,123 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Car struct {
+	Model    string            `json:"model"`
+	Year     int               `json:"year"`
+	Code     string            `json:"code"`
+	Color    *string           `json:"color,omitempty"`
+	Price    int               `json:"price,string"`
+	SoldAt   time.Time         `json:"sold_at"`
+	Extras   map[string]string `json:"extras"`
+	Internal string            `json:"-"`
+	Audit
+}
+
+type Audit struct {
+	CreatedBy string
+}
+
+func TestHashes(t *testing.T) {
+	ctx := context.Background()
+	_, client := newClient(t)
+	store := newStore[Car](t, client, "cars", WithHashes())
+
+	red := "red"
+	car := Car{
+		Model:    "Corolla",
+		Year:     2020,
+		Code:     "12",
+		Color:    &red,
+		Price:    19999,
+		SoldAt:   time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+		Extras:   map[string]string{"gps": "yes"},
+		Internal: "not stored",
+		Audit:    Audit{CreatedBy: "admin"},
+	}
+	inserted, err := store.Insert(ctx, "1", &car)
+	require.NoError(t, err)
+
+	t.Run("Readable fields", func(t *testing.T) {
+		fields, err := client.HGetAll(ctx, "cars:1").Result()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"model":     "Corolla",
+			"year":      "2020",
+			"code":      "12",
+			"color":     "red",
+			"price":     `"19999"`,
+			"sold_at":   `"2024-05-01T10:30:00Z"`,
+			"extras":    `{"gps":"yes"}`,
+			"CreatedBy": "admin",
+		}, fields)
+	})
+
+	t.Run("Round trip", func(t *testing.T) {
+		stored, err := store.GetByID(ctx, "1")
+		require.NoError(t, err)
+		car.Internal = ""
+		assert.Equal(t, &car, stored)
+		assert.Equal(t, &car, inserted)
+	})
+
+	t.Run("Replace drops the old fields", func(t *testing.T) {
+		require.NoError(t, store.Replace(ctx, "1", &Car{Model: "Yaris"}))
+
+		exists, err := client.HExists(ctx, "cars:1", "color").Result()
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		stored, err := store.GetByID(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "Yaris", stored.Model)
+		assert.Nil(t, stored.Color)
+	})
+
+	t.Run("Entities that are not objects", func(t *testing.T) {
+		numbers := newStore[int](t, client, "numbers", WithHashes())
+		n := 1
+		_, err := numbers.Insert(ctx, "1", &n)
+		assert.ErrorContains(t, err, "JSON objects")
+
+		type optional struct {
+			A *string `json:"a"`
+		}
+		empty := newStore[optional](t, client, "empty", WithHashes())
+		_, err = empty.Insert(ctx, "1", &optional{})
+		assert.ErrorContains(t, err, "no fields")
+	})
+
+	t.Run("Hash written by someone else", func(t *testing.T) {
+		client.HSet(ctx, "cars:2", "model", "Aygo", "year", "soon")
+		_, err := store.GetByID(ctx, "2")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, gostore.ErrNotFound)
+
+		_, err = store.GetMultipleByID(ctx, []string{"2"})
+		assert.ErrorContains(t, err, "cars:2")
+	})
+}
+
+func TestStringFields(t *testing.T) {
+	names := map[string]bool{}
+	stringFields(reflectType[*Car](), names)
+	assert.Equal(t, map[string]bool{"model": true, "code": true, "color": true, "CreatedBy": true}, names)
+
+	names = map[string]bool{}
+	stringFields(reflectType[map[string]string](), names)
+	assert.Empty(t, names)
+}
+
+func reflectType[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
@@ -0,0 +1,298 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	store "github.com/Silencevoice/go-store"
+	"github.com/redis/go-redis/v9"
+)
+
+const backend = "redis"
+
+// scanBatch is the COUNT hint of SCAN, and how many keys GetAll reads at once.
+const scanBatch = 100
+
+// RedisStore keeps every entity under the prefix:id key, as a string encoded
+// by its Codec or as a hash. GetAll scans the keys of a single node, so
+// cluster clients are not supported.
+type RedisStore[T any] struct {
+	client redis.UniversalClient
+	prefix string
+	layout layout[T]
+}
+
+// layout reads and writes the values of the keys. Reads return nil for the
+// missing keys, and the writes whether the condition of the write held.
+// insert returns the entity decoded from what it wrote, or nil when the key
+// exists.
+type layout[T any] interface {
+	get(ctx context.Context, key string) (*T, error)
+	getMany(ctx context.Context, keys []string) ([]*T, error)
+	insert(ctx context.Context, key string, entity *T) (*T, error)
+	replace(ctx context.Context, key string, entity *T) (bool, error)
+	upsert(ctx context.Context, key string, entity *T) (created bool, err error)
+}
+
+// NewRedisStore returns a RedisStore of the keys starting with "prefix:",
+// which are strings encoded with JSONCodec unless the options say otherwise.
+// The prefix may have colons, as in "app:cars": the ones in the ids are
+// escaped, so the keys of an "app" store are never keys of an "app:cars" one.
+func NewRedisStore[T any](client redis.UniversalClient, prefix string, opts ...Option) (*RedisStore[T], error) {
+	if prefix == "" {
+		return nil, store.NewError(backend, "Open", nil, errors.New("empty prefix"))
+	}
+
+	cfg := config{codec: JSONCodec}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	r := &RedisStore[T]{client: client, prefix: prefix}
+	if cfg.hashes {
+		r.layout = newHashLayout[T](client)
+	} else {
+		r.layout = &stringLayout[T]{client: client, codec: cfg.codec}
+	}
+	return r, nil
+}
+
+// key escapes the colons of the id, and the backslashes that escape them.
+func (r *RedisStore[T]) key(id string) string {
+	return r.prefix + ":" + idEscaper.Replace(id)
+}
+
+var idEscaper = strings.NewReplacer(`\`, `\\`, ":", `\:`)
+
+// owns tells whether a key matched by the SCAN of the store is one of its
+// own, rather than a key of a store whose prefix continues with a colon.
+func (r *RedisStore[T]) owns(key string) bool {
+	id := key[len(r.prefix)+1:]
+	for i := 0; i < len(id); i++ {
+		switch id[i] {
+		case '\\':
+			i++
+		case ':':
+			return false
+		}
+	}
+	return true
+}
+
+func (r *RedisStore[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	entity, err := r.layout.get(ctx, r.key(id))
+	if err != nil {
+		return nil, store.NewError(backend, "GetByID", id, err)
+	}
+	if entity == nil {
+		return nil, store.NewError(backend, "GetByID", id, store.ErrNotFound)
+	}
+	return entity, nil
+}
+
+// GetMultipleByID reads every entity in a single round trip.
+func (r *RedisStore[T]) GetMultipleByID(ctx context.Context, ids []string) ([]*T, error) {
+	if len(ids) == 0 {
+		return []*T{}, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = r.key(id)
+	}
+	ents, err := r.layout.getMany(ctx, keys)
+	if err != nil {
+		return nil, store.NewError(backend, "GetMultipleByID", nil, err)
+	}
+	for i, entity := range ents {
+		if entity == nil {
+			return nil, store.NewError(backend, "GetMultipleByID", ids[i], store.ErrNotFound)
+		}
+	}
+	return ents, nil
+}
+
+// GetAll scans the keys of the store, reading them in batches. Like SCAN
+// itself, it may miss the entities written while it runs.
+func (r *RedisStore[T]) GetAll(ctx context.Context) ([]*T, error) {
+	ents := []*T{}
+	seen := map[string]bool{}
+	var cursor uint64
+	for {
+		keys, next, err := r.client.Scan(ctx, cursor, escapeGlob(r.prefix)+":*", scanBatch).Result()
+		if err != nil {
+			return nil, store.NewError(backend, "GetAll", nil, err)
+		}
+
+		// SCAN may return a key more than once
+		unseen := keys[:0]
+		for _, key := range keys {
+			if !seen[key] && r.owns(key) {
+				seen[key] = true
+				unseen = append(unseen, key)
+			}
+		}
+		if len(unseen) > 0 {
+			batch, err := r.layout.getMany(ctx, unseen)
+			if err != nil {
+				return nil, store.NewError(backend, "GetAll", nil, err)
+			}
+			for _, entity := range batch {
+				// Deleted since the scan
+				if entity != nil {
+					ents = append(ents, entity)
+				}
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return ents, nil
+		}
+	}
+}
+
+// Insert is atomic: of several concurrent inserts of the same id, only one
+// succeeds. It returns the entity decoded from what it wrote, so the result
+// lacks what the codec or the hash fields do not keep.
+func (r *RedisStore[T]) Insert(ctx context.Context, id string, entity *T) (*T, error) {
+	inserted, err := r.layout.insert(ctx, r.key(id), entity)
+	if err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
+	if inserted == nil {
+		return nil, store.NewError(backend, "Insert", id, store.ErrAlreadyExists)
+	}
+	return inserted, nil
+}
+
+func (r *RedisStore[T]) Delete(ctx context.Context, id string) error {
+	n, err := r.client.Del(ctx, r.key(id)).Result()
+	if err != nil {
+		return store.NewError(backend, "Delete", id, err)
+	}
+	if n == 0 {
+		return store.NewError(backend, "Delete", id, store.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *RedisStore[T]) Update(ctx context.Context, id string, entity *T) error {
+	return r.replace(ctx, "Update", id, entity)
+}
+
+// Replace overwrites the whole string or hash of the entity, as Update
+// does.
+func (r *RedisStore[T]) Replace(ctx context.Context, id string, entity *T) error {
+	return r.replace(ctx, "Replace", id, entity)
+}
+
+func (r *RedisStore[T]) replace(ctx context.Context, op string, id string, entity *T) error {
+	ok, err := r.layout.replace(ctx, r.key(id), entity)
+	if err != nil {
+		return store.NewError(backend, op, id, err)
+	}
+	if !ok {
+		return store.NewError(backend, op, id, store.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *RedisStore[T]) Upsert(ctx context.Context, id string, entity *T) (bool, error) {
+	created, err := r.layout.upsert(ctx, r.key(id), entity)
+	if err != nil {
+		return false, store.NewError(backend, "Upsert", id, err)
+	}
+	return created, nil
+}
+
+// stringLayout keeps every entity in a string key.
+type stringLayout[T any] struct {
+	client redis.UniversalClient
+	codec  Codec
+}
+
+func (l *stringLayout[T]) decode(data []byte) (*T, error) {
+	var entity T
+	if err := l.codec.Unmarshal(data, &entity); err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+func (l *stringLayout[T]) get(ctx context.Context, key string) (*T, error) {
+	data, err := l.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return l.decode(data)
+}
+
+func (l *stringLayout[T]) getMany(ctx context.Context, keys []string) ([]*T, error) {
+	values, err := l.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ents := make([]*T, len(keys))
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if ents[i], err = l.decode([]byte(data)); err != nil {
+			return nil, fmt.Errorf("%s: %w", keys[i], err)
+		}
+	}
+	return ents, nil
+}
+
+func (l *stringLayout[T]) insert(ctx context.Context, key string, entity *T) (*T, error) {
+	data, err := l.codec.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := l.client.SetNX(ctx, key, data, 0).Result()
+	if err != nil || !ok {
+		return nil, err
+	}
+	return l.decode(data)
+}
+
+func (l *stringLayout[T]) replace(ctx context.Context, key string, entity *T) (bool, error) {
+	data, err := l.codec.Marshal(entity)
+	if err != nil {
+		return false, err
+	}
+	return l.client.SetXX(ctx, key, data, 0).Result()
+}
+
+func (l *stringLayout[T]) upsert(ctx context.Context, key string, entity *T) (bool, error) {
+	data, err := l.codec.Marshal(entity)
+	if err != nil {
+		return false, err
+	}
+
+	// SET GET returns the previous value, telling whether there was one
+	err = l.client.SetArgs(ctx, key, data, redis.SetArgs{Get: true}).Err()
+	if errors.Is(err, redis.Nil) {
+		return true, nil
+	}
+	return false, err
+}
+
+// escapeGlob escapes the characters special to the patterns of SCAN.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,147 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/storetest"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestEntity struct {
+	ID    string
+	Value string
+}
+
+var _ gostore.Store[TestEntity] = (*RedisStore[TestEntity])(nil)
+
+func newClient(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func newStore[T any](t *testing.T, client redis.UniversalClient, prefix string, opts ...Option) *RedisStore[T] {
+	store, err := NewRedisStore[T](client, prefix, opts...)
+	require.NoError(t, err)
+	return store
+}
+
+func TestConformance(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"JSON":   nil,
+		"Gob":    {WithCodec(GobCodec)},
+		"Hashes": {WithHashes()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			storetest.Run(t, func(t *testing.T) gostore.KeyedStore[string, storetest.Entity] {
+				_, client := newClient(t)
+				return newStore[storetest.Entity](t, client, "entities", opts...)
+			})
+		})
+	}
+}
+
+func TestGetAll(t *testing.T) {
+	ctx := context.Background()
+	_, client := newClient(t)
+	store := newStore[TestEntity](t, client, "entities")
+
+	expected := []*TestEntity{}
+	for i := 0; i < 3*scanBatch+7; i++ {
+		entity := &TestEntity{ID: fmt.Sprint(i)}
+		store.Insert(ctx, entity.ID, entity)
+		expected = append(expected, entity)
+	}
+
+	// Neither other collections nor other types of keys
+	client.Set(ctx, "entitiesX:1", "{}", 0)
+	client.Set(ctx, "other:1", "{}", 0)
+	newStore[TestEntity](t, client, "entit*").Insert(ctx, "1", &TestEntity{})
+
+	entities, err := store.GetAll(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, expected, entities)
+}
+
+func TestPrefix(t *testing.T) {
+	ctx := context.Background()
+	_, client := newClient(t)
+
+	_, err := NewRedisStore[TestEntity](client, "")
+	assert.Error(t, err)
+
+	t.Run("Nested prefixes do not share keys", func(t *testing.T) {
+		app := newStore[TestEntity](t, client, "app")
+		cars := newStore[TestEntity](t, client, "app:cars")
+
+		_, err := app.Insert(ctx, "cars:1", &TestEntity{ID: "cars:1"})
+		require.NoError(t, err)
+		_, err = cars.GetByID(ctx, "1")
+		assert.ErrorIs(t, err, gostore.ErrNotFound)
+
+		_, err = cars.Insert(ctx, "1", &TestEntity{ID: "1"})
+		require.NoError(t, err)
+		entities, err := app.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*TestEntity{{ID: "cars:1"}}, entities)
+		entities, err = cars.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []*TestEntity{{ID: "1"}}, entities)
+	})
+
+	t.Run("Escaped ids", func(t *testing.T) {
+		store := newStore[TestEntity](t, client, "escaped")
+		for _, id := range []string{`a:b`, `a\:b`, `a\`, `a\\:`} {
+			_, err := store.Insert(ctx, id, &TestEntity{ID: id})
+			require.NoError(t, err)
+			entity, err := store.GetByID(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, id, entity.ID)
+		}
+		entities, err := store.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, entities, 4)
+		assert.Equal(t, int64(1), client.Exists(ctx, `escaped:a\:b`).Val())
+	})
+}
+
+func TestRedisErrors(t *testing.T) {
+	ctx := context.Background()
+	server, client := newClient(t)
+	store := newStore[TestEntity](t, client, "entities")
+
+	t.Run("Undecodable value", func(t *testing.T) {
+		client.Set(ctx, "entities:1", "{", 0)
+
+		_, err := store.GetByID(ctx, "1")
+		assert.Error(t, err)
+		_, err = store.GetAll(ctx)
+		assert.ErrorContains(t, err, "entities:1")
+	})
+
+	t.Run("Server down", func(t *testing.T) {
+		server.Close()
+
+		_, err := store.GetByID(ctx, "1")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, gostore.ErrNotFound)
+		_, err = store.Insert(ctx, "2", &TestEntity{})
+		var storeErr *gostore.Error
+		require.ErrorAs(t, err, &storeErr)
+		assert.Equal(t, "Insert", storeErr.Op)
+		err = store.Delete(ctx, "1")
+		assert.Error(t, err)
+	})
+}
+
+func TestEscapeGlob(t *testing.T) {
+	assert.Equal(t, "cars", escapeGlob("cars"))
+	assert.Equal(t, `a\*b\?c\[d\]\\`, escapeGlob(`a*b?c[d]\`))
+}
@@ -0,0 +1,108 @@
+package sql
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect is the flavour of SQL spoken by the database.
+type Dialect int
+
+const (
+	// SQLite uses ? placeholders.
+	SQLite Dialect = iota
+	// Postgres uses $1, $2... placeholders.
+	Postgres
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case SQLite:
+		return "sqlite"
+	case Postgres:
+		return "postgres"
+	}
+	return "dialect(" + strconv.Itoa(int(d)) + ")"
+}
+
+// placeholder returns the placeholder of the n-th parameter, starting at 1.
+func (d Dialect) placeholder(n int) string {
+	if d == Postgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// placeholders returns count placeholders separated by commas, the first one
+// being the n-th parameter.
+func (d Dialect) placeholders(n, count int) string {
+	list := make([]string, count)
+	for i := range list {
+		list[i] = d.placeholder(n + i)
+	}
+	return strings.Join(list, ", ")
+}
+
+// quote quotes an identifier, and each part of a qualified one like
+// "schema.table".
+func quote(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte(nil))
+)
+
+// columnType returns the type of the column keeping values of type t. The
+// values stored as JSON get the JSON type.
+func (d Dialect) columnType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		if d == Postgres {
+			return "TIMESTAMPTZ"
+		}
+		return "TIMESTAMP"
+	case t == bytesType:
+		if d == Postgres {
+			return "BYTEA"
+		}
+		return "BLOB"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "TEXT"
+	case reflect.Bool:
+		return "BOOLEAN"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if d == Postgres {
+			return "BIGINT"
+		}
+		return "INTEGER"
+	case reflect.Float32, reflect.Float64:
+		if d == Postgres {
+			return "DOUBLE PRECISION"
+		}
+		return "REAL"
+	}
+	return d.jsonType()
+}
+
+func (d Dialect) jsonType() string {
+	if d == Postgres {
+		return "JSONB"
+	}
+	return "TEXT"
+}
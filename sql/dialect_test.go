@@ -0,0 +1,19 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialect(t *testing.T) {
+	assert.Equal(t, "?, ?, ?", SQLite.placeholders(1, 3))
+	assert.Equal(t, "$2, $3", Postgres.placeholders(2, 2))
+	assert.Equal(t, "sqlite", SQLite.String())
+	assert.Equal(t, "postgres", Postgres.String())
+	assert.Equal(t, "dialect(9)", Dialect(9).String())
+
+	assert.Equal(t, `"cars"`, quote("cars"))
+	assert.Equal(t, `"public"."cars"`, quote("public.cars"))
+	assert.Equal(t, `"a""b"`, quote(`a"b`))
+}
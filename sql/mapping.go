@@ -0,0 +1,226 @@
+package sql
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Mapping decides how entities are laid out in the table. Both keep the key
+// in the "id" column.
+type Mapping int
+
+const (
+	// JSONColumn stores the whole entity as JSON in the "data" column.
+	JSONColumn Mapping = iota
+	// StructColumns stores every exported field of the entity in its own
+	// column, named by its `db` tag or else by the field name in snake case.
+	// Fields tagged `db:"-"` are skipped, and the fields of embedded structs
+	// are stored as if they were declared in the entity. Fields of any type
+	// without a matching column type are stored as JSON.
+	StructColumns
+)
+
+const (
+	keyColumn  = "id"
+	dataColumn = "data"
+)
+
+type config struct {
+	mapping Mapping
+}
+
+type Option func(*config)
+
+func WithMapping(mapping Mapping) Option {
+	return func(c *config) {
+		c.mapping = mapping
+	}
+}
+
+// column is a column of the table besides the key.
+type column struct {
+	name   string
+	typ    string
+	index  []int
+	asJSON bool
+}
+
+// layout is how the entities of type T are laid out in the table.
+type layout[T any] struct {
+	mapping Mapping
+	columns []column
+	// key is the index of the field mapped to the key column, which is
+	// filled from the key when reading
+	key []int
+}
+
+func newLayout[T any](mapping Mapping, dialect Dialect) (*layout[T], error) {
+	l := &layout[T]{mapping: mapping}
+	switch mapping {
+	case JSONColumn:
+		l.columns = []column{{name: dataColumn, typ: dialect.jsonType(), asJSON: true}}
+		return l, nil
+	case StructColumns:
+	default:
+		return nil, fmt.Errorf("unknown mapping %d", mapping)
+	}
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("StructColumns needs a struct entity, not %s", t)
+	}
+
+	seen := map[string]bool{}
+	var walk func(t reflect.Type, index []int) error
+	walk = func(t reflect.Type, index []int) error {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("db")
+			if !f.IsExported() || tag == "-" {
+				continue
+			}
+			fieldIndex := append(append([]int{}, index...), i)
+			if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+				if err := walk(f.Type, fieldIndex); err != nil {
+					return err
+				}
+				continue
+			}
+
+			name := tag
+			if name == "" {
+				name = snakeCase(f.Name)
+			}
+			if seen[name] {
+				return fmt.Errorf("column %q is mapped twice", name)
+			}
+			seen[name] = true
+
+			if name == keyColumn {
+				if f.Type.Kind() != reflect.String {
+					return fmt.Errorf("field %s is mapped to the key column but is not a string", f.Name)
+				}
+				l.key = fieldIndex
+				continue
+			}
+
+			typ := dialect.columnType(f.Type)
+			l.columns = append(l.columns, column{
+				name:   name,
+				typ:    typ,
+				index:  fieldIndex,
+				asJSON: typ == dialect.jsonType() && !isText(f.Type),
+			})
+		}
+		return nil
+	}
+	if err := walk(t, nil); err != nil {
+		return nil, err
+	}
+
+	if len(l.columns) == 0 {
+		return nil, fmt.Errorf("%s has no fields to store besides the key", t)
+	}
+	return l, nil
+}
+
+func isText(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.String
+}
+
+// values returns the values of the columns of entity.
+func (l *layout[T]) values(entity *T) ([]any, error) {
+	v := reflect.ValueOf(entity).Elem()
+	values := make([]any, len(l.columns))
+	for i, c := range l.columns {
+		var field any = entity
+		if c.index != nil {
+			field = v.FieldByIndex(c.index).Interface()
+		}
+		if !c.asJSON {
+			values[i] = field
+			continue
+		}
+
+		data, err := json.Marshal(field)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", c.name, err)
+		}
+		values[i] = string(data)
+	}
+	return values, nil
+}
+
+// scanner scans a row with the key and the columns into a new entity.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (l *layout[T]) scan(row scanner) (string, *T, error) {
+	var id string
+	entity := new(T)
+	v := reflect.ValueOf(entity).Elem()
+
+	dest := make([]any, len(l.columns)+1)
+	dest[0] = &id
+	for i, c := range l.columns {
+		switch {
+		case c.index == nil:
+			dest[i+1] = &jsonValue{target: entity}
+		case c.asJSON:
+			dest[i+1] = &jsonValue{target: v.FieldByIndex(c.index).Addr().Interface()}
+		default:
+			dest[i+1] = v.FieldByIndex(c.index).Addr().Interface()
+		}
+	}
+	if err := row.Scan(dest...); err != nil {
+		return "", nil, err
+	}
+
+	if l.key != nil {
+		v.FieldByIndex(l.key).SetString(id)
+	}
+	return id, entity, nil
+}
+
+// jsonValue decodes a JSON column into target. NULL leaves it untouched.
+type jsonValue struct {
+	target any
+}
+
+func (j *jsonValue) Scan(src any) error {
+	switch data := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(data, j.target)
+	case string:
+		return json.Unmarshal([]byte(data), j.target)
+	}
+	return fmt.Errorf("cannot decode %T as JSON", src)
+}
+
+// snakeCase turns a Go field name into a column name: "CreatedAt" becomes
+// "created_at" and "HTTPStatus" becomes "http_status".
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			lowerBefore := i > 0 && !unicode.IsUpper(runes[i-1])
+			lowerAfter := i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if i > 0 && runes[i-1] != '_' && (lowerBefore || lowerAfter) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLayout(t *testing.T) {
+	t.Run("Columns of a struct", func(t *testing.T) {
+		l, err := newLayout[Car](StructColumns, Postgres)
+		require.NoError(t, err)
+
+		columns := map[string]string{}
+		for _, c := range l.columns {
+			columns[c.name] = c.typ
+		}
+		assert.Equal(t, map[string]string{
+			"model":      "TEXT",
+			"year":       "BIGINT",
+			"price":      "DOUBLE PRECISION",
+			"used":       "BOOLEAN",
+			"color":      "TEXT",
+			"sold_at":    "TIMESTAMPTZ",
+			"photo":      "BYTEA",
+			"extras":     "JSONB",
+			"labels":     "JSONB",
+			"created_by": "TEXT",
+		}, columns)
+		assert.Equal(t, []int{0}, l.key)
+	})
+
+	t.Run("JSON column", func(t *testing.T) {
+		l, err := newLayout[string](JSONColumn, SQLite)
+		require.NoError(t, err)
+		assert.Equal(t, []column{{name: "data", typ: "TEXT", asJSON: true}}, l.columns)
+	})
+
+	t.Run("Invalid entities", func(t *testing.T) {
+		_, err := newLayout[string](StructColumns, SQLite)
+		assert.ErrorContains(t, err, "struct")
+
+		_, err = newLayout[struct{ ID int }](StructColumns, SQLite)
+		assert.ErrorContains(t, err, "not a string")
+
+		_, err = newLayout[struct {
+			A string `db:"x"`
+			B string `db:"x"`
+		}](StructColumns, SQLite)
+		assert.ErrorContains(t, err, "mapped twice")
+
+		_, err = newLayout[struct{ ID string }](StructColumns, SQLite)
+		assert.ErrorContains(t, err, "no fields")
+
+		_, err = newLayout[TestEntity](Mapping(7), SQLite)
+		assert.Error(t, err)
+	})
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, expected := range map[string]string{
+		"ID":         "id",
+		"Model":      "model",
+		"CreatedAt":  "created_at",
+		"HTTPStatus": "http_status",
+		"UserID":     "user_id",
+		"Field1":     "field1",
+		"Already_ok": "already_ok",
+	} {
+		assert.Equal(t, expected, snakeCase(name), name)
+	}
+}
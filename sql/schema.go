@@ -0,0 +1,28 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	store "github.com/Silencevoice/go-store"
+)
+
+// EnsureSchema creates the table when it does not exist, with a column for
+// the key and every column of the mapping. An existing table is left as it
+// is, even when its columns differ. It is meant to be called once at
+// startup.
+func (s *SQLStore[T]) EnsureSchema(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, s.createTable()); err != nil {
+		return store.NewError(backend, "EnsureSchema", nil, err)
+	}
+	return nil
+}
+
+func (s *SQLStore[T]) createTable() string {
+	columns := []string{quote(keyColumn) + " TEXT PRIMARY KEY"}
+	for _, c := range s.layout.columns {
+		columns = append(columns, quote(c.name)+" "+c.typ)
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", s.table, strings.Join(columns, ", "))
+}
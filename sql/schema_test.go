@@ -0,0 +1,49 @@
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureSchema(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Create once", func(t *testing.T) {
+		store := newStore[TestEntity](t)
+		store.Insert(ctx, "1", &TestEntity{ID: "1"})
+
+		require.NoError(t, store.EnsureSchema(ctx))
+		entities, err := store.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, entities, 1)
+	})
+
+	t.Run("Postgres statements", func(t *testing.T) {
+		store, err := NewSQLStore[TestEntity](nil, "public.cars", Postgres, WithMapping(StructColumns))
+		require.NoError(t, err)
+
+		assert.Equal(t, `CREATE TABLE IF NOT EXISTS "public"."cars" ("id" TEXT PRIMARY KEY, "value" TEXT)`, store.createTable())
+		assert.Equal(t, `INSERT INTO "public"."cars" ("id", "value") VALUES ($1, $2) ON CONFLICT ("id") DO NOTHING`, store.insert)
+		assert.Equal(t, `UPDATE "public"."cars" SET "value" = $1 WHERE "id" = $2`, store.update)
+	})
+
+	t.Run("SQLite statements", func(t *testing.T) {
+		store, err := NewSQLStore[TestEntity](nil, "cars", SQLite)
+		require.NoError(t, err)
+
+		assert.Equal(t, `CREATE TABLE IF NOT EXISTS "cars" ("id" TEXT PRIMARY KEY, "data" TEXT)`, store.createTable())
+		assert.Equal(t, `INSERT INTO "cars" ("id", "data") VALUES (?, ?) ON CONFLICT ("id") DO NOTHING`, store.insert)
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		store, err := NewSQLStore[TestEntity](openDB(t), `bad"name`, SQLite)
+		require.NoError(t, err)
+		require.NoError(t, store.EnsureSchema(ctx))
+
+		store.db.Close()
+		assert.Error(t, store.EnsureSchema(ctx))
+	})
+}
@@ -0,0 +1,260 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	store "github.com/Silencevoice/go-store"
+)
+
+const backend = "sql"
+
+// SQLStore keeps the entities in a table of a database/sql database, keyed
+// by their id.
+type SQLStore[T any] struct {
+	db      *sql.DB
+	table   string
+	dialect Dialect
+	layout  *layout[T]
+
+	// statements built once from the layout
+	selectColumns string
+	insert        string
+	insertRow     string
+	update        string
+	// upsert is only used on Postgres
+	upsert string
+}
+
+// NewSQLStore returns a SQLStore on table, which EnsureSchema can create. It
+// fails when T cannot be laid out with the mapping, JSONColumn by default.
+func NewSQLStore[T any](db *sql.DB, table string, dialect Dialect, opts ...Option) (*SQLStore[T], error) {
+	cfg := config{mapping: JSONColumn}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	l, err := newLayout[T](cfg.mapping, dialect)
+	if err != nil {
+		return nil, store.NewError(backend, "Open", nil, err)
+	}
+
+	s := &SQLStore[T]{db: db, table: quote(table), dialect: dialect, layout: l}
+
+	names := []string{quote(keyColumn)}
+	sets := []string{}
+	excluded := []string{}
+	for i, c := range l.columns {
+		names = append(names, quote(c.name))
+		sets = append(sets, fmt.Sprintf("%s = %s", quote(c.name), dialect.placeholder(i+1)))
+		excluded = append(excluded, fmt.Sprintf("%s = excluded.%s", quote(c.name), quote(c.name)))
+	}
+	s.selectColumns = strings.Join(names, ", ")
+	s.insert = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO NOTHING",
+		s.table, s.selectColumns, dialect.placeholders(1, len(names)), quote(keyColumn))
+	s.insertRow = s.insert + " RETURNING " + s.selectColumns
+	s.update = fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s",
+		s.table, strings.Join(sets, ", "), quote(keyColumn), dialect.placeholder(len(l.columns)+1))
+	// xmax is 0 for a row no transaction has updated or deleted, so only for
+	// the rows the statement inserted
+	s.upsert = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s RETURNING (xmax = 0)",
+		s.table, s.selectColumns, dialect.placeholders(1, len(names)), quote(keyColumn), strings.Join(excluded, ", "))
+	return s, nil
+}
+
+func (s *SQLStore[T]) GetByID(ctx context.Context, id string) (*T, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s", s.selectColumns, s.table, quote(keyColumn), s.dialect.placeholder(1))
+
+	_, entity, err := s.layout.scan(s.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.NewError(backend, "GetByID", id, store.ErrNotFound)
+	} else if err != nil {
+		return nil, store.NewError(backend, "GetByID", id, err)
+	}
+	return entity, nil
+}
+
+func (s *SQLStore[T]) GetMultipleByID(ctx context.Context, ids []string) ([]*T, error) {
+	if len(ids) == 0 {
+		return []*T{}, nil
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s IN (%s)", s.selectColumns, s.table, quote(keyColumn), s.dialect.placeholders(1, len(ids)))
+
+	found := map[string]*T{}
+	if err := s.each(ctx, query, args, func(id string, entity *T) {
+		found[id] = entity
+	}); err != nil {
+		return nil, store.NewError(backend, "GetMultipleByID", nil, err)
+	}
+
+	ents := make([]*T, len(ids))
+	returned := map[string]bool{}
+	for i, id := range ids {
+		entity, ok := found[id]
+		if !ok {
+			return nil, store.NewError(backend, "GetMultipleByID", id, store.ErrNotFound)
+		}
+		// The same id twice gets two entities, like in the other stores
+		if returned[id] {
+			copied := *entity
+			entity = &copied
+		}
+		returned[id] = true
+		ents[i] = entity
+	}
+	return ents, nil
+}
+
+func (s *SQLStore[T]) GetAll(ctx context.Context) ([]*T, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s", s.selectColumns, s.table, quote(keyColumn))
+
+	ents := []*T{}
+	if err := s.each(ctx, query, nil, func(id string, entity *T) {
+		ents = append(ents, entity)
+	}); err != nil {
+		return nil, store.NewError(backend, "GetAll", nil, err)
+	}
+	return ents, nil
+}
+
+// each calls f with every row of query.
+func (s *SQLStore[T]) each(ctx context.Context, query string, args []any, f func(id string, entity *T)) error {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		id, entity, err := s.layout.scan(rows)
+		if err != nil {
+			return err
+		}
+		f(id, entity)
+	}
+	return rows.Err()
+}
+
+// Insert returns the row it inserted, so the result lacks the fields no
+// column keeps, like the ones tagged db:"-" or left out of the JSON.
+func (s *SQLStore[T]) Insert(ctx context.Context, id string, entity *T) (*T, error) {
+	values, err := s.layout.values(entity)
+	if err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
+
+	// The conflicting inserts return no row
+	_, inserted, err := s.layout.scan(s.db.QueryRowContext(ctx, s.insertRow, append([]any{id}, values...)...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.NewError(backend, "Insert", id, store.ErrAlreadyExists)
+	} else if err != nil {
+		return nil, store.NewError(backend, "Insert", id, err)
+	}
+	return inserted, nil
+}
+
+func (s *SQLStore[T]) Delete(ctx context.Context, id string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", s.table, quote(keyColumn), s.dialect.placeholder(1))
+
+	result, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return store.NewError(backend, "Delete", id, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return store.NewError(backend, "Delete", id, err)
+	}
+	if n == 0 {
+		return store.NewError(backend, "Delete", id, store.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *SQLStore[T]) Update(ctx context.Context, id string, entity *T) error {
+	return s.replace(ctx, "Update", id, entity)
+}
+
+// Replace runs the same UPDATE of every column as Update.
+func (s *SQLStore[T]) Replace(ctx context.Context, id string, entity *T) error {
+	return s.replace(ctx, "Replace", id, entity)
+}
+
+func (s *SQLStore[T]) replace(ctx context.Context, op string, id string, entity *T) error {
+	updated, err := s.updateRow(ctx, id, entity)
+	if err != nil {
+		return store.NewError(backend, op, id, err)
+	}
+	if !updated {
+		return store.NewError(backend, op, id, store.ErrNotFound)
+	}
+	return nil
+}
+
+// updateRow writes the entity over the row of id, if there is one.
+func (s *SQLStore[T]) updateRow(ctx context.Context, id string, entity *T) (bool, error) {
+	values, err := s.layout.values(entity)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := s.db.ExecContext(ctx, s.update, append(values, id)...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// Upsert writes the entity with a single INSERT ... ON CONFLICT DO UPDATE
+// statement on Postgres, which returns whether it inserted the row. SQLite
+// cannot tell, so there it inserts the row or else updates it in a
+// transaction: the insert takes the write lock of the database, so the row
+// cannot be deleted before the update.
+func (s *SQLStore[T]) Upsert(ctx context.Context, id string, entity *T) (bool, error) {
+	values, err := s.layout.values(entity)
+	if err != nil {
+		return false, store.NewError(backend, "Upsert", id, err)
+	}
+
+	var created bool
+	if s.dialect == Postgres {
+		err = s.db.QueryRowContext(ctx, s.upsert, append([]any{id}, values...)...).Scan(&created)
+	} else {
+		created, err = s.upsertInTx(ctx, id, values)
+	}
+	if err != nil {
+		return false, store.NewError(backend, "Upsert", id, err)
+	}
+	return created, nil
+}
+
+func (s *SQLStore[T]) upsertInTx(ctx context.Context, id string, values []any) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, s.insert, append([]any{id}, values...)...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		if _, err := tx.ExecContext(ctx, s.update, append(values, id)...); err != nil {
+			return false, err
+		}
+	}
+	return n == 1, tx.Commit()
+}
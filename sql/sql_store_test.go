@@ -0,0 +1,158 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gostore "github.com/Silencevoice/go-store"
+	"github.com/Silencevoice/go-store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+type TestEntity struct {
+	ID    string
+	Value string
+}
+
+var _ gostore.Store[TestEntity] = (*SQLStore[TestEntity])(nil)
+
+func openDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db")+"?_pragma=busy_timeout(5000)")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newStore[T any](t *testing.T, opts ...Option) *SQLStore[T] {
+	store, err := NewSQLStore[T](openDB(t), "entities", SQLite, opts...)
+	require.NoError(t, err)
+	require.NoError(t, store.EnsureSchema(context.Background()))
+	return store
+}
+
+func TestConformance(t *testing.T) {
+	for name, mapping := range map[string]Mapping{"JSON column": JSONColumn, "Struct columns": StructColumns} {
+		t.Run(name, func(t *testing.T) {
+			storetest.Run(t, func(t *testing.T) gostore.KeyedStore[string, storetest.Entity] {
+				return newStore[storetest.Entity](t, WithMapping(mapping))
+			})
+		})
+	}
+}
+
+func TestGetAllIsSorted(t *testing.T) {
+	ctx := context.Background()
+	store := newStore[TestEntity](t)
+	store.Insert(ctx, "2", &TestEntity{ID: "2"})
+	store.Insert(ctx, "1", &TestEntity{ID: "1"})
+
+	entities, err := store.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*TestEntity{{ID: "1"}, {ID: "2"}}, entities)
+}
+
+func TestPostgresUpsert(t *testing.T) {
+	store, err := NewSQLStore[TestEntity](nil, "entities", Postgres)
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "entities" ("id", "data") VALUES ($1, $2) ON CONFLICT ("id") DO UPDATE SET "data" = excluded."data" RETURNING (xmax = 0)`, store.upsert)
+}
+
+func TestDatabaseErrors(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewSQLStore[TestEntity](openDB(t), "missing", SQLite)
+	require.NoError(t, err)
+
+	_, err = store.GetByID(ctx, "1")
+	assert.ErrorContains(t, err, "no such table")
+	assert.NotErrorIs(t, err, gostore.ErrNotFound)
+	_, err = store.Insert(ctx, "1", &TestEntity{})
+	var storeErr *gostore.Error
+	require.ErrorAs(t, err, &storeErr)
+	assert.Equal(t, "Insert", storeErr.Op)
+	_, err = store.GetAll(ctx)
+	assert.Error(t, err)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = store.GetByID(canceled, "1")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+type Car struct {
+	VIN        string `db:"id"`
+	Model      string
+	Year       int
+	Price      float64
+	Used       bool
+	Color      *string
+	SoldAt     time.Time
+	Photo      []byte
+	Extras     map[string]string
+	Tags       []string `db:"labels"`
+	Internal   string   `db:"-"`
+	unexported string
+	Audit
+}
+
+type Audit struct {
+	CreatedBy string
+}
+
+func TestStructColumns(t *testing.T) {
+	ctx := context.Background()
+	store := newStore[Car](t, WithMapping(StructColumns))
+
+	red := "red"
+	car := Car{
+		VIN:        "ignored",
+		Model:      "Corolla",
+		Year:       2020,
+		Price:      19999.5,
+		Used:       true,
+		Color:      &red,
+		SoldAt:     time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+		Photo:      []byte{1, 2, 3},
+		Extras:     map[string]string{"gps": "yes"},
+		Tags:       []string{"hybrid"},
+		Internal:   "not stored",
+		unexported: "not stored",
+		Audit:      Audit{CreatedBy: "admin"},
+	}
+	inserted, err := store.Insert(ctx, "VIN1", &car)
+	require.NoError(t, err)
+
+	stored, err := store.GetByID(ctx, "VIN1")
+	require.NoError(t, err)
+	car.VIN, car.Internal, car.unexported = "VIN1", "", ""
+	assert.True(t, car.SoldAt.Equal(stored.SoldAt))
+	stored.SoldAt = car.SoldAt
+	assert.Equal(t, &car, stored)
+	inserted.SoldAt = car.SoldAt
+	assert.Equal(t, &car, inserted)
+
+	t.Run("Null columns", func(t *testing.T) {
+		_, err := store.Insert(ctx, "VIN2", &Car{Model: "Yaris"})
+		require.NoError(t, err)
+
+		stored, err := store.GetByID(ctx, "VIN2")
+		require.NoError(t, err)
+		assert.Nil(t, stored.Color)
+		assert.Nil(t, stored.Extras)
+	})
+
+	t.Run("Columns", func(t *testing.T) {
+		var model string
+		var year int
+		var labels string
+		row := store.db.QueryRowContext(ctx, `SELECT "model", "year", "labels" FROM "entities" WHERE "id" = ?`, "VIN1")
+		require.NoError(t, row.Scan(&model, &year, &labels))
+		assert.Equal(t, "Corolla", model)
+		assert.Equal(t, 2020, year)
+		assert.Equal(t, `["hybrid"]`, labels)
+	})
+}
@@ -1,12 +1,45 @@
 package store
 
-import "context"
+import (
+	"context"
 
-type Store[T any] interface {
-	GetByID(ctx context.Context, id string) (*T, error)
-	GetMultipleByID(ctx context.Context, ids []string) ([]*T, error)
+	"github.com/Silencevoice/go-store/query"
+)
+
+// KeyedStore is the set of operations every backend implements.
+//
+// Update writes the entity over the stored one, while Replace swaps the whole
+// stored entity, so nothing of the previous one survives even in backends
+// that keep fields unknown to T. Upsert is the atomic "insert or replace" and
+// reports whether the entity was created.
+type KeyedStore[K comparable, T any] interface {
+	GetByID(ctx context.Context, id K) (*T, error)
+	GetMultipleByID(ctx context.Context, ids []K) ([]*T, error)
 	GetAll(ctx context.Context) ([]*T, error)
-	Insert(ctx context.Context, id string, entity *T) (*T, error)
-	Delete(ctx context.Context, id string) error
-	Update(ctx context.Context, id string, entity *T) error
+	Insert(ctx context.Context, id K, entity *T) (*T, error)
+	Delete(ctx context.Context, id K) error
+	Update(ctx context.Context, id K, entity *T) error
+	Replace(ctx context.Context, id K, entity *T) error
+	Upsert(ctx context.Context, id K, entity *T) (created bool, err error)
+}
+
+// Store is the string keyed KeyedStore. It is kept as its own interface
+// because generic type aliases are not available in the Go version we target,
+// but both interfaces have the same method set and are interchangeable.
+type Store[T any] interface {
+	KeyedStore[string, T]
+}
+
+// Finder is implemented by the stores able to evaluate backend agnostic
+// query expressions.
+type Finder[T any] interface {
+	Find(ctx context.Context, filter query.Expr) ([]*T, error)
+}
+
+// Counter answers existence and count questions without loading the
+// entities.
+type Counter[K comparable] interface {
+	Exists(ctx context.Context, id K) (bool, error)
+	Count(ctx context.Context) (int64, error)
+	CountWhere(ctx context.Context, filter query.Expr) (int64, error)
 }
@@ -0,0 +1,283 @@
+// Package storetest checks that a store behaves like the backends of this
+// module, so every backend runs the same tests and custom ones can too:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.KeyedStore[string, storetest.Entity] {
+//			return NewMyStore[storetest.Entity](...)
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	store "github.com/Silencevoice/go-store"
+)
+
+// Entity is the entity the tests store.
+type Entity struct {
+	ID    string
+	Value string
+}
+
+// Run runs every test against a new empty store returned by newStore.
+func Run(t *testing.T, newStore func(t *testing.T) store.KeyedStore[string, Entity]) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s store.KeyedStore[string, Entity])
+	}{
+		{"Insert", testInsert},
+		{"GetByID", testGetByID},
+		{"GetMultipleByID", testGetMultipleByID},
+		{"GetAll", testGetAll},
+		{"Update and Replace", testUpdateAndReplace},
+		{"Upsert", testUpsert},
+		{"Concurrent upserts", testConcurrentUpserts},
+		{"Delete", testDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+func testInsert(t *testing.T, s store.KeyedStore[string, Entity]) {
+	ctx := context.Background()
+
+	entity := Entity{ID: "1", Value: "test-value"}
+	inserted, err := s.Insert(ctx, entity.ID, &entity)
+	if err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	if *inserted != entity {
+		t.Errorf("Insert returned %+v, want %+v", *inserted, entity)
+	}
+
+	// Neither the entity nor the one returned are shared with the store
+	entity.Value = "changed"
+	inserted.Value = "changed"
+	expectValue(t, s, "1", "test-value")
+
+	_, err = s.Insert(ctx, "1", &Entity{ID: "1", Value: "test-value-duplicate"})
+	expectError(t, "Insert of an existing id", err, store.ErrAlreadyExists)
+	expectValue(t, s, "1", "test-value")
+}
+
+func testGetByID(t *testing.T, s store.KeyedStore[string, Entity]) {
+	ctx := context.Background()
+	s.Insert(ctx, "1", &Entity{ID: "1", Value: "test-value"})
+
+	entity, err := s.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if want := (Entity{ID: "1", Value: "test-value"}); *entity != want {
+		t.Errorf("GetByID returned %+v, want %+v", *entity, want)
+	}
+
+	_, err = s.GetByID(ctx, "non-existent")
+	expectError(t, "GetByID of a missing id", err, store.ErrNotFound)
+
+	var storeErr *store.Error
+	if !errors.As(err, &storeErr) {
+		t.Fatalf("GetByID of a missing id returned %v, want a *store.Error", err)
+	}
+	if storeErr.Op != "GetByID" || storeErr.Key != "non-existent" || storeErr.Backend == "" {
+		t.Errorf("GetByID of a missing id returned %+v, want Op GetByID, Key non-existent and a Backend", storeErr)
+	}
+}
+
+func testGetMultipleByID(t *testing.T, s store.KeyedStore[string, Entity]) {
+	ctx := context.Background()
+	s.Insert(ctx, "1", &Entity{ID: "1", Value: "value-1"})
+	s.Insert(ctx, "2", &Entity{ID: "2", Value: "value-2"})
+
+	entities, err := s.GetMultipleByID(ctx, []string{"2", "1", "2"})
+	if err != nil {
+		t.Fatalf("GetMultipleByID: %v", err)
+	}
+	want := []*Entity{{ID: "2", Value: "value-2"}, {ID: "1", Value: "value-1"}, {ID: "2", Value: "value-2"}}
+	if !reflect.DeepEqual(entities, want) {
+		t.Fatalf("GetMultipleByID returned %s, want %s", format(entities), format(want))
+	}
+	if entities[0] == entities[2] {
+		t.Error("GetMultipleByID returned the same pointer for a repeated id")
+	}
+
+	entities, err = s.GetMultipleByID(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetMultipleByID without ids: %v", err)
+	}
+	if len(entities) != 0 {
+		t.Errorf("GetMultipleByID without ids returned %s", format(entities))
+	}
+
+	_, err = s.GetMultipleByID(ctx, []string{"1", "non-existent"})
+	expectError(t, "GetMultipleByID of a missing id", err, store.ErrNotFound)
+	var storeErr *store.Error
+	if !errors.As(err, &storeErr) {
+		t.Fatalf("GetMultipleByID of a missing id returned %v, want a *store.Error", err)
+	}
+	if storeErr.Key != "non-existent" {
+		t.Errorf("GetMultipleByID of a missing id returned Key %v, want non-existent", storeErr.Key)
+	}
+}
+
+func testGetAll(t *testing.T, s store.KeyedStore[string, Entity]) {
+	ctx := context.Background()
+
+	entities, err := s.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(entities) != 0 {
+		t.Errorf("GetAll of an empty store returned %s", format(entities))
+	}
+
+	s.Insert(ctx, "2", &Entity{ID: "2", Value: "value-2"})
+	s.Insert(ctx, "1", &Entity{ID: "1", Value: "value-1"})
+
+	entities, err = s.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	byID := map[string]Entity{}
+	for _, entity := range entities {
+		byID[entity.ID] = *entity
+	}
+	want := map[string]Entity{"1": {ID: "1", Value: "value-1"}, "2": {ID: "2", Value: "value-2"}}
+	if len(entities) != len(want) || !reflect.DeepEqual(byID, want) {
+		t.Errorf("GetAll returned %s, want the entities 1 and 2 in any order", format(entities))
+	}
+}
+
+func testUpdateAndReplace(t *testing.T, s store.KeyedStore[string, Entity]) {
+	ctx := context.Background()
+	s.Insert(ctx, "1", &Entity{ID: "1", Value: "test-value"})
+
+	if err := s.Update(ctx, "1", &Entity{ID: "1", Value: "updated-value"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	expectValue(t, s, "1", "updated-value")
+
+	if err := s.Replace(ctx, "1", &Entity{ID: "1"}); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	expectValue(t, s, "1", "")
+
+	// Neither creates missing entities
+	err := s.Update(ctx, "non-existent", &Entity{})
+	expectError(t, "Update of a missing id", err, store.ErrNotFound)
+	err = s.Replace(ctx, "non-existent", &Entity{})
+	expectError(t, "Replace of a missing id", err, store.ErrNotFound)
+	entities, err := s.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(entities) != 1 {
+		t.Errorf("GetAll returned %s, want only the entity 1", format(entities))
+	}
+}
+
+func testUpsert(t *testing.T, s store.KeyedStore[string, Entity]) {
+	ctx := context.Background()
+
+	created, err := s.Upsert(ctx, "1", &Entity{ID: "1", Value: "created"})
+	if err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	if !created {
+		t.Error("Upsert of a new id reported no creation")
+	}
+
+	created, err = s.Upsert(ctx, "1", &Entity{ID: "1", Value: "overwritten"})
+	if err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	if created {
+		t.Error("Upsert of an existing id reported a creation")
+	}
+
+	expectValue(t, s, "1", "overwritten")
+}
+
+func testConcurrentUpserts(t *testing.T, s store.KeyedStore[string, Entity]) {
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	results := make(chan bool, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			created, err := s.Upsert(ctx, "1", &Entity{ID: "1"})
+			if err != nil {
+				t.Errorf("Upsert: %v", err)
+			}
+			results <- created
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	creations := 0
+	for created := range results {
+		if created {
+			creations++
+		}
+	}
+	if creations != 1 {
+		t.Errorf("%d concurrent upserts reported a creation, want 1", creations)
+	}
+}
+
+func testDelete(t *testing.T, s store.KeyedStore[string, Entity]) {
+	ctx := context.Background()
+	s.Insert(ctx, "1", &Entity{ID: "1", Value: "test-value"})
+	s.Insert(ctx, "2", &Entity{ID: "2", Value: "test-value"})
+
+	if err := s.Delete(ctx, "1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	_, err := s.GetByID(ctx, "1")
+	expectError(t, "GetByID of a deleted id", err, store.ErrNotFound)
+	if _, err := s.GetByID(ctx, "2"); err != nil {
+		t.Errorf("GetByID of the entity left: %v", err)
+	}
+
+	err = s.Delete(ctx, "1")
+	expectError(t, "Delete of a missing id", err, store.ErrNotFound)
+}
+
+// expectValue checks the Value of the entity stored under id.
+func expectValue(t *testing.T, s store.KeyedStore[string, Entity], id, value string) {
+	t.Helper()
+	entity, err := s.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetByID %s: %v", id, err)
+	}
+	if entity.Value != value {
+		t.Errorf("GetByID %s returned Value %q, want %q", id, entity.Value, value)
+	}
+}
+
+func expectError(t *testing.T, what string, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Errorf("%s returned %v, want %v", what, err, target)
+	}
+}
+
+func format(entities []*Entity) string {
+	values := make([]Entity, len(entities))
+	for i, entity := range entities {
+		values[i] = *entity
+	}
+	return fmt.Sprintf("%+v", values)
+}
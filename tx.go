@@ -0,0 +1,10 @@
+package store
+
+import "context"
+
+// Transactor runs fn in a transaction: every call fn makes to the stores of
+// the same backend with the context it receives is committed atomically when
+// fn returns nil, and rolled back when it returns an error.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,20 @@
+package store
+
+import "context"
+
+// Versioned is implemented by the stores that keep a revision number for
+// every entity, so concurrent writers can detect that they would overwrite
+// each other (optimistic concurrency):
+//
+//	car, version, err := repo.GetWithVersion(ctx, id)
+//	...
+//	err = repo.UpdateIfVersion(ctx, id, version, car)
+//	if errors.Is(err, store.ErrVersionConflict) {
+//		// someone else updated the car, read it again and retry
+//	}
+//
+// Inserted entities start at version 1 and every write increments it.
+type Versioned[K comparable, T any] interface {
+	GetWithVersion(ctx context.Context, id K) (*T, int64, error)
+	UpdateIfVersion(ctx context.Context, id K, version int64, entity *T) error
+}